@@ -0,0 +1,194 @@
+/*
+Package admin exposes the maintenance operations of the safe package over HTTP, so operators can inspect
+and recover managed directories without shelling into the hosts.
+
+Mount the handler under the admin port of an application:
+
+	mux.Handle("/safe/", http.StripPrefix("/safe", admin.NewHandler("/etc/myapp")))
+
+The handler serves the following endpoints. All of them respond with JSON.
+
+	GET  /health?dir=$(dir)     safe.DirHealth of the directory
+	GET  /verify?dir=$(dir)     safe.Verify of the directory
+	GET  /describe?name=$(name) safe.Describe of the file
+	GET  /history?name=$(name)  safe.ListVersions of the file
+	GET  /stats                 safe.WriteStats of the files in the root directory, by relative name
+	POST /recover?dir=$(dir)    safe.RecoverDir of the directory
+	POST /repair?name=$(name)   safe.Repair of the file, responds with safe.Describe of the repaired file
+
+The names and directories are relative to the root directory of the handler (see safe.WithPrefix),
+so the handler can not reach files outside of it.
+*/
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	safe "github.com/robojones/safe-write"
+)
+
+// handler serves the admin endpoints.
+type handler struct {
+	mux  *http.ServeMux
+	root string
+	opts []safe.Option
+}
+
+// NewHandler returns a http.Handler which serves the admin endpoints for the files in the root directory
+// and passes the options to every operation. The root replaces a safe.WithPrefix of the options.
+// If the root is empty, every request is rejected with 403 Forbidden.
+func NewHandler(root string, opts ...safe.Option) http.Handler {
+	all := make([]safe.Option, 0, len(opts)+1)
+	all = append(all, opts...)
+	all = append(all, safe.WithPrefix(root))
+	h := &handler{mux: http.NewServeMux(), root: root, opts: all}
+	h.mux.HandleFunc("/health", h.health)
+	h.mux.HandleFunc("/verify", h.verify)
+	h.mux.HandleFunc("/describe", h.describe)
+	h.mux.HandleFunc("/history", h.history)
+	h.mux.HandleFunc("/stats", h.stats)
+	h.mux.HandleFunc("/recover", h.recover)
+	h.mux.HandleFunc("/repair", h.repair)
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.root == "" {
+		http.Error(w, "no root directory", http.StatusForbidden)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// health responds with the health of a directory.
+func (h *handler) health(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	health, err := safe.DirHealth(r.URL.Query().Get("dir"), h.opts...)
+	respond(w, health, err)
+}
+
+// verify responds with the issues of a directory.
+func (h *handler) verify(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	issues, err := safe.Verify(r.URL.Query().Get("dir"), h.opts...)
+	respond(w, issues, err)
+}
+
+// describe responds with the description of a file.
+func (h *handler) describe(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	name, ok := nameOf(w, r)
+	if !ok {
+		return
+	}
+	d, err := safe.Describe(name, h.opts...)
+	respond(w, d, err)
+}
+
+// history responds with the versions of a file.
+func (h *handler) history(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	name, ok := nameOf(w, r)
+	if !ok {
+		return
+	}
+	versions, err := safe.ListVersions(name, h.opts...)
+	respond(w, versions, err)
+}
+
+// stats responds with the write statistics of the files in the root directory by their relative names.
+func (h *handler) stats(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	root, err := filepath.Abs(h.root)
+	if err != nil {
+		respond(w, nil, err)
+		return
+	}
+	stats := make(map[string]safe.WriteStat)
+	for name, s := range safe.WriteStats() {
+		rel, err := filepath.Rel(root, name)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		stats[rel] = s
+	}
+	respond(w, stats, nil)
+}
+
+// recover recovers a directory and responds with the report.
+func (h *handler) recover(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	report, err := safe.RecoverDir(r.URL.Query().Get("dir"), h.opts...)
+	respond(w, report, err)
+}
+
+// repair repairs a file and responds with its description.
+func (h *handler) repair(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	name, ok := nameOf(w, r)
+	if !ok {
+		return
+	}
+	if _, err := safe.Repair(name, h.opts...); err != nil {
+		respond(w, nil, err)
+		return
+	}
+	d, err := safe.Describe(name, h.opts...)
+	respond(w, d, err)
+}
+
+// nameOf returns the name parameter of the request or responds with 400 Bad Request if it is missing.
+func nameOf(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "missing name", http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// allow responds with 405 Method Not Allowed if the request does not use the method.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// respond writes the value as JSON or the error.
+func respond(w http.ResponseWriter, v interface{}, err error) {
+	switch {
+	case errors.Is(err, safe.ErrInvalidPath):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case os.IsNotExist(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	safe "github.com/robojones/safe-write"
+)
+
+func TestHandler(t *testing.T) {
+	if err := os.Mkdir("testdir", 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("testdir")
+	if err := safe.WriteFile("testdir/testfile", []byte("some data")); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler("testdir")
+
+	t.Run("should respond with the health of the directory", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health?dir=.", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expect status 200 but got %d: %s", w.Code, w.Body)
+		}
+		var health safe.Health
+		if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+			t.Fatal(err)
+		}
+		if health.Consistent != 1 {
+			t.Errorf("expect 1 consistent file but got %+v", health)
+		}
+	})
+
+	t.Run("should respond with the description of the file", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/describe?name=testfile", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expect status 200 but got %d: %s", w.Code, w.Body)
+		}
+		var d safe.Description
+		if err := json.NewDecoder(w.Body).Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+		if d.Primary == nil || d.Primary.Size != 9 {
+			t.Errorf("expect the description of the primary but got %+v", d)
+		}
+	})
+
+	t.Run("should only recover with POST", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/recover?dir=.", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expect status 405 but got %d", w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/recover?dir=.", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expect status 200 but got %d: %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("should not serve paths outside of the prefix", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health?dir=..", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expect status 400 but got %d", w.Code)
+		}
+	})
+
+	t.Run("should not serve absolute paths", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/recover?dir=/tmp", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expect status 400 but got %d", w.Code)
+		}
+	})
+
+	t.Run("should reject every request without a root directory", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewHandler("").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/describe?name=testdir/testfile", nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expect status 403 but got %d", w.Code)
+		}
+	})
+
+	t.Run("should respond with the issues of the directory", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verify?dir=.", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expect status 200 but got %d: %s", w.Code, w.Body)
+		}
+		var issues []safe.Issue
+		if err := json.NewDecoder(w.Body).Decode(&issues); err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != 0 {
+			t.Errorf("expect no issues but got %v", issues)
+		}
+	})
+
+	t.Run("should respond with the versions of the file", func(t *testing.T) {
+		if err := safe.WriteFileVersioned("testdir/versioned", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history?name=versioned", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expect status 200 but got %d: %s", w.Code, w.Body)
+		}
+		var versions []safe.VersionInfo
+		if err := json.NewDecoder(w.Body).Decode(&versions); err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 1 || versions[0].Size != 9 {
+			t.Errorf("expect 1 version but got %+v", versions)
+		}
+	})
+
+	t.Run("should respond with the write statistics of the root directory", func(t *testing.T) {
+		defer safe.ResetWriteStats()
+		if err := safe.WriteFile("testdir/testfile", []byte("some data"), safe.WithWriteStats()); err != nil {
+			t.Fatal(err)
+		}
+		if err := safe.WriteFile("otherfile", []byte("some data"), safe.WithWriteStats()); err != nil {
+			t.Fatal(err)
+		}
+		defer safe.RemoveFile("otherfile")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expect status 200 but got %d: %s", w.Code, w.Body)
+		}
+		var stats map[string]safe.WriteStat
+		if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 1 || stats["testfile"].Writes != 1 {
+			t.Errorf("expect the statistics of testfile only but got %+v", stats)
+		}
+	})
+
+	t.Run("should repair the file", func(t *testing.T) {
+		if err := os.Remove("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/repair?name=testfile", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expect status 200 but got %d: %s", w.Code, w.Body)
+		}
+		if _, err := os.Stat("testdir/testfile"); err != nil {
+			t.Errorf("expect the file to be restored but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Adopt takes over an existing file which was written without this package, so ReadFile and WriteFile
+// can fall back to its alt file from now on. The permissions of the file are set to the permissions of the options
+// (DefaultPerm or WithPerm) like WriteFile would. Then the alt file is created according to the commit strategy:
+// a hard link for StrategyHardlink and StrategyExchange, a copy for StrategyRename and nothing for StrategyReplace.
+// With StrategySymlink, the name becomes a symbolic link to a version and the original file is the previous version.
+// A stale alt file is replaced. If the name is not a regular file, a *os.PathError wrapping ErrNotRegular is returned.
+func Adopt(name string, opts ...Option) error {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	info, err := os.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return &os.PathError{Op: "adopt", Path: name, Err: ErrNotRegular}
+	}
+	if err := os.Chmod(name, c.perm); err != nil {
+		return err
+	}
+
+	s, err := c.strategy(name)
+	if err != nil {
+		return err
+	}
+	switch s {
+	case StrategyReplace:
+		err = remove(c.altName(name))
+	case StrategyRename:
+		err = c.copyAlt(name)
+	case StrategySymlink:
+		err = c.adoptVersion(name)
+	default:
+		err = c.linkAlt(name)
+	}
+	if err != nil {
+		return err
+	}
+	if c.dirSync >= DirSyncParent {
+		if err := c.syncDirs(name, c.altName(name)); err != nil {
+			return err
+		}
+	}
+	if c.index {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return c.addToIndex(name, data, info.ModTime())
+	}
+	return nil
+}
+
+// copyAlt atomically replaces the alt name of the resolved name with a copy of the name.
+func (c *config) copyAlt(name string) error {
+	alt := c.altName(name)
+	tmp := c.tempName(alt, time.Now())
+	if err := copyFile(name, tmp, !c.noSync); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := replaceFile(tmp, alt); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// adoptVersion commits a copy of the resolved name with SymlinkCommit, which keeps the file as the previous version.
+func (c *config) adoptVersion(name string) error {
+	tmp := c.tempName(name, time.Now())
+	defer os.Remove(tmp)
+	if err := copyFile(name, tmp, !c.noSync); err != nil {
+		return err
+	}
+	return SymlinkCommit{}.Commit(tmp, c.altName(name), name)
+}
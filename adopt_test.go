@@ -0,0 +1,58 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestAdopt(t *testing.T) {
+	t.Run("should link the alt file to the file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile", "some data")
+		createFile(t, "testdir/testfile.1", "stale data")
+
+		if err := Adopt("testdir/testfile", WithPerm(0600)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile.1", "some data")
+		info, err := os.Stat("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		alt, err := os.Stat("testdir/testfile.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !os.SameFile(info, alt) {
+			t.Error("expect the alt file to be a hard link to the file")
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("expect the permissions 0600 but got %v", info.Mode().Perm())
+		}
+	})
+
+	t.Run("should remove the alt file with StrategyReplace", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile", "some data")
+		createFile(t, "testdir/testfile.1", "stale data")
+
+		if err := Adopt("testdir/testfile", WithStrategy(StrategyReplace)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkNotExist(t, "testdir/testfile.1")
+	})
+
+	t.Run("should fail for a directory", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createDir(t, "testdir/testfile")
+
+		if err := Adopt("testdir/testfile"); !errors.Is(err, ErrNotRegular) {
+			t.Errorf("expect ErrNotRegular but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package safe
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Suggestion is a tuning measure proposed by Advisor.
+type Suggestion string
+
+const (
+	// SuggestDedupe means that many writes did not change the contents of the file
+	// and should be skipped by the caller.
+	SuggestDedupe Suggestion = "dedupe"
+	// SuggestJournal means that a large file is rewritten to change a small part of it,
+	// so appending the changes to a journal (see OpenLog) is cheaper.
+	SuggestJournal Suggestion = "journal"
+	// SuggestDebounce means that the file is written so often that the writes should be batched.
+	SuggestDebounce Suggestion = "debounce"
+)
+
+// WriteStat contains the statistics of the writes of a file which were made with WithWriteStats.
+type WriteStat struct {
+	// Writes is the number of completed writes.
+	Writes int
+	// Unchanged is the number of writes whose data was identical to the previous contents.
+	Unchanged int
+	// Bytes is the total size of the data which was written.
+	Bytes int64
+	// ChangedBytes is the total size of the ranges which differed from the previous contents.
+	ChangedBytes int64
+	// Syncs is the number of fsync calls which were made for the writes.
+	Syncs int
+	// First and Last are the times of the first and the last write.
+	First time.Time
+	Last  time.Time
+}
+
+// Amplification returns the ratio between the bytes which were written and the bytes which changed.
+// It is 0 if nothing was written yet.
+func (s WriteStat) Amplification() float64 {
+	if s.ChangedBytes == 0 {
+		return 0
+	}
+	return float64(s.Bytes) / float64(s.ChangedBytes)
+}
+
+// Advice is a Suggestion for a single file.
+type Advice struct {
+	Path       string
+	Suggestion Suggestion
+	// Reason explains which statistics led to the suggestion.
+	Reason string
+}
+
+// The thresholds used by Advisor.
+const (
+	adviseMinWrites      = 10
+	adviseJournalSize    = 64 << 10
+	adviseJournalRatio   = 10
+	adviseDebounceRate   = 10
+	adviseUnchangedRatio = 0.5
+)
+
+// stats contains the WriteStat per absolute name.
+var stats = struct {
+	sync.Mutex
+	m map[string]*WriteStat
+}{m: make(map[string]*WriteStat)}
+
+// WithWriteStats makes WriteFile and Create record the size, the changed bytes and the fsync calls of each write,
+// so they can be inspected with WriteStats and Advisor. Finding the changed bytes requires reading
+// the previous contents of the file, so this is meant for tuning rather than for production use.
+func WithWriteStats() Option {
+	return func(c *config) {
+		c.writeStats = true
+	}
+}
+
+// WriteStats returns the statistics of the files which were written with WithWriteStats by absolute name.
+func WriteStats() map[string]WriteStat {
+	stats.Lock()
+	defer stats.Unlock()
+	m := make(map[string]WriteStat, len(stats.m))
+	for name, s := range stats.m {
+		m[name] = *s
+	}
+	return m
+}
+
+// ResetWriteStats discards the statistics, e.g. to start a new measurement.
+func ResetWriteStats() {
+	stats.Lock()
+	defer stats.Unlock()
+	stats.m = make(map[string]*WriteStat)
+}
+
+// Advisor inspects the statistics recorded with WithWriteStats and suggests how the writes of each file can be tuned.
+// The advice is sorted by path.
+func Advisor() []Advice {
+	all := WriteStats()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var advice []Advice
+	for _, name := range names {
+		s := all[name]
+		if s.Writes < adviseMinWrites {
+			continue
+		}
+		if float64(s.Unchanged) >= float64(s.Writes)*adviseUnchangedRatio {
+			advice = append(advice, Advice{name, SuggestDedupe,
+				fmt.Sprintf("%d of %d writes did not change the contents", s.Unchanged, s.Writes)})
+		}
+		if s.Bytes/int64(s.Writes) >= adviseJournalSize && s.ChangedBytes*adviseJournalRatio <= s.Bytes {
+			advice = append(advice, Advice{name, SuggestJournal,
+				fmt.Sprintf("%d bytes were written to change %d bytes", s.Bytes, s.ChangedBytes)})
+		}
+		if span := s.Last.Sub(s.First); float64(s.Writes-1) > span.Seconds()*adviseDebounceRate {
+			advice = append(advice, Advice{name, SuggestDebounce,
+				fmt.Sprintf("%d writes and %d fsync calls within %v", s.Writes, s.Syncs, span)})
+		}
+	}
+	return advice
+}
+
+// previous returns the current contents of the resolved name if write statistics are enabled.
+func (c *config) previous(name string) []byte {
+	if !c.writeStats {
+		return nil
+	}
+	data, _ := c.storage().ReadFile(name)
+	return data
+}
+
+// recordWrite adds a completed write of the data to the statistics of the resolved name.
+func (c *config) recordWrite(name string, old []byte, data []byte) {
+	key, err := filepath.Abs(name)
+	if err != nil {
+		return
+	}
+	t := time.Now()
+	changed := delta(old, data)
+	syncs := 0
+	if !c.noSync {
+		syncs++
+	}
+	if c.dirSync >= DirSyncParent {
+		syncs++
+	}
+
+	stats.Lock()
+	defer stats.Unlock()
+	s, ok := stats.m[key]
+	if !ok {
+		s = &WriteStat{First: t}
+		stats.m[key] = s
+	}
+	s.Writes++
+	if changed == 0 && len(old) == len(data) {
+		s.Unchanged++
+	}
+	s.Bytes += int64(len(data))
+	s.ChangedBytes += changed
+	s.Syncs += syncs
+	s.Last = t
+}
+
+// delta returns the size of the range which differs between the old and the new data.
+func delta(old []byte, data []byte) int64 {
+	prefix := 0
+	for prefix < len(old) && prefix < len(data) && old[prefix] == data[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(data)-prefix && old[len(old)-1-suffix] == data[len(data)-1-suffix] {
+		suffix++
+	}
+	if len(old) > len(data) {
+		return int64(len(old) - prefix - suffix)
+	}
+	return int64(len(data) - prefix - suffix)
+}
@@ -0,0 +1,84 @@
+package safe
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithWriteStats(t *testing.T) {
+	t.Run("should record the written and the changed bytes", func(t *testing.T) {
+		ResetWriteStats()
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		for _, data := range []string{"abcdef", "abXdef", "abXdef"} {
+			if err := WriteFile("testfile", []byte(data), WithWriteStats()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		abs, _ := filepath.Abs("testfile")
+		s := WriteStats()[abs]
+		if s.Writes != 3 || s.Unchanged != 1 || s.Bytes != 18 || s.ChangedBytes != 7 || s.Syncs != 6 {
+			t.Errorf("unexpected stats %+v", s)
+		}
+	})
+}
+
+func TestAdvisor(t *testing.T) {
+	t.Run("should suggest dedupe and debounce for frequent identical writes", func(t *testing.T) {
+		ResetWriteStats()
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		for i := 0; i < adviseMinWrites; i++ {
+			if err := WriteFile("testfile", []byte("data"), WithWriteStats()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got := make(map[Suggestion]bool)
+		for _, a := range Advisor() {
+			got[a.Suggestion] = true
+		}
+		if !got[SuggestDedupe] || !got[SuggestDebounce] || got[SuggestJournal] {
+			t.Errorf("unexpected advice %v", Advisor())
+		}
+	})
+
+	t.Run("should suggest a journal if small parts of large files change", func(t *testing.T) {
+		ResetWriteStats()
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		data := bytes.Repeat([]byte("x"), adviseJournalSize)
+		for i := 0; i < 2*adviseMinWrites; i++ {
+			data[i] = 'y'
+			if err := WriteFile("testfile", data, WithWriteStats()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got := make(map[Suggestion]bool)
+		for _, a := range Advisor() {
+			got[a.Suggestion] = true
+		}
+		if !got[SuggestJournal] || got[SuggestDedupe] {
+			t.Errorf("unexpected advice %v", Advisor())
+		}
+	})
+
+	t.Run("should not give advice for files with few writes", func(t *testing.T) {
+		ResetWriteStats()
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		if err := WriteFile("testfile", []byte("data"), WithWriteStats()); err != nil {
+			t.Fatal(err)
+		}
+		if a := Advisor(); len(a) != 0 {
+			t.Errorf("expect no advice but got %v", a)
+		}
+	})
+}
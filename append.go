@@ -0,0 +1,246 @@
+package safe
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordHeaderSize is the size of the length and the checksum which precede every record in a log.
+const recordHeaderSize = 8
+
+// castagnoli is the table used for the checksums of the records.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Log is an append-only file of length-prefixed and checksummed records.
+// Use it next to a file written with WriteFile for changes which are too frequent to rewrite the whole file.
+// A Log is safe for concurrent use.
+type Log struct {
+	mu       sync.Mutex
+	f        *os.File
+	interval time.Duration
+	lastSync time.Time
+	dirty    bool
+	// timer syncs the records at the end of the interval if no other call does.
+	timer *time.Timer
+	// err is the error of the last sync of the timer, which is returned by the next call.
+	err    error
+	closed bool
+}
+
+// WithSyncInterval makes a Log sync the appended records to the disk at most once per interval
+// instead of after every record. Records appended since the last sync are synced at the end of the interval,
+// or earlier by Sync and Close. If that sync fails, the error is returned by the next call of the Log.
+// If the process is interrupted, the records of the last interval can be lost.
+func WithSyncInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.syncInterval = d
+	}
+}
+
+// AppendRecord appends the record to the log with the name and syncs it to the disk.
+// Use OpenLog to append many records without opening the file every time.
+func AppendRecord(name string, rec []byte, opts ...Option) error {
+	l, err := OpenLog(name, opts...)
+	if err != nil {
+		return err
+	}
+	if err := l.Append(rec); err != nil {
+		l.Close()
+		return err
+	}
+	return l.Close()
+}
+
+// OpenLog opens the log with the name for appending and creates it if it does not exist.
+// A torn record at the end of the log, which is left over if a previous process was interrupted
+// during an append, is removed with RecoverLog first.
+func OpenLog(name string, opts ...Option) (*Log, error) {
+	c := newConfig(opts)
+	if err := c.writable("open", name); err != nil {
+		return nil, err
+	}
+	name, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.prepareDir(name); err != nil {
+		return nil, err
+	}
+	if _, err := recoverLog(name); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, c.perm)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{f: f, interval: c.syncInterval, lastSync: time.Now()}, nil
+}
+
+// Append writes the record to the end of the log.
+func (l *Log) Append(rec []byte) error {
+	buf := encodeRecord(rec)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(buf); err != nil {
+		return err
+	}
+	l.dirty = true
+	if time.Since(l.lastSync) >= l.interval {
+		return l.sync()
+	}
+	if l.timer == nil {
+		l.timer = time.AfterFunc(l.interval-time.Since(l.lastSync), l.flush)
+	}
+	return l.takeErr()
+}
+
+// flush syncs the records at the end of the interval.
+func (l *Log) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timer = nil
+	if l.closed {
+		return
+	}
+	if err := l.sync(); err != nil {
+		l.err = err
+	}
+}
+
+// takeErr returns and clears the error of the last sync of the timer.
+func (l *Log) takeErr() error {
+	err := l.err
+	l.err = nil
+	return err
+}
+
+// encodeRecord prefixes the record with its length and checksum.
+func encodeRecord(rec []byte) []byte {
+	buf := make([]byte, recordHeaderSize+len(rec))
+	binary.BigEndian.PutUint32(buf, uint32(len(rec)))
+	binary.BigEndian.PutUint32(buf[4:], crc32.Checksum(rec, castagnoli))
+	copy(buf[recordHeaderSize:], rec)
+	return buf
+}
+
+// Sync writes the appended records to the disk.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sync()
+}
+
+// sync the file if records were appended since the last sync.
+func (l *Log) sync() error {
+	if err := l.takeErr(); err != nil {
+		return err
+	}
+	if !l.dirty {
+		return nil
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+	l.lastSync = time.Now()
+	return nil
+}
+
+// Truncate removes all records from the log.
+func (l *Log) Truncate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	l.dirty = true
+	return l.sync()
+}
+
+// Close syncs the appended records and closes the log.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	if err := l.sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// ReadRecords returns the records of the log with the name.
+// Reading stops at the first record which is incomplete or does not match its checksum.
+func ReadRecords(name string, opts ...Option) ([][]byte, error) {
+	name, err := newConfig(opts).path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	records, _ := scanRecords(data)
+	return records, nil
+}
+
+// RecoverLog truncates the log with the name after the last valid record and returns the number of bytes removed.
+func RecoverLog(name string, opts ...Option) (int64, error) {
+	c := newConfig(opts)
+	if err := c.writable("recover", name); err != nil {
+		return 0, err
+	}
+	name, err := c.path(name)
+	if err != nil {
+		return 0, err
+	}
+	return recoverLog(name)
+}
+
+// recoverLog truncates the log with the resolved name after the last valid record.
+func recoverLog(name string) (int64, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return 0, err
+	}
+	_, valid := scanRecords(data)
+	torn := int64(len(data)) - valid
+	if torn == 0 {
+		return 0, nil
+	}
+	if err := os.Truncate(name, valid); err != nil {
+		return 0, err
+	}
+	return torn, nil
+}
+
+// scanRecords decodes the records of the data and returns them with the number of bytes which contain valid records.
+func scanRecords(data []byte) ([][]byte, int64) {
+	var (
+		records [][]byte
+		offset  int64
+	)
+	for len(data) >= recordHeaderSize {
+		n := int64(binary.BigEndian.Uint32(data))
+		sum := binary.BigEndian.Uint32(data[4:])
+		if int64(len(data)-recordHeaderSize) < n {
+			break
+		}
+		rec := data[recordHeaderSize : recordHeaderSize+n]
+		if crc32.Checksum(rec, castagnoli) != sum {
+			break
+		}
+		records = append(records, rec)
+		data = data[recordHeaderSize+n:]
+		offset += recordHeaderSize + n
+	}
+	return records, offset
+}
@@ -0,0 +1,127 @@
+package safe
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAppendRecord(t *testing.T) {
+	t.Run("should append the records to the log", func(t *testing.T) {
+		defer clean(t, "testlog")
+		for _, rec := range []string{"first", "second", ""} {
+			if err := AppendRecord("testlog", []byte(rec)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := ReadRecords("testlog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 3 || string(got[0]) != "first" || string(got[1]) != "second" || len(got[2]) != 0 {
+			t.Errorf("ReadRecords does not return the appended records. Got %q", got)
+		}
+	})
+
+	t.Run("should remove a torn record at the end of the log before appending", func(t *testing.T) {
+		defer clean(t, "testlog")
+		if err := AppendRecord("testlog", []byte("first")); err != nil {
+			t.Fatal(err)
+		}
+		// Simulate an interrupted append.
+		f, err := os.OpenFile("testlog", os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte{0, 0, 0, 10, 1, 2, 3, 4, 'p', 'a', 'r'}); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		if err := AppendRecord("testlog", []byte("second")); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ReadRecords("testlog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || string(got[0]) != "first" || string(got[1]) != "second" {
+			t.Errorf("ReadRecords does not return the appended records. Got %q", got)
+		}
+	})
+}
+
+func TestRecoverLog(t *testing.T) {
+	t.Run("should truncate the log after the last valid record", func(t *testing.T) {
+		defer clean(t, "testlog")
+		if err := AppendRecord("testlog", []byte("first")); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.OpenFile("testlog", os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte{0, 0}); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		n, err := RecoverLog("testlog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 2 {
+			t.Errorf("expect 2 truncated bytes but got %d", n)
+		}
+	})
+}
+
+func TestLog(t *testing.T) {
+	t.Run("should sync the records on close", func(t *testing.T) {
+		defer clean(t, "testlog")
+		l, err := OpenLog("testlog", WithSyncInterval(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Append([]byte("first")); err != nil {
+			t.Fatal(err)
+		}
+		if !l.dirty {
+			t.Errorf("expect the record not to be synced yet")
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if l.dirty {
+			t.Errorf("expect the record to be synced")
+		}
+	})
+
+	t.Run("should sync the records at the end of the interval", func(t *testing.T) {
+		defer clean(t, "testlog")
+		l, err := OpenLog("testlog", WithSyncInterval(20*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		if err := l.Append([]byte("first")); err != nil {
+			t.Fatal(err)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			l.mu.Lock()
+			dirty := l.dirty
+			l.mu.Unlock()
+			if !dirty {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expect the record to be synced without another call")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
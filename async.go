@@ -0,0 +1,178 @@
+package safe
+
+import (
+	"os"
+	"sync"
+)
+
+// OverflowPolicy decides what an AsyncWriter does if its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes WriteFile wait until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest queued write to make room. The dropped write is reported
+	// to the error callback with ErrDropped.
+	OverflowDropOldest
+	// OverflowError makes WriteFile return ErrQueueFull.
+	OverflowError
+)
+
+// QueueStats are the metrics of the queue of an AsyncWriter.
+type QueueStats struct {
+	// Depth is the number of queued writes.
+	Depth int
+	// Capacity is the maximum number of queued writes.
+	Capacity int
+	// Enqueued, Dropped and Rejected count the writes which were queued, dropped with OverflowDropOldest
+	// and rejected with OverflowError.
+	Enqueued, Dropped, Rejected int64
+	// Written and Failed count the writes which were committed and which failed.
+	Written, Failed int64
+}
+
+// AsyncWriter commits writes in the background in the order in which they were queued,
+// so producers don't have to wait for the disk. The queue is bounded, so bursty producers can't grow
+// the memory without bound. An AsyncWriter is safe for concurrent use.
+type AsyncWriter struct {
+	policy  OverflowPolicy
+	onError func(name string, err error)
+	opts    []Option
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    []asyncWrite
+	capacity int
+	busy     bool
+	closed   bool
+	stats    QueueStats
+	done     chan struct{}
+}
+
+// asyncWrite is a queued write.
+type asyncWrite struct {
+	name string
+	data []byte
+}
+
+// NewAsyncWriter creates an AsyncWriter whose queue holds up to capacity writes and starts its worker.
+// Writes which fail or are dropped are reported to the onError callback, which may be nil.
+// The callback is called while the queue is locked, so it must not call the AsyncWriter.
+// The options apply to every write.
+func NewAsyncWriter(capacity int, policy OverflowPolicy, onError func(name string, err error), opts ...Option) *AsyncWriter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	w := &AsyncWriter{
+		policy:   policy,
+		onError:  onError,
+		opts:     opts,
+		capacity: capacity,
+		done:     make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	w.stats.Capacity = capacity
+	go w.run()
+	return w
+}
+
+// WriteFile queues the write of the data to the file with the name. The data must not be modified afterwards.
+// If the queue is full, the OverflowPolicy applies. After Close, ErrClosed is returned.
+func (w *AsyncWriter) WriteFile(name string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for !w.closed && len(w.queue) >= w.capacity {
+		switch w.policy {
+		case OverflowError:
+			w.stats.Rejected++
+			return &os.PathError{Op: "write", Path: name, Err: ErrQueueFull}
+		case OverflowDropOldest:
+			dropped := w.queue[0]
+			w.queue = w.queue[1:]
+			w.stats.Dropped++
+			w.report(dropped.name, &os.PathError{Op: "write", Path: dropped.name, Err: ErrDropped})
+		default:
+			w.cond.Wait()
+		}
+	}
+	if w.closed {
+		return &os.PathError{Op: "write", Path: name, Err: ErrClosed}
+	}
+	w.queue = append(w.queue, asyncWrite{name, data})
+	w.stats.Enqueued++
+	w.cond.Broadcast()
+	return nil
+}
+
+// Stats returns the current metrics of the queue.
+func (w *AsyncWriter) Stats() QueueStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.stats
+	s.Depth = len(w.queue)
+	return s
+}
+
+// Flush waits until all writes which were queued so far are committed or failed.
+func (w *AsyncWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.queue) > 0 || w.busy {
+		w.cond.Wait()
+	}
+}
+
+// Close stops accepting writes, commits the queued writes and stops the worker.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	<-w.done
+	return nil
+}
+
+// run commits the queued writes until the AsyncWriter is closed and the queue is empty.
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for {
+		for len(w.queue) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if len(w.queue) == 0 {
+			return
+		}
+		next := w.queue[0]
+		w.queue = w.queue[1:]
+		w.busy = true
+		// Wake up producers which wait for room in the queue.
+		w.cond.Broadcast()
+
+		w.mu.Unlock()
+		err := WriteFile(next.name, next.data, w.opts...)
+		w.mu.Lock()
+
+		w.busy = false
+		if err != nil {
+			w.stats.Failed++
+			w.report(next.name, err)
+		} else {
+			w.stats.Written++
+		}
+		w.cond.Broadcast()
+	}
+}
+
+// report passes the error to the error callback. The caller holds the lock.
+func (w *AsyncWriter) report(name string, err error) {
+	if w.onError != nil {
+		w.onError(name, err)
+	}
+}
@@ -0,0 +1,105 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// stalled returns an option which blocks every write until the returned channel is closed.
+func stalled() (Option, chan struct{}) {
+	release := make(chan struct{})
+	return WithValidator(func(data []byte) error {
+		<-release
+		return nil
+	}), release
+}
+
+func TestAsyncWriter(t *testing.T) {
+	t.Run("should commit the queued writes in order", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		w := NewAsyncWriter(10, OverflowBlock, func(name string, err error) { t.Error(err) })
+
+		for _, data := range []string{"a", "b", "c"} {
+			if err := w.WriteFile("testfile", []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "c")
+		if s := w.Stats(); s.Enqueued != 3 || s.Written != 3 || s.Depth != 0 {
+			t.Errorf("unexpected stats %+v", s)
+		}
+		if err := w.WriteFile("testfile", nil); !errors.Is(err, ErrClosed) {
+			t.Errorf("expect ErrClosed but got %v", err)
+		}
+	})
+
+	t.Run("should return ErrQueueFull with OverflowError", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		opt, release := stalled()
+		w := NewAsyncWriter(1, OverflowError, nil, opt)
+
+		// The first write is taken by the worker, the second one fills the queue.
+		if err := w.WriteFile("testfile", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		waitDepth(t, w, 0)
+		if err := w.WriteFile("testfile", []byte("b")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteFile("testfile", []byte("c")); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("expect ErrQueueFull but got %v", err)
+		}
+		close(release)
+		w.Close()
+		checkContents(t, "testfile", "b")
+		if s := w.Stats(); s.Rejected != 1 {
+			t.Errorf("expect 1 rejected write but got %+v", s)
+		}
+	})
+
+	t.Run("should drop the oldest write with OverflowDropOldest", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		opt, release := stalled()
+		var dropped []string
+		w := NewAsyncWriter(1, OverflowDropOldest, func(name string, err error) {
+			if errors.Is(err, ErrDropped) {
+				dropped = append(dropped, name)
+			}
+		}, opt)
+
+		if err := w.WriteFile("testfile", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		waitDepth(t, w, 0)
+		for _, data := range []string{"b", "c"} {
+			if err := w.WriteFile("testfile", []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		close(release)
+		w.Flush()
+		checkContents(t, "testfile", "c")
+		if len(dropped) != 1 {
+			t.Errorf("expect 1 dropped write but got %v", dropped)
+		}
+		w.Close()
+	})
+}
+
+// waitDepth waits until the worker took the queued writes.
+func waitDepth(t *testing.T, w *AsyncWriter, depth int) {
+	for i := 0; i < 100; i++ {
+		if w.Stats().Depth == depth {
+			return
+		}
+		time.Sleep(SleepTime)
+	}
+	t.Fatalf("expect the queue depth to become %d", depth)
+}
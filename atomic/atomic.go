@@ -0,0 +1,51 @@
+/*
+Package atomic mirrors the API of github.com/natefinch/atomic, backed by the hard link protocol of the safe package.
+Projects can migrate by changing the import path:
+
+	import "github.com/robojones/safe-write/atomic"
+
+	err := atomic.WriteFile("config.json", body)
+
+Unlike the original, the previous version of a file stays available as $(name).1 while it is replaced,
+so it has to be read with safe.ReadFile to profit from the stronger guarantees.
+*/
+package atomic
+
+import (
+	"io"
+	"os"
+
+	safe "github.com/robojones/safe-write"
+)
+
+// DefaultPerm are the permissions of new files, matching the temporary files of the original package.
+const DefaultPerm = 0600
+
+// WriteFile copies the data from the reader to the file with the filename.
+// If the file already exists, its permissions are kept.
+func WriteFile(filename string, r io.Reader) error {
+	_, err := safe.WriteFileFrom(filename, r, safe.WithPerm(perm(filename)))
+	return err
+}
+
+// ReplaceFile replaces the destination with the contents of the source and removes the source.
+func ReplaceFile(source string, destination string) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	err = safe.CommitFile(f, destination, safe.WithPerm(perm(destination)))
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(source)
+}
+
+// perm returns the permissions of the existing file with the name or DefaultPerm.
+func perm(name string) os.FileMode {
+	if info, err := os.Stat(name); err == nil {
+		return info.Mode().Perm()
+	}
+	return DefaultPerm
+}
@@ -0,0 +1,61 @@
+package atomic
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	if err := os.Mkdir("testdir", 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("testdir")
+
+	t.Run("should write the data of the reader", func(t *testing.T) {
+		if err := WriteFile("testdir/testfile", strings.NewReader("some data")); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "some data" {
+			t.Errorf("expect some data but got %q", data)
+		}
+		info, err := os.Stat("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != DefaultPerm {
+			t.Errorf("expect the permissions %v but got %v", os.FileMode(DefaultPerm), info.Mode().Perm())
+		}
+	})
+}
+
+func TestReplaceFile(t *testing.T) {
+	if err := os.Mkdir("testdir", 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("testdir")
+
+	t.Run("should replace the destination and remove the source", func(t *testing.T) {
+		if err := ioutil.WriteFile("testdir/source", []byte("some data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := ReplaceFile("testdir/source", "testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "some data" {
+			t.Errorf("expect some data but got %q", data)
+		}
+		if _, err := os.Stat("testdir/source"); !os.IsNotExist(err) {
+			t.Errorf("expect the source to be removed but got %v", err)
+		}
+	})
+}
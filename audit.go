@@ -0,0 +1,321 @@
+package safe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry is an entry of the audit journal which records a write, a removal or a recovery (see WithAudit).
+type AuditEntry struct {
+	// Name is the resolved name of the file which was written or removed.
+	Name string `json:"name"`
+	// User is the user who wrote the file (see WithAuditUser).
+	User string `json:"user"`
+	// Time is the time of the write in UTC.
+	Time time.Time `json:"time"`
+	// Digest is the digest of the written contents (see Digest and WithHash). It is empty for a removal
+	// and a recovery.
+	Digest string `json:"digest"`
+	// Removed is true if the file was removed.
+	Removed bool `json:"removed,omitempty"`
+	// Recovery is the action which RecoverDir or Repair took for the file, or empty for a write or a removal.
+	Recovery RecoveryAction `json:"recovery,omitempty"`
+	// Error is the message of the error if the Recovery failed.
+	Error string `json:"error,omitempty"`
+	// Chain is the digest of the entry and the Chain of the previous entry. It links the entries,
+	// so an entry can not be modified or removed without breaking the chain of the following entries.
+	// Keep the Chain of the newest entry elsewhere to detect that entries were removed from the end of the journal.
+	Chain string `json:"chain"`
+}
+
+// WithAudit makes every function of this package which writes or removes a file (e.g. WriteFile, Update,
+// Create, Tx, CopyFile, MoveFile, Exchange and RemoveFile) record it as an AuditEntry in the audit journal
+// with the name, which can be queried with ReadAudit. The actions of RecoverDir and Repair are recorded as well.
+// Combined with a Manager, all writes of the Manager are recorded in one journal, e.g.
+//
+//	m := safe.New(safe.WithAudit("audit.log"))
+//
+// The journal is an append-only log of checksummed records (see OpenLog) whose entries are linked by a hash chain,
+// so changes to the recorded history are detected. Appends are serialized with an advisory lock on the journal,
+// so several processes can share it. An append only reads the last entry, unless another process appended
+// since, so it does not slow down as the journal grows; ReadAudit verifies the whole chain.
+// The entry is synced before the write returns. If it can not be appended, the error is returned
+// although the file was written.
+func WithAudit(journal string) Option {
+	return func(c *config) {
+		c.auditLog = journal
+	}
+}
+
+// WithAuditUser sets the user who is recorded in the audit journal, e.g. the user of a request which changes
+// the configuration. By default, the name of the user of the process is recorded.
+func WithAuditUser(name string) Option {
+	return func(c *config) {
+		c.auditUser = name
+	}
+}
+
+// ReadAudit returns the entries of the audit journal of WithAudit which record writes of the file with the name,
+// sorted from the oldest to the newest. If the name is empty, the entries of all files are returned.
+// The hash chain of the whole journal is verified. If an entry was modified or removed,
+// an error wrapping ErrAuditTampered is returned. Without WithAudit, an error wrapping ErrNoAudit is returned.
+func ReadAudit(name string, opts ...Option) ([]AuditEntry, error) {
+	c := newConfig(opts)
+	if c.auditLog == "" {
+		return nil, &os.PathError{Op: "audit", Path: name, Err: ErrNoAudit}
+	}
+	journal, err := c.path(c.auditLog)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		if name, err = c.path(name); err != nil {
+			return nil, err
+		}
+	}
+	data, err := ioutil.ReadFile(journal)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := scanAudit(journal, data)
+	if err != nil || name == "" {
+		return entries, err
+	}
+	var matched []AuditEntry
+	for _, e := range entries {
+		if filepath.Clean(e.Name) == filepath.Clean(name) {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+// audit records the write of the data to the resolved name in the audit journal if WithAudit is set.
+func (c *config) audit(name string, data []byte) error {
+	if c.auditLog == "" {
+		return nil
+	}
+	digest, err := Digest(c.hash, data)
+	if err != nil {
+		return err
+	}
+	return c.appendEntry(AuditEntry{Name: name, Digest: digest})
+}
+
+// auditFile records the current contents of the resolved name like audit.
+func (c *config) auditFile(name string) error {
+	if c.auditLog == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return c.audit(name, data)
+}
+
+// auditRemove records the removal of the resolved name in the audit journal if WithAudit is set.
+func (c *config) auditRemove(name string) error {
+	if c.auditLog == "" {
+		return nil
+	}
+	return c.appendEntry(AuditEntry{Name: name, Removed: true})
+}
+
+// auditRecovery records the actions of the report in the audit journal if WithAudit is set.
+func (c *config) auditRecovery(report *RecoveryReport) error {
+	if c.auditLog == "" {
+		return nil
+	}
+	for _, f := range report.Files {
+		if err := c.appendEntry(AuditEntry{Name: f.Name, Recovery: f.Action, Error: f.Error}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendEntry adds the user and the time to the entry and appends it to the audit journal.
+func (c *config) appendEntry(e AuditEntry) error {
+	journal, err := c.path(c.auditLog)
+	if err != nil {
+		return err
+	}
+	e.User, e.Time = c.auditUser, time.Now().UTC()
+	if e.User == "" {
+		e.User = processUser()
+	}
+	if err := c.prepareDir(journal); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(journal, os.O_RDWR|os.O_CREATE, c.perm)
+	if err != nil {
+		return err
+	}
+	if err := c.acquireFile(f, true); err != nil && !errors.Is(err, errNotSupported) {
+		f.Close()
+		return err
+	}
+	err = c.appendAudit(f, e)
+	unlockFile(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// auditTail is the last entry of an audit journal as it was appended by this process.
+type auditTail struct {
+	// offset is the position of the record of the entry.
+	offset int64
+	// record is the encoded record of the entry.
+	record []byte
+	// chain is the Chain of the entry.
+	chain string
+}
+
+// auditTails are the last entries which this process appended, by the resolved name of the journal.
+var (
+	auditTailsMu sync.Mutex
+	auditTails   = make(map[string]auditTail)
+)
+
+// appendAudit links the entry to the last entry of the locked journal and appends it.
+// A torn entry of an interrupted append is overwritten.
+func (c *config) appendAudit(f *os.File, e AuditEntry) error {
+	prev, valid, err := lastChain(f)
+	if err != nil {
+		return err
+	}
+	if e.Chain, err = chainDigest(c.hash, prev, e); err != nil {
+		return err
+	}
+	rec, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	buf := encodeRecord(rec)
+
+	auditTailsMu.Lock()
+	delete(auditTails, f.Name())
+	auditTailsMu.Unlock()
+	if err := f.Truncate(valid); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(buf, valid); err != nil {
+		return err
+	}
+	if !c.noSync {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		if valid == 0 && c.dirSync >= DirSyncParent {
+			if err := syncDir(filepath.Dir(f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	auditTailsMu.Lock()
+	auditTails[f.Name()] = auditTail{offset: valid, record: buf, chain: e.Chain}
+	auditTailsMu.Unlock()
+	return nil
+}
+
+// lastChain returns the Chain of the last entry of the locked journal and the number of bytes
+// which contain valid entries. If the journal still ends with the entry which this process appended last,
+// only that entry is read. Otherwise, the whole chain is verified.
+func lastChain(f *os.File) (string, int64, error) {
+	auditTailsMu.Lock()
+	tail, ok := auditTails[f.Name()]
+	auditTailsMu.Unlock()
+	if ok {
+		info, err := f.Stat()
+		if err != nil {
+			return "", 0, err
+		}
+		end := tail.offset + int64(len(tail.record))
+		if info.Size() == end {
+			buf := make([]byte, len(tail.record))
+			if _, err := f.ReadAt(buf, tail.offset); err == nil && bytes.Equal(buf, tail.record) {
+				return tail.chain, end, nil
+			}
+		}
+	}
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", 0, err
+	}
+	entries, valid, err := scanAudit(f.Name(), data)
+	if err != nil || len(entries) == 0 {
+		return "", valid, err
+	}
+	return entries[len(entries)-1].Chain, valid, nil
+}
+
+// scanAudit decodes the entries of the journal, verifies their chain and returns them
+// with the number of bytes which contain valid entries. Only an incomplete last record is ignored,
+// because an interrupted append leaves it behind.
+func scanAudit(journal string, data []byte) ([]AuditEntry, int64, error) {
+	tampered := &os.PathError{Op: "audit", Path: journal, Err: ErrAuditTampered}
+	records, valid := scanRecords(data)
+	rest := data[valid:]
+	if len(rest) >= recordHeaderSize && int(binary.BigEndian.Uint32(rest)) <= len(rest)-recordHeaderSize {
+		// The record is complete but does not match its checksum.
+		return nil, 0, tampered
+	}
+	entries := make([]AuditEntry, 0, len(records))
+	prev := ""
+	for _, rec := range records {
+		var e AuditEntry
+		if err := json.Unmarshal(rec, &e); err != nil {
+			return nil, 0, tampered
+		}
+		i := strings.IndexByte(e.Chain, ':')
+		if i < 0 {
+			return nil, 0, tampered
+		}
+		chain, err := chainDigest(e.Chain[:i], prev, e)
+		if err != nil {
+			return nil, 0, err
+		}
+		if chain != e.Chain {
+			return nil, 0, tampered
+		}
+		prev = e.Chain
+		entries = append(entries, e)
+	}
+	return entries, valid, nil
+}
+
+// chainDigest computes the Chain of the entry which follows the entry with the Chain prev.
+func chainDigest(algorithm string, prev string, e AuditEntry) (string, error) {
+	e.Chain = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return Digest(algorithm, append([]byte(prev), b...))
+}
+
+// processUser returns the name of the user of the process or its ID if the name can not be looked up.
+func processUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "uid:" + strconv.Itoa(os.Getuid())
+}
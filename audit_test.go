@@ -0,0 +1,216 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadAudit(t *testing.T) {
+	t.Run("should record every write of a Manager", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		m := New(WithPrefix("testdir"), WithAudit("audit.log"))
+
+		if err := m.WriteFile("a", []byte("1"), WithAuditUser("alice")); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.WriteFile("b", []byte("2"), WithAuditUser("bob")); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.WriteFileVersioned("a", []byte("3"), WithAuditUser("alice")); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := m.ReadAudit("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expect 2 entries but got %d", len(entries))
+		}
+		for i, data := range []string{"1", "3"} {
+			digest, _ := Digest(DefaultHash, []byte(data))
+			if e := entries[i]; e.Name != filepath.Join("testdir", "a") || e.User != "alice" || e.Digest != digest || e.Time.IsZero() {
+				t.Errorf("unexpected entry %+v", e)
+			}
+		}
+
+		all, err := m.ReadAudit("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 3 || all[1].User != "bob" {
+			t.Errorf("expect the entries of all files but got %+v", all)
+		}
+	})
+
+	t.Run("should record the user of the process by default", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("data"), WithAudit("testdir/audit.log")); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := ReadAudit("testdir/testfile", WithAudit("testdir/audit.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].User != processUser() {
+			t.Errorf("expect an entry of %s but got %+v", processUser(), entries)
+		}
+	})
+
+	t.Run("should detect a modified entry", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		for _, user := range []string{"alice", "bob"} {
+			if err := WriteFile("testdir/testfile", []byte(user), WithAudit("testdir/audit.log"), WithAuditUser(user)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		data, err := ioutil.ReadFile("testdir/audit.log")
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, _ := scanRecords(data)
+		// Rewrite the last entry with a valid record checksum, so only the chain reveals the change.
+		forged := append(encodeRecord(records[0]), encodeRecord([]byte(strings.Replace(string(records[1]), "bob", "eve", 1)))...)
+		if err := ioutil.WriteFile("testdir/audit.log", forged, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ReadAudit("testdir/testfile", WithAudit("testdir/audit.log")); !errors.Is(err, ErrAuditTampered) || !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expect ErrAuditTampered but got %v", err)
+		}
+		if err := WriteFile("testdir/testfile", []byte("data"), WithAudit("testdir/audit.log")); !errors.Is(err, ErrAuditTampered) {
+			t.Errorf("expect WriteFile to refuse to extend the journal but got %v", err)
+		}
+	})
+
+	t.Run("should detect a removed entry", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		for _, data := range []string{"1", "2", "3"} {
+			if err := WriteFile("testdir/testfile", []byte(data), WithAudit("testdir/audit.log")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		data, err := ioutil.ReadFile("testdir/audit.log")
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, _ := scanRecords(data)
+		removed := append(encodeRecord(records[0]), encodeRecord(records[2])...)
+		if err := ioutil.WriteFile("testdir/audit.log", removed, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ReadAudit("", WithAudit("testdir/audit.log")); !errors.Is(err, ErrAuditTampered) {
+			t.Errorf("expect ErrAuditTampered but got %v", err)
+		}
+	})
+
+	t.Run("should overwrite a torn entry", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("1"), WithAudit("testdir/audit.log")); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.OpenFile("testdir/audit.log", os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(encodeRecord([]byte(`{"name":"testdir/testfile"}`))[:12])
+		f.Close()
+
+		if entries, err := ReadAudit("", WithAudit("testdir/audit.log")); err != nil || len(entries) != 1 {
+			t.Errorf("expect the torn entry to be ignored but got %d entries and %v", len(entries), err)
+		}
+		if err := WriteFile("testdir/testfile", []byte("2"), WithAudit("testdir/audit.log")); err != nil {
+			t.Fatal(err)
+		}
+		if entries, err := ReadAudit("", WithAudit("testdir/audit.log")); err != nil || len(entries) != 2 {
+			t.Errorf("expect 2 entries but got %d and %v", len(entries), err)
+		}
+	})
+
+	t.Run("should return ErrNoAudit without a journal", func(t *testing.T) {
+		if _, err := ReadAudit("testfile"); !errors.Is(err, ErrNoAudit) {
+			t.Errorf("expect ErrNoAudit but got %v", err)
+		}
+	})
+
+	t.Run("should verify the chain after another process appended", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("1"), WithAudit("testdir/audit.log")); err != nil {
+			t.Fatal(err)
+		}
+		// An entry with a valid record checksum which is not linked to the previous entry.
+		f, err := os.OpenFile("testdir/audit.log", os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(encodeRecord([]byte(`{"name":"testdir/testfile","chain":"sha256:0"}`)))
+		f.Close()
+
+		if err := WriteFile("testdir/testfile", []byte("2"), WithAudit("testdir/audit.log")); !errors.Is(err, ErrAuditTampered) {
+			t.Errorf("expect ErrAuditTampered but got %v", err)
+		}
+	})
+
+	t.Run("should record the writes and removals of all functions", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		opts := []Option{WithPrefix("testdir"), WithAudit("audit.log")}
+
+		if err := Update("a", func(old []byte) ([]byte, error) {
+			return []byte("a"), nil
+		}, opts...); err != nil {
+			t.Fatal(err)
+		}
+		f, err := Create("b", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("b"))
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := CopyFile("a", "c", 0600, opts...); err != nil {
+			t.Fatal(err)
+		}
+		if err := Exchange("a", "b", opts...); err != nil {
+			t.Fatal(err)
+		}
+		if err := MoveFile("c", "d", opts...); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFile("d", opts...); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := ReadAudit("", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			s := filepath.Base(e.Name)
+			if e.Removed {
+				s = "-" + s
+			}
+			got = append(got, s)
+		}
+		want := "a b c a b d -c -d"
+		if strings.Join(got, " ") != want {
+			t.Errorf("expect the entries %q but got %q", want, strings.Join(got, " "))
+		}
+	})
+}
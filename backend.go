@@ -0,0 +1,171 @@
+package safe
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Backend is the storage which is used by WriteFile, ReadFile and RemoveFile with WithBackend.
+// It abstracts the calls of the operating system, e.g. to test without touching the disk (see MemBackend)
+// or to store the files remotely. The errors must be compatible with os.IsNotExist and os.IsExist.
+type Backend interface {
+	// Create creates a new file with the name and the permissions for writing. It fails if the file exists.
+	Create(name string, perm os.FileMode) (io.WriteCloser, error)
+	// Link creates the name newname as a hard link to the file oldname. It fails if newname exists.
+	Link(oldname string, newname string) error
+	// Rename renames the file oldname to newname and replaces newname if it exists.
+	Rename(oldname string, newname string) error
+	// Remove removes the file with the name.
+	Remove(name string) error
+	// ReadFile reads the whole file with the name.
+	ReadFile(name string) ([]byte, error)
+	// Sync makes the file or directory with the name durable.
+	Sync(name string) error
+}
+
+// OSBackend is the Backend of the operating system.
+type OSBackend struct{}
+
+// Create creates the file with os.OpenFile.
+func (OSBackend) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+}
+
+// Link creates the hard link with os.Link.
+func (OSBackend) Link(oldname string, newname string) error {
+	return osLink(oldname, newname)
+}
+
+// Rename renames the file with os.Rename, or MoveFileEx with MOVEFILE_WRITE_THROUGH on Windows.
+func (OSBackend) Rename(oldname string, newname string) error {
+	return replaceFile(oldname, newname)
+}
+
+// Remove removes the file with os.Remove.
+func (OSBackend) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// ReadFile reads the file with ioutil.ReadFile.
+func (OSBackend) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+// Sync opens the file or directory and syncs it.
+func (OSBackend) Sync(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WithBackend makes WriteFile, ReadFile and RemoveFile use the Backend instead of the operating system.
+// The files are committed with the same procedure as on the operating system, so WithStrategy (StrategyHardlink,
+// StrategyRename and StrategyReplace), WithCommitStrategy, WithBackup and WithChecksum apply, as well as
+// transforms, validators and decompression. StrategyExchange falls back to StrategyHardlink, and StrategySymlink
+// is not supported. The options which rely on the operating system (e.g. WithFlock, WithShadowDir, WithMkdirAll,
+// WithIndex, WithIncludes, WithFencingToken, WithReproducible and WithAssertCommitted) are not supported
+// with a Backend, and WithStrict only verifies the links on the operating system. The other functions of this package always use the operating system.
+func WithBackend(b Backend) Option {
+	return func(c *config) {
+		c.backend = b
+	}
+}
+
+// storage returns the Backend of WithBackend or the OSBackend.
+func (c *config) storage() Backend {
+	return storageOf(c.backend)
+}
+
+// createBackendTemp writes the data to the temporary file tmp of the resolved name in the Backend and syncs it.
+func (c *config) createBackendTemp(name string, tmp string, data []byte) error {
+	if err := createIn(c.backend, tmp, data, c.perm, false); err != nil {
+		return err
+	}
+	if c.noSync {
+		return nil
+	}
+	start := time.Now()
+	err := c.backend.Sync(tmp)
+	c.observe(EventSynced, name, tmp, start, err)
+	return err
+}
+
+// createNew writes the data to a new file with the resolved name in the Backend of WithBackend
+// or on the operating system and syncs it like syncFile.
+func (c *config) createNew(name string, data []byte) error {
+	if c.backend != nil {
+		return createIn(c.backend, name, data, c.perm, !c.noSync)
+	}
+	return write(name, data, c.perm, c.syncFile)
+}
+
+// copyNew copies the file src to a new file dst in the Backend of WithBackend or on the operating system.
+func (c *config) copyNew(src string, dst string) error {
+	if c.backend != nil {
+		return copyBackend(c.backend, src, dst, c.perm, !c.noSync)
+	}
+	return copyFile(src, dst, !c.noSync)
+}
+
+// createIn writes the data to a new file with the name and the permissions in the Backend.
+// With sync, the file is synced.
+func createIn(b Backend, name string, data []byte, perm os.FileMode, sync bool) error {
+	f, err := b.Create(name, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if !sync {
+		return nil
+	}
+	return b.Sync(name)
+}
+
+// readBackend reads the resolved name or the alt name from the Backend and retries if neither exists.
+func (c *config) readBackend(name string, alt string) ([]byte, error) {
+	for i := 0; i < c.retries; i++ {
+		data, err := c.backend.ReadFile(name)
+		if !os.IsNotExist(err) {
+			return data, err
+		}
+		start := time.Now()
+		data, err = c.backend.ReadFile(alt)
+		if !os.IsNotExist(err) {
+			c.observe(EventAltRead, name, alt, start, err)
+			return data, err
+		}
+
+		if i == c.retries-1 {
+			break
+		}
+		if err := c.wait(name, i+1); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &os.PathError{Op: "read", Path: name, Err: ErrNotExist}
+}
+
+// removeBackend removes the files with the resolved names from the Backend. NotExist errors are ignored.
+func (c *config) removeBackend(names []string) error {
+	var errs MultiError
+	for _, n := range names {
+		if err := c.backend.Remove(n); err != nil && !os.IsNotExist(err) {
+			errs.add(n, err)
+		}
+	}
+	return errs.single()
+}
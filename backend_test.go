@@ -0,0 +1,139 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestWithBackend(t *testing.T) {
+	t.Run("should write and read the files in memory", func(t *testing.T) {
+		b := NewMemBackend()
+		if err := WriteFile("testdir/testfile", []byte("old data"), WithBackend(b)); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/testfile", []byte("new data"), WithBackend(b)); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/testfile")
+
+		if want := []string{"testdir/testfile", "testdir/testfile.1"}; !reflect.DeepEqual(b.Names(), want) {
+			t.Errorf("expect the files %v but got %v", want, b.Names())
+		}
+		data, err := ReadFile("testdir/testfile", WithBackend(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "new data" {
+			t.Errorf("expect %q but got %q", "new data", data)
+		}
+	})
+
+	t.Run("should fall back to the alt file", func(t *testing.T) {
+		b := NewMemBackend()
+		if err := WriteFile("testfile", []byte("some data"), WithBackend(b)); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Remove("testfile"); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ReadFile("testfile", WithBackend(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "some data" {
+			t.Errorf("expect %q but got %q", "some data", data)
+		}
+	})
+
+	t.Run("should remove the files", func(t *testing.T) {
+		b := NewMemBackend()
+		if err := WriteFile("testfile", []byte("some data"), WithBackend(b)); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFile("testfile", WithBackend(b)); err != nil {
+			t.Fatal(err)
+		}
+		if names := b.Names(); len(names) != 0 {
+			t.Errorf("expect no files but got %v", names)
+		}
+		if _, err := ReadFile("testfile", WithBackend(b), WithRetries(1)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expect ErrNotExist but got %v", err)
+		}
+	})
+
+	t.Run("should write to the disk with the OSBackend", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithBackend(OSBackend{})); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkContents(t, "testdir/testfile.1", "some data")
+	})
+
+	t.Run("should apply the strategy", func(t *testing.T) {
+		b := NewMemBackend()
+		for _, contents := range []string{"old data", "new data"} {
+			if err := WriteFile("testfile", []byte(contents), WithBackend(b), WithStrategy(StrategyReplace)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if want := []string{"testfile"}; !reflect.DeepEqual(b.Names(), want) {
+			t.Errorf("expect the files %v but got %v", want, b.Names())
+		}
+	})
+
+	t.Run("should call the commit strategy", func(t *testing.T) {
+		b := NewMemBackend()
+		called := false
+		commit := CommitFunc(func(tmp string, alt string, final string) error {
+			called = true
+			return b.Rename(tmp, final)
+		})
+		if err := WriteFile("testfile", []byte("some data"), WithBackend(b), WithCommitStrategy(commit)); err != nil {
+			t.Fatal(err)
+		}
+		if !called {
+			t.Error("expect the commit strategy to be called")
+		}
+		if data, err := b.ReadFile("testfile"); err != nil || string(data) != "some data" {
+			t.Errorf("expect %q but got %q and %v", "some data", data, err)
+		}
+	})
+
+	t.Run("should keep the backup and the checksum", func(t *testing.T) {
+		b := NewMemBackend()
+		opts := []Option{WithBackend(b), WithBackup(".bak"), WithChecksum()}
+		for _, contents := range []string{"old data", "new data"} {
+			if err := WriteFile("testfile", []byte(contents), opts...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if data, err := b.ReadFile("testfile.bak"); err != nil || string(data) != "old data" {
+			t.Errorf("expect the backup %q but got %q and %v", "old data", data, err)
+		}
+
+		if err := b.Remove("testfile"); err != nil {
+			t.Fatal(err)
+		}
+		f, err := b.Create("testfile", 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("bit rot"))
+		f.Close()
+		if _, err := ReadFile("testfile", opts...); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("expect ErrChecksumMismatch but got %v", err)
+		}
+
+		if err := RemoveFile("testfile", opts...); err != nil {
+			t.Fatal(err)
+		}
+		if names := b.Names(); len(names) != 0 {
+			t.Errorf("expect no files but got %v", names)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package safe
+
+import (
+	"os"
+	"time"
+)
+
+// WithBackup makes WriteFile keep the previous contents of a file as $(name)$(suffix) (e.g. config.json.bak)
+// after each successful write, so the last good version can be restored by hand. Unlike WriteFileVersioned,
+// only one backup is kept. The backup is a hard link to the previous contents, or a copy if the filesystem does
+// not support hard links, and it is replaced atomically. It is not updated if the write fails.
+// The suffix must differ from the alt suffix. RemoveFile removes the backup as well.
+func WithBackup(suffix string) Option {
+	return func(c *config) {
+		c.backup = suffix
+	}
+}
+
+// stageBackup links the current contents of the resolved name to a temporary file of its backup
+// and returns the name of the temporary file, or "" if there is nothing to back up.
+func (c *config) stageBackup(name string, t time.Time) (string, error) {
+	if c.backup == "" {
+		return "", nil
+	}
+	b := c.storage()
+	staged := c.tempName(name+c.backup, t)
+	// The alt file contains the current contents if a previous write was interrupted.
+	for _, src := range []string{name, c.altName(name)} {
+		err := b.Link(src, staged)
+		if err != nil && !os.IsNotExist(err) {
+			err = c.copyNew(src, staged)
+		}
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			b.Remove(staged)
+			return "", err
+		}
+		return staged, nil
+	}
+	return "", nil
+}
+
+// finishBackup renames the staged backup of the resolved name over the backup if the write succeeded
+// and removes it otherwise. It returns the error of the write or of the rename.
+func (c *config) finishBackup(name string, staged string, err error) error {
+	if staged == "" {
+		return err
+	}
+	b := c.storage()
+	if err != nil {
+		b.Remove(staged)
+		return err
+	}
+	if err := b.Rename(staged, name+c.backup); err != nil {
+		b.Remove(staged)
+		return err
+	}
+	if c.dirSync >= DirSyncParent {
+		return c.syncParent(name)
+	}
+	return nil
+}
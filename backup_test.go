@@ -0,0 +1,97 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithBackup(t *testing.T) {
+	t.Run("should keep the previous contents", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, contents := range []string{"first", "second", "third"} {
+			if err := WriteFile("testdir/testfile", []byte(contents), WithBackup(".bak")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkContents(t, "testdir/testfile", "third")
+		checkContents(t, "testdir/testfile.bak", "second")
+	})
+
+	t.Run("should not create a backup for a new file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("first"), WithBackup(".bak")); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/testfile.bak")
+	})
+
+	t.Run("should back up the alt file after an interrupted write", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile.1", "interrupted")
+
+		if err := WriteFile("testdir/testfile", []byte("new"), WithBackup(".bak")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile.bak", "interrupted")
+	})
+
+	t.Run("should copy the previous contents without hard links", func(t *testing.T) {
+		defer withoutLinks()()
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		opts := []Option{WithBackup(".bak"), WithStrategy(StrategyRename)}
+		for _, contents := range []string{"first", "second"} {
+			if err := WriteFile("testdir/testfile", []byte(contents), opts...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkContents(t, "testdir/testfile.bak", "first")
+	})
+
+	t.Run("should keep the backup if the write fails", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		failure := errors.New("failure")
+		fail := WithCommitStrategy(CommitFunc(func(tmp string, alt string, final string) error {
+			return failure
+		}))
+
+		for _, contents := range []string{"first", "second"} {
+			if err := WriteFile("testdir/testfile", []byte(contents), WithBackup(".bak")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := WriteFile("testdir/testfile", []byte("third"), WithBackup(".bak"), fail); !errors.Is(err, failure) {
+			t.Fatalf("expect the error of the commit but got %v", err)
+		}
+		checkContents(t, "testdir/testfile.bak", "first")
+		names, err := readDirNames("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 3 {
+			t.Errorf("expect only the file, the alt file and the backup but got %v", names)
+		}
+	})
+
+	t.Run("should remove the backup with the file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, contents := range []string{"first", "second"} {
+			if err := WriteFile("testdir/testfile", []byte(contents), WithBackup(".bak")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := RemoveFile("testdir/testfile", WithBackup(".bak")); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/testfile.bak")
+	})
+}
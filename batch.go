@@ -0,0 +1,111 @@
+package safe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MultiError is returned by the batch functions and RemoveFile if some of the files failed.
+// It contains the error of each failed file, so the caller can retry only those.
+type MultiError struct {
+	// Errors maps the names of the failed files to their errors.
+	Errors map[string]error
+}
+
+// Error lists the errors of all failed files.
+func (e *MultiError) Error() string {
+	names := e.Failed()
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = e.Errors[name].Error()
+	}
+	return fmt.Sprintf("safe: %d files failed: %s", len(names), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed files sorted by name, so errors.Is and errors.As check each of them.
+func (e *MultiError) Unwrap() []error {
+	names := e.Failed()
+	errs := make([]error, len(names))
+	for i, name := range names {
+		errs[i] = e.Errors[name]
+	}
+	return errs
+}
+
+// Failed returns the names of the failed files in sorted order.
+func (e *MultiError) Failed() []string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// add records the error of the file with the name.
+func (e *MultiError) add(name string, err error) {
+	if e.Errors == nil {
+		e.Errors = make(map[string]error)
+	}
+	e.Errors[name] = err
+}
+
+// err returns the MultiError or nil if no file failed.
+func (e *MultiError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// single returns the error of the only failed file, the MultiError if several files failed or nil.
+func (e *MultiError) single() error {
+	if len(e.Errors) == 1 {
+		for _, err := range e.Errors {
+			return err
+		}
+	}
+	return e.err()
+}
+
+// WriteFiles writes each of the files like WriteFile. The files are written independently,
+// so if some of them fail, the others are still written. Use Pipeline to write the files all-or-nothing.
+// If a file fails, a *MultiError is returned.
+func WriteFiles(files map[string][]byte, opts ...Option) error {
+	var errs MultiError
+	for name, data := range files {
+		if err := WriteFile(name, data, opts...); err != nil {
+			errs.add(name, err)
+		}
+	}
+	return errs.err()
+}
+
+// ReadFiles reads each of the files like ReadFile and returns their contents by name.
+// If a file fails, the contents of the others are returned together with a *MultiError.
+func ReadFiles(names []string, opts ...Option) (map[string][]byte, error) {
+	var errs MultiError
+	files := make(map[string][]byte, len(names))
+	for _, name := range names {
+		data, err := ReadFile(name, opts...)
+		if err != nil {
+			errs.add(name, err)
+			continue
+		}
+		files[name] = data
+	}
+	return files, errs.err()
+}
+
+// RemoveFiles removes each of the files like RemoveFile.
+// If a file fails, the others are still removed and a *MultiError is returned.
+func RemoveFiles(names []string, opts ...Option) error {
+	var errs MultiError
+	for _, name := range names {
+		if err := RemoveFile(name, opts...); err != nil {
+			errs.add(name, err)
+		}
+	}
+	return errs.err()
+}
@@ -0,0 +1,74 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWriteFiles(t *testing.T) {
+	t.Run("should write the other files if a file fails", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		err := WriteFiles(map[string][]byte{
+			"testdir/a":   []byte("data a"),
+			"testdir/b.1": []byte("data b"),
+		})
+		var multi *MultiError
+		if !errors.As(err, &multi) {
+			t.Fatalf("expect a *MultiError but got %v", err)
+		}
+		if failed := multi.Failed(); len(failed) != 1 || failed[0] != "testdir/b.1" {
+			t.Errorf("expect testdir/b.1 to fail but got %v", failed)
+		}
+		if !errors.Is(err, ErrReservedName) {
+			t.Errorf("expect the error to wrap ErrReservedName but got %v", err)
+		}
+		checkContents(t, "testdir/a", "data a")
+	})
+
+	t.Run("should return nil if all files are written", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFiles(map[string][]byte{"testdir/a": []byte("data a")}); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestReadFiles(t *testing.T) {
+	t.Run("should return the contents of the files which could be read", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/a", "data a")
+
+		files, err := ReadFiles([]string{"testdir/a", "testdir/b"}, WithRetries(1))
+		var multi *MultiError
+		if !errors.As(err, &multi) {
+			t.Fatalf("expect a *MultiError but got %v", err)
+		}
+		if _, ok := multi.Errors["testdir/b"]; !ok || len(multi.Errors) != 1 {
+			t.Errorf("expect testdir/b to fail but got %v", multi.Errors)
+		}
+		if string(files["testdir/a"]) != "data a" {
+			t.Errorf("expect data a but got %q", files["testdir/a"])
+		}
+	})
+}
+
+func TestRemoveFiles(t *testing.T) {
+	t.Run("should remove the files", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFiles(map[string][]byte{"testdir/a": nil, "testdir/b": nil}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := RemoveFiles([]string{"testdir/a", "testdir/b"}); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/a")
+		checkNotExist(t, "testdir/b")
+	})
+}
@@ -0,0 +1,51 @@
+package safe
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which buffers are not returned to the pool to avoid holding on to memory.
+const maxPooledBuffer = 16 << 20
+
+// buffers is the pool used by GetBuffer, WriteBuffer and WriteFileOwned.
+var buffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// GetBuffer returns an empty buffer from the pool of this package.
+// Fill it with the contents of a file and pass it to WriteBuffer, which returns it to the pool.
+func GetBuffer() *bytes.Buffer {
+	buf := buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// WriteBuffer writes the contents of the buffer like WriteFile and returns the buffer to the pool.
+// The buffer must not be used after WriteBuffer was called, not even if an error is returned.
+func WriteBuffer(name string, buf *bytes.Buffer, opts ...Option) error {
+	err := WriteFile(name, buf.Bytes(), opts...)
+	release(buf)
+	return err
+}
+
+// WriteFileOwned writes the data like WriteFile and takes the ownership of the data.
+// The underlying array of the data is returned to the pool and is reused by GetBuffer, so the caller must
+// not use the data after WriteFileOwned was called. In return, the caller does not need to copy data which
+// is still used elsewhere, because WriteFileOwned is documented to be its last user.
+func WriteFileOwned(name string, data []byte, opts ...Option) error {
+	err := WriteFile(name, data, opts...)
+	release(bytes.NewBuffer(data[:0]))
+	return err
+}
+
+// release returns the buffer to the pool unless it is too large.
+func release(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	buffers.Put(buf)
+}
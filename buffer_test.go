@@ -0,0 +1,39 @@
+package safe
+
+import "testing"
+
+func TestWriteBuffer(t *testing.T) {
+	t.Run("should write the contents of the buffer", func(t *testing.T) {
+		buf := GetBuffer()
+		buf.WriteString("some important data")
+
+		if err := WriteBuffer("testfile", buf); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		checkContents(t, "testfile", "some important data")
+	})
+
+	t.Run("should return an empty buffer", func(t *testing.T) {
+		buf := GetBuffer()
+		if buf.Len() != 0 {
+			t.Errorf("expect an empty buffer but got %q", buf.String())
+		}
+	})
+}
+
+func TestWriteFileOwned(t *testing.T) {
+	t.Run("should write the data", func(t *testing.T) {
+		data := []byte("some important data")
+
+		if err := WriteFileOwned("testfile", data); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		checkContents(t, "testfile", "some important data")
+	})
+}
@@ -0,0 +1,161 @@
+package safe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChecksumPostfix is the extension appended to the name of a file to get the name of its sidecar checksum file
+// for WithChecksum.
+const ChecksumPostfix = ".sha256"
+
+// WithChecksum makes WriteFile record the SHA-256 checksum of the contents in the sidecar file $(name).sha256
+// and ReadFile verify the contents against it, so bit rot and partially restored backups, which the links
+// can not detect, are noticed. If the contents do not match, ReadFile returns a *os.PathError wrapping
+// ErrChecksumMismatch, which matches ErrCorrupt. A file without a sidecar is read without verification.
+// The sidecar has the format of sha256sum, so it can be checked with sha256sum -c as well.
+// While a file is committed, its sidecar lists the checksums of the previous and the new contents,
+// so the file matches its sidecar at every step, even if the write is interrupted.
+// RemoveFile removes the sidecar as well.
+func WithChecksum() Option {
+	return func(c *config) {
+		c.checksum = true
+	}
+}
+
+// commitChecked commits the tmpname to the resolved name like commitTemp and updates the sidecar
+// of WithChecksum before and after the commit.
+func (c *config) commitChecked(tmpname string, name string, size int64, t time.Time, data []byte) error {
+	if !c.checksum {
+		return c.commitTemp(tmpname, name, size, t, data)
+	}
+	sum := checksum(data)
+	sums, err := c.currentChecksums(name)
+	if err != nil {
+		return err
+	}
+	if err := c.writeChecksums(name, t, append([]string{sum}, sums...)); err != nil {
+		return err
+	}
+	if err := c.commitTemp(tmpname, name, size, t, data); err != nil {
+		return err
+	}
+	return c.writeChecksums(name, t, []string{sum})
+}
+
+// currentChecksums returns the checksums which the resolved name can match before a commit:
+// the checksums of its sidecar or, if it has none yet, the checksums of the name and the alt name.
+func (c *config) currentChecksums(name string) ([]string, error) {
+	sums, err := c.readChecksums(name)
+	if !os.IsNotExist(err) {
+		return sums, err
+	}
+	sums = nil
+	for _, n := range []string{name, c.altName(name)} {
+		data, err := c.storage().ReadFile(n)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sums = append(sums, checksum(data))
+	}
+	return sums, nil
+}
+
+// writeChecksums atomically replaces the sidecar of the resolved name with the checksums.
+func (c *config) writeChecksums(name string, t time.Time, sums []string) error {
+	var b strings.Builder
+	seen := make(map[string]bool, len(sums))
+	for _, sum := range sums {
+		if !seen[sum] {
+			seen[sum] = true
+			b.WriteString(sum + "  " + filepath.Base(name) + "\n")
+		}
+	}
+	sidecar := name + ChecksumPostfix
+	tmp := c.tempName(sidecar, t)
+	if err := c.createNew(tmp, []byte(b.String())); err != nil {
+		c.storage().Remove(tmp)
+		return err
+	}
+	if err := c.storage().Rename(tmp, sidecar); err != nil {
+		c.storage().Remove(tmp)
+		return err
+	}
+	if c.dirSync >= DirSyncParent {
+		return c.syncParent(name)
+	}
+	return nil
+}
+
+// moveChecksums moves the sidecar of the resolved oldname to the newname for MoveFile, so the sidecar follows
+// the contents. With WithChecksum, the commit already wrote the sidecar of the newname.
+func (c *config) moveChecksums(oldname string, newname string, t time.Time) error {
+	if !c.checksum {
+		sums, err := c.readChecksums(oldname)
+		switch {
+		case os.IsNotExist(err):
+			// A sidecar of the newname belongs to the replaced contents.
+			err = remove(newname + ChecksumPostfix)
+		case err == nil:
+			err = c.writeChecksums(newname, t, sums)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return remove(oldname + ChecksumPostfix)
+}
+
+// verifyChecksum checks the contents of the resolved name against its sidecar.
+// A concurrent write can replace the file after its contents were read, so they are read again on a mismatch.
+func (c *config) verifyChecksum(name string, data []byte) ([]byte, error) {
+	for i := 1; ; i++ {
+		sums, err := c.readChecksums(name)
+		if os.IsNotExist(err) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := checksum(data)
+		for _, s := range sums {
+			if s == sum {
+				return data, nil
+			}
+		}
+		if i >= c.retries {
+			return nil, &os.PathError{Op: "read", Path: name, Err: ErrChecksumMismatch}
+		}
+		if data, err = c.readContents(name); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readChecksums returns the checksums of the sidecar of the resolved name.
+func (c *config) readChecksums(name string) ([]string, error) {
+	data, err := c.storage().ReadFile(name + ChecksumPostfix)
+	if err != nil {
+		return nil, err
+	}
+	var sums []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			sums = append(sums, strings.ToLower(fields[0]))
+		}
+	}
+	return sums, nil
+}
+
+// checksum returns the hex encoded SHA-256 checksum of the data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
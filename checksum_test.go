@@ -0,0 +1,124 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestWithChecksum(t *testing.T) {
+	t.Run("should write the checksum in the format of sha256sum", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, contents := range []string{"first", "second"} {
+			if err := WriteFile("testdir/testfile", []byte(contents), WithChecksum()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkContents(t, "testdir/testfile.sha256", checksum([]byte("second"))+"  testfile\n")
+
+		got, err := ReadFile("testdir/testfile", WithChecksum())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "second" {
+			t.Errorf("expect %q but got %q", "second", got)
+		}
+	})
+
+	t.Run("should return ErrCorrupt if the contents do not match", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some important data"), WithChecksum()); err != nil {
+			t.Fatal(err)
+		}
+		// Flip a byte in place, like bit rot, which changes the name and the alt name.
+		f, err := os.OpenFile("testdir/testfile", os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteAt([]byte("S"), 0)
+		f.Close()
+
+		_, err = ReadFile("testdir/testfile", WithChecksum())
+		if !errors.Is(err, ErrChecksumMismatch) || !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expect ErrChecksumMismatch but got %v", err)
+		}
+		if _, err := ReadFile("testdir/testfile"); err != nil {
+			t.Errorf("expect the file to be read without WithChecksum but got %v", err)
+		}
+	})
+
+	t.Run("should read a file without a checksum", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile", "data")
+
+		if _, err := ReadFile("testdir/testfile", WithChecksum()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("should match both versions after an interrupted write", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		interrupted := errors.New("interrupted")
+		// Link the new contents to the alt name only, like a write which was interrupted before the name was linked.
+		crash := WithCommitStrategy(CommitFunc(func(tmp string, alt string, final string) error {
+			os.Remove(alt)
+			if err := os.Link(tmp, alt); err != nil {
+				return err
+			}
+			return interrupted
+		}))
+
+		if err := WriteFile("testdir/testfile", []byte("old"), WithChecksum()); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/testfile", []byte("new"), WithChecksum(), crash); !errors.Is(err, interrupted) {
+			t.Fatalf("expect the error of the commit but got %v", err)
+		}
+
+		if got, err := ReadFile("testdir/testfile", WithChecksum()); err != nil || string(got) != "old" {
+			t.Errorf("expect the old contents but got %q and %v", got, err)
+		}
+		if err := os.Remove("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ReadFile("testdir/testfile", WithChecksum()); err != nil || string(got) != "new" {
+			t.Errorf("expect the new contents of the alt file but got %q and %v", got, err)
+		}
+	})
+
+	t.Run("should record the checksum of a streamed file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		f, err := Create("testdir/testfile", WithChecksum())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("streamed")); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile.sha256", checksum([]byte("streamed"))+"  testfile\n")
+	})
+
+	t.Run("should remove the checksum with the file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("data"), WithChecksum()); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFile("testdir/testfile", WithChecksum()); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/testfile.sha256")
+	})
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package safe
+
+import "os"
+
+// cloneFile is not supported on this platform.
+func cloneFile(dst *os.File, src *os.File) error {
+	return errNotSupported
+}
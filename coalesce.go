@@ -0,0 +1,80 @@
+package safe
+
+import (
+	"os"
+	"sync"
+)
+
+// flight is a read which is shared by concurrent calls.
+type flight struct {
+	done chan struct{}
+	data []byte
+	err  error
+	// canceled is true if the read stopped because the context of the call which started it was done.
+	canceled bool
+}
+
+// flightKey identifies the reads which can be shared. Calls which read the same files with the same number of
+// retries get the same result. The name is resolved and the contents are decompressed and verified by each call.
+type flightKey struct {
+	name    string
+	alt     string
+	retries int
+}
+
+// flights contains the reads which are currently in progress by key.
+var flights = struct {
+	sync.Mutex
+	m map[flightKey]*flight
+}{m: make(map[flightKey]*flight)}
+
+// WithCoalescing makes concurrent ReadFile calls for the same file share a single read from the disk.
+// A call which starts while another call with the same name, alt name and retries is reading the file
+// waits for that read and gets a copy of its result. It stops waiting when its own context is done.
+// This helps when many goroutines read the same file at once, e.g. after a cache miss.
+func WithCoalescing() Option {
+	return func(c *config) {
+		c.coalesce = true
+	}
+}
+
+// readShared reads the file with the name or the alt name or joins a read of the file which is already in progress.
+// If the read which was joined stops because the context of its caller is done, the file is read again.
+func (c *config) readShared(name string, alt string) ([]byte, error) {
+	key := flightKey{name: name, alt: alt, retries: c.retries}
+	for {
+		flights.Lock()
+		f, ok := flights.m[key]
+		if ok {
+			flights.Unlock()
+			select {
+			case <-f.done:
+			case <-c.ctx.Done():
+				return nil, &os.PathError{Op: "read", Path: name, Err: c.ctx.Err()}
+			}
+			if f.canceled {
+				continue
+			}
+		} else {
+			f = &flight{done: make(chan struct{})}
+			flights.m[key] = f
+			flights.Unlock()
+
+			f.data, f.err = c.read(name, alt)
+			f.canceled = f.err != nil && c.ctx.Err() != nil
+
+			flights.Lock()
+			delete(flights.m, key)
+			flights.Unlock()
+			close(f.done)
+		}
+
+		if f.err != nil {
+			return nil, f.err
+		}
+		// Every caller gets its own copy in case it modifies the data.
+		data := make([]byte, len(f.data))
+		copy(data, f.data)
+		return data, nil
+	}
+}
@@ -0,0 +1,107 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadFileWithCoalescing(t *testing.T) {
+	t.Run("should return the contents of the file to all concurrent callers", func(t *testing.T) {
+		createFile(t, "testfile", "some important data")
+		defer clean(t, "testfile")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := ReadFile("testfile", WithCoalescing())
+				if err != nil {
+					t.Error(err)
+				} else if string(got) != "some important data" {
+					t.Errorf("ReadFile does not return the correct file contents. Want %q but got %q", "some important data", got)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("should not share a read with other retries", func(t *testing.T) {
+		defer clean(t, "testfile")
+		done := make(chan error, 1)
+		go func() {
+			_, err := ReadFile("testfile", WithCoalescing(), WithRetries(100))
+			done <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		start := time.Now()
+		if _, err := ReadFile("testfile", WithCoalescing(), WithRetries(1)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expect ErrNotExist but got %v", err)
+		}
+		if d := time.Since(start); d > 100*time.Millisecond {
+			t.Errorf("expect the read not to wait for the other retries but it took %v", d)
+		}
+
+		createFile(t, "testfile", "some data")
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("should stop waiting when the context of a caller is canceled", func(t *testing.T) {
+		defer clean(t, "testfile")
+		done := make(chan error, 1)
+		go func() {
+			_, err := ReadFile("testfile", WithCoalescing(), WithRetries(100))
+			done <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		start := time.Now()
+		if _, err := ReadFileContext(ctx, "testfile", WithCoalescing(), WithRetries(100)); !errors.Is(err, context.Canceled) {
+			t.Errorf("expect context.Canceled but got %v", err)
+		}
+		if d := time.Since(start); d > 200*time.Millisecond {
+			t.Errorf("expect the canceled caller to return immediately but it took %v", d)
+		}
+
+		createFile(t, "testfile", "some data")
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("should not return the context error of another caller", func(t *testing.T) {
+		defer clean(t, "testfile")
+		ctx, cancel := context.WithCancel(context.Background())
+		canceled := make(chan error, 1)
+		go func() {
+			_, err := ReadFileContext(ctx, "testfile", WithCoalescing(), WithRetries(100))
+			canceled <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := ReadFile("testfile", WithCoalescing(), WithRetries(100))
+			done <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		if err := <-canceled; !errors.Is(err, context.Canceled) {
+			t.Errorf("expect context.Canceled but got %v", err)
+		}
+
+		createFile(t, "testfile", "some data")
+		if err := <-done; err != nil {
+			t.Errorf("expect the file to be read but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package safe
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CommitStrategy commits a completely written temporary file, so the final name points to its contents.
+// The alt name may be used to keep a complete version while the final name is replaced.
+// The temporary file is removed after Commit returns if it still exists.
+type CommitStrategy interface {
+	Commit(tmp string, alt string, final string) error
+}
+
+// CommitFunc is a function which implements CommitStrategy.
+type CommitFunc func(tmp string, alt string, final string) error
+
+// Commit calls the function.
+func (f CommitFunc) Commit(tmp string, alt string, final string) error {
+	return f(tmp, alt, final)
+}
+
+// WithCommitStrategy makes WriteFile and Create commit the files with the CommitStrategy,
+// e.g. to use the commit mechanics of a specific platform or filesystem.
+// It takes precedence over WithStrategy and WithRenameFallback. WithAssertCommitted is not applied.
+func WithCommitStrategy(s CommitStrategy) Option {
+	return func(c *config) {
+		c.committer = s
+	}
+}
+
+// HardlinkCommit hard links the temporary file to the alt name and the alt name to the final name.
+// It is used by StrategyHardlink. With Strict, the result of each step is verified.
+type HardlinkCommit struct {
+	Strict bool
+
+	// report is called with the steps of the commit if it is not nil.
+	report func(kind EventKind, path string, start time.Time, err error)
+	// backend contains the files. It is the operating system if it is nil.
+	backend Backend
+}
+
+// Commit links the tmp file to the alt name and the final name.
+func (h HardlinkCommit) Commit(tmp string, alt string, final string) error {
+	return safelink(storageOf(h.backend), tmp, alt, final, h.Strict, h.report)
+}
+
+// RenameCommit renames the temporary file to the final name and removes a stale alt name.
+// It is used by StrategyReplace.
+type RenameCommit struct {
+	// backend contains the files. It is the operating system if it is nil.
+	backend Backend
+}
+
+// Commit renames the tmp file to the final name.
+func (r RenameCommit) Commit(tmp string, alt string, final string) error {
+	b := storageOf(r.backend)
+	if err := b.Rename(tmp, final); err != nil {
+		return err
+	}
+	if err := b.Remove(alt); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CopyRenameCommit renames a copy of the temporary file to the alt name and the temporary file itself
+// to the final name. It is used by StrategyRename. With Sync, the copy is synced before it is renamed.
+type CopyRenameCommit struct {
+	Sync bool
+
+	// backend contains the files and perm are the permissions of the copy if backend is not nil.
+	backend Backend
+	perm    os.FileMode
+}
+
+// Commit renames a copy of the tmp file to the alt name and the tmp file to the final name.
+func (r CopyRenameCommit) Commit(tmp string, alt string, final string) error {
+	b := storageOf(r.backend)
+	cp := copyName(tmp, alt, final)
+	var err error
+	if r.backend == nil {
+		err = copyFile(tmp, cp, r.Sync)
+	} else {
+		err = copyBackend(r.backend, tmp, cp, r.perm, r.Sync)
+	}
+	if err != nil {
+		b.Remove(cp)
+		return err
+	}
+	if err := b.Rename(cp, alt); err != nil {
+		b.Remove(cp)
+		return err
+	}
+	return b.Rename(tmp, final)
+}
+
+// storageOf returns the Backend, or the OSBackend if it is nil.
+func storageOf(b Backend) Backend {
+	if b == nil {
+		return OSBackend{}
+	}
+	return b
+}
+
+// copyBackend copies the contents of the file src of the Backend to a new file dst with the permissions.
+func copyBackend(b Backend, src string, dst string, perm os.FileMode, sync bool) error {
+	data, err := b.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return createIn(b, dst, data, perm, sync)
+}
+
+// copyName returns the name of the copy of the tmp file for the alt name.
+// The copy gets the suffix of the tmp file, so it is recognized as a temporary file of the alt name.
+func copyName(tmp string, alt string, final string) string {
+	suffix := strings.TrimPrefix(filepath.Base(tmp), filepath.Base(final))
+	if suffix == filepath.Base(tmp) {
+		suffix = ".copy"
+	}
+	return alt + suffix
+}
+
+// ExchangeCommit atomically swaps the temporary file and the final name (RENAME_EXCHANGE on Linux)
+// and moves the previous version to the alt name, so it can be restored.
+// If the final name does not exist yet, the temporary file is renamed to it.
+// On other platforms and filesystems without support for exchanges, Commit returns an error.
+type ExchangeCommit struct{}
+
+// Commit swaps the tmp file with the final name and moves the previous version to the alt name.
+func (ExchangeCommit) Commit(tmp string, alt string, final string) error {
+	err := renameExchange(tmp, final)
+	if os.IsNotExist(err) {
+		if _, statErr := os.Lstat(final); os.IsNotExist(statErr) {
+			return RenameCommit{}.Commit(tmp, alt, final)
+		}
+	}
+	if err != nil {
+		return &os.LinkError{Op: "exchange", Old: tmp, New: final, Err: err}
+	}
+	return os.Rename(tmp, alt)
+}
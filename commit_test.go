@@ -0,0 +1,75 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithCommitStrategy(t *testing.T) {
+	t.Run("should commit the file with the strategy", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		var got []string
+		s := CommitFunc(func(tmp string, alt string, final string) error {
+			got = append(got, alt, final)
+			return RenameCommit{}.Commit(tmp, alt, final)
+		})
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithCommitStrategy(s)); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0] != "testdir/testfile.1" || got[1] != "testdir/testfile" {
+			t.Errorf("expect the alt name and the name but got %v", got)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkNotExist(t, "testdir/testfile.1")
+	})
+
+	t.Run("should return the error of the strategy", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		want := errors.New("commit failed")
+		s := CommitFunc(func(tmp string, alt string, final string) error {
+			return want
+		})
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithCommitStrategy(s)); err != want {
+			t.Errorf("expect the error of the strategy but got %v", err)
+		}
+		checkNotExist(t, "testdir/testfile")
+	})
+}
+
+func TestCopyRenameCommit(t *testing.T) {
+	t.Run("should commit a copy to the alt name", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithCommitStrategy(CopyRenameCommit{})); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkContents(t, "testdir/testfile.1", "some data")
+	})
+}
+
+func TestExchangeCommit(t *testing.T) {
+	t.Run("should keep the previous version as the alt file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		opt := WithCommitStrategy(ExchangeCommit{})
+		if err := WriteFile("testdir/testfile", []byte("old data"), opt); err != nil {
+			t.Fatal(err)
+		}
+		err := WriteFile("testdir/testfile", []byte("new data"), opt)
+		if errors.Is(err, errNotSupported) {
+			t.Skip("exchanges are not supported on this platform")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "new data")
+		checkContents(t, "testdir/testfile.1", "old data")
+	})
+}
@@ -0,0 +1,26 @@
+package safe
+
+import "context"
+
+// WriteFileContext works like WriteFile but stops when the context is done.
+// A canceled write is stopped before it is committed and its temporary file is removed,
+// so the previous contents of the file stay untouched. Waiting for a lock (see WithLock) is canceled as well.
+// Once the commit has started, it is completed. The returned error wraps the error of the context.
+func WriteFileContext(ctx context.Context, name string, data []byte, opts ...Option) error {
+	return WriteFile(name, data, withContext(ctx, opts)...)
+}
+
+// ReadFileContext works like ReadFile but stops retrying when the context is done.
+// The returned error wraps the error of the context.
+func ReadFileContext(ctx context.Context, name string, opts ...Option) ([]byte, error) {
+	return ReadFile(name, withContext(ctx, opts)...)
+}
+
+// withContext appends an option which sets the context of the call to the options.
+func withContext(ctx context.Context, opts []Option) []Option {
+	all := make([]Option, 0, len(opts)+1)
+	all = append(all, opts...)
+	return append(all, func(c *config) {
+		c.ctx = ctx
+	})
+}
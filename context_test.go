@@ -0,0 +1,72 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestWriteFileContext(t *testing.T) {
+	t.Run("should write the file", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		if err := WriteFileContext(context.Background(), "testfile", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "data")
+	})
+
+	t.Run("should not commit a canceled write and remove the temporary file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := WriteFileContext(ctx, "testdir/testfile", []byte("new data"))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expect context.Canceled but got %v", err)
+		}
+		checkContents(t, "testdir/testfile", "old data")
+		if infos, _ := ioutil.ReadDir("testdir"); len(infos) != 2 {
+			t.Errorf("expect only the file and its alt file but got %d files", len(infos))
+		}
+	})
+
+	t.Run("should stop waiting for the lock", func(t *testing.T) {
+		defer clean(t, "testfile")
+		f, err := Create("testfile", WithLock())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Abort()
+
+		ctx, cancel := context.WithTimeout(context.Background(), SleepTime)
+		defer cancel()
+		err = WriteFileContext(ctx, "testfile", []byte("data"), WithLock())
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expect context.DeadlineExceeded but got %v", err)
+		}
+	})
+}
+
+func TestReadFileContext(t *testing.T) {
+	t.Run("should stop retrying when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		_, err := ReadFileContext(ctx, "testfile", WithRetries(100))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expect context.Canceled but got %v", err)
+		}
+		if d := time.Since(start); d > 10*SleepTime {
+			t.Errorf("expect ReadFileContext to return right away but took %v", d)
+		}
+	})
+}
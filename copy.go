@@ -0,0 +1,102 @@
+package safe
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// WithReflink makes CopyFile clone the contents of the source (FICLONE on Linux) if the filesystem
+// supports it (e.g. Btrfs or XFS), so the copy shares the blocks of the source until one of them is changed.
+// Otherwise, the contents are copied.
+func WithReflink() Option {
+	return func(c *config) {
+		c.reflink = true
+	}
+}
+
+// CopyFile copies the file with the name src to the name dst with the permissions perm.
+// The source is read with the same fallback to the alt file as ReadFile,
+// and the destination is written like WriteFile, so it always points to a complete version.
+// Transforms and validators are not applied to the contents.
+func CopyFile(src string, dst string, perm os.FileMode, opts ...Option) error {
+	c := newConfig(opts)
+	c.perm = perm
+	src, err := c.path(src)
+	if err != nil {
+		return err
+	}
+	dst, unlock, err := c.begin(dst)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if src == dst {
+		return nil
+	}
+
+	in, err := c.openSource(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	t := time.Now()
+	tmp := c.tempName(dst, t)
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if err := c.copyContents(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := c.syncFile(out); err != nil {
+		out.Close()
+		return err
+	}
+	info, err := out.Stat()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	var data []byte
+	if c.index || c.writeStats || c.checksum || c.auditLog != "" {
+		if data, err = ioutil.ReadFile(tmp); err != nil {
+			return err
+		}
+	}
+	if err := c.ctx.Err(); err != nil {
+		return &os.PathError{Op: "copy", Path: dst, Err: err}
+	}
+	return c.commit(tmp, dst, info.Size(), t, data)
+}
+
+// openSource opens the resolved name or its alt name for reading while holding a shared lock (see WithFlock).
+// The lock is only needed until the file is open, because the version of an open file is never modified.
+func (c *config) openSource(name string) (*os.File, error) {
+	unlock, err := c.flock(name, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return c.open(name, c.altName(name))
+}
+
+// copyContents clones or copies the contents of the file in to the file out.
+func (c *config) copyContents(out *os.File, in *os.File) error {
+	if err := out.Chmod(c.perm); err != nil {
+		return err
+	}
+	if c.reflink && cloneFile(out, in) == nil {
+		return nil
+	}
+	_, err := io.Copy(out, in)
+	return err
+}
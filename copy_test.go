@@ -0,0 +1,54 @@
+package safe
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCopyFile(t *testing.T) {
+	t.Run("should copy the file with its alt file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/src", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := CopyFile("testdir/src", "testdir/dst", 0600); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/dst", "some data")
+		checkContents(t, "testdir/dst.1", "some data")
+		checkContents(t, "testdir/src", "some data")
+		info, err := os.Stat("testdir/dst")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("expect the permissions 0600 but got %v", info.Mode().Perm())
+		}
+	})
+
+	t.Run("should read the alt file of an interrupted write", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/src.1", "some data")
+		if err := WriteFile("testdir/dst", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := CopyFile("testdir/src", "testdir/dst", 0600, WithReflink()); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/dst", "some data")
+	})
+
+	t.Run("should fail if the source does not exist", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := CopyFile("testdir/src", "testdir/dst", 0600, WithRetries(1)); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+		checkNotExist(t, "testdir/dst")
+	})
+}
@@ -0,0 +1,58 @@
+package safe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// DefaultMaxDecompressedSize is the maximum size of the decompressed contents of a file if no other limit is set.
+const DefaultMaxDecompressedSize = 64 << 20
+
+// gzipMagic are the first bytes of gzip compressed data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// WithDecompression makes ReadFile transparently decompress gzip compressed files.
+// Files which are not compressed are returned as they are.
+// The contents are decompressed into a buffer which is limited to DefaultMaxDecompressedSize
+// or the size set with WithMaxDecompressedSize.
+func WithDecompression() Option {
+	return func(c *config) {
+		c.decompress = true
+	}
+}
+
+// WithMaxDecompressedSize sets the maximum size of the decompressed contents in bytes.
+// If a file decompresses to more data, ReadFile returns an error wrapping ErrTooLarge
+// instead of exhausting the memory.
+func WithMaxDecompressedSize(n int64) Option {
+	return func(c *config) {
+		c.maxDecompressedSize = n
+	}
+}
+
+// decompress the data of the file with the name if it is gzip compressed.
+// At most max bytes are decompressed.
+func decompress(name string, data []byte, max int64) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, &os.PathError{Op: "decompress", Path: name, Err: &kindWrapError{err: err, kind: ErrCorrupt}}
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	// Read one byte more than allowed to detect if the limit is exceeded.
+	n, err := buf.ReadFrom(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, &os.PathError{Op: "decompress", Path: name, Err: &kindWrapError{err: err, kind: ErrCorrupt}}
+	}
+	if n > max {
+		return nil, &os.PathError{Op: "decompress", Path: name, Err: ErrTooLarge}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,59 @@
+package safe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+// gzipped compresses the data for the tests.
+func gzipped(t *testing.T, data string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestReadFileWithDecompression(t *testing.T) {
+	t.Run("should decompress gzip compressed files", func(t *testing.T) {
+		createFile(t, "testfile", gzipped(t, "some important data"))
+		defer clean(t, "testfile")
+
+		got, err := ReadFile("testfile", WithDecompression())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "some important data" {
+			t.Errorf("ReadFile does not return the decompressed contents. Want %q but got %q", "some important data", got)
+		}
+	})
+
+	t.Run("should return files which are not compressed as they are", func(t *testing.T) {
+		createFile(t, "testfile", "some important data")
+		defer clean(t, "testfile")
+
+		got, err := ReadFile("testfile", WithDecompression())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "some important data" {
+			t.Errorf("ReadFile does not return the file contents. Want %q but got %q", "some important data", got)
+		}
+	})
+
+	t.Run("should return ErrTooLarge if the decompressed contents exceed the limit", func(t *testing.T) {
+		createFile(t, "testfile", gzipped(t, string(make([]byte, 1<<20))))
+		defer clean(t, "testfile")
+
+		_, err := ReadFile("testfile", WithDecompression(), WithMaxDecompressedSize(1024))
+		if !errors.Is(err, ErrTooLarge) {
+			t.Errorf("expect ErrTooLarge but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Description is a diagnostic snapshot of the physical copies of a managed file.
+type Description struct {
+	// Primary describes the file with the name. It is nil if the file does not exist.
+	Primary *FileDescription
+	// Alt describes the file $(name).1 It is nil if the file does not exist.
+	Alt *FileDescription
+	// Temps describes the temporary files of the file which were not removed.
+	Temps []FileDescription
+}
+
+// FileDescription describes a single physical copy of a managed file.
+type FileDescription struct {
+	Name    string
+	Inode   uint64
+	Links   uint64
+	Size    int64
+	ModTime time.Time
+	// Digest of the contents as returned by Digest.
+	Digest string
+}
+
+// Describe returns the inode numbers, link counts, sizes, modification times and digests
+// of the file with the name, the file $(name).1 and its temporary files.
+// The inode numbers and link counts are zero on platforms which do not provide them.
+func Describe(name string, opts ...Option) (Description, error) {
+	var d Description
+	c := newConfig(opts)
+	name, err := c.path(name)
+	if err != nil {
+		return d, err
+	}
+
+	if d.Primary, err = describeFile(name, c); err != nil {
+		return d, err
+	}
+	if d.Alt, err = describeFile(c.altName(name), c); err != nil {
+		return d, err
+	}
+
+	infos, err := ioutil.ReadDir(filepath.Dir(name))
+	if err != nil {
+		return d, err
+	}
+	base := filepath.Base(name)
+	for _, info := range infos {
+		if owner, _, ok := c.isTemp(info.Name()); !ok || owner != base {
+			continue
+		}
+		temp, err := describeFile(filepath.Join(filepath.Dir(name), info.Name()), c)
+		if err != nil {
+			return d, err
+		}
+		if temp != nil {
+			d.Temps = append(d.Temps, *temp)
+		}
+	}
+	return d, nil
+}
+
+// describeFile describes the file with the resolved name. It returns nil if the file does not exist.
+func describeFile(name string, c *config) (*FileDescription, error) {
+	info, err := os.Lstat(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := &FileDescription{
+		Name:    name,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	d.Inode, d.Links = inode(info)
+
+	if !info.Mode().IsRegular() {
+		return d, nil
+	}
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if d.Digest, err = Digest(c.hash, data); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
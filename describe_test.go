@@ -0,0 +1,50 @@
+package safe
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDescribe(t *testing.T) {
+	t.Run("should describe the primary, the alt file and the temps", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("some important data")); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		temp := "testfile" + time.Now().Format(TimestampFormat)
+		createFile(t, temp, "partial")
+		defer clean(t, temp)
+
+		d, err := Describe("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Primary == nil || d.Alt == nil {
+			t.Fatalf("expect the primary and the alt file to be described but got %+v", d)
+		}
+		if d.Primary.Inode != d.Alt.Inode || d.Primary.Links != 2 {
+			t.Errorf("expect the primary and the alt file to share an inode with 2 links but got %+v and %+v", d.Primary, d.Alt)
+		}
+		want, _ := Digest(DefaultHash, []byte("some important data"))
+		if d.Primary.Digest != want || d.Primary.Size != 19 {
+			t.Errorf("expect the digest %q and the size 19 but got %+v", want, d.Primary)
+		}
+		if len(d.Temps) != 1 || d.Temps[0].Name != temp {
+			t.Errorf("expect the temp %q to be described but got %+v", temp, d.Temps)
+		}
+	})
+
+	t.Run("should leave out copies which do not exist", func(t *testing.T) {
+		createFile(t, "testfile.1", "data")
+		defer clean(t, "testfile.1")
+
+		d, err := Describe("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Primary != nil || d.Alt == nil {
+			t.Errorf("expect only the alt file to be described but got %+v", d)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package safe
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffContext is the number of unchanged lines around the changes in the output of DiffVersions.
+const DiffContext = 3
+
+// DiffVersions compares two versions of the file with the name which were kept by WriteFileVersioned,
+// so tools can show what changed between writes. Text is compared in the unified diff format.
+// If the name ends with .json and both versions are valid JSON, a structural diff is returned instead, with one line
+// per changed value, e.g. "~ /server/port: 8080 -> 9090", "+ /debug: true" or "- /legacy: \"x\"".
+// If the versions are equal, the diff is empty. If a version does not exist, a NotExist error is returned.
+func DiffVersions(name string, a VersionID, b VersionID, opts ...Option) ([]byte, error) {
+	dataA, err := ReadVersion(name, a, opts...)
+	if err != nil {
+		return nil, err
+	}
+	dataB, err := ReadVersion(name, b, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(name) == ".json" {
+		if diff, ok := diffJSON(dataA, dataB); ok {
+			return diff, nil
+		}
+	}
+	base := filepath.Base(name) + VersionPostfix
+	return unifiedDiff(base+string(a), base+string(b), dataA, dataB), nil
+}
+
+// edit is a step of a line diff. The op is ' ' for an unchanged line, '-' for a removed line and '+' for an added line.
+// a and b are the positions of the step in the old and the new lines.
+type edit struct {
+	op   byte
+	a, b int
+}
+
+// unifiedDiff returns the diff of the old and the new data with the names in the unified diff format.
+func unifiedDiff(nameA string, nameB string, a []byte, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	if bytes.IndexByte(a, 0) >= 0 || bytes.IndexByte(b, 0) >= 0 {
+		return []byte(fmt.Sprintf("Binary files %s and %s differ\n", nameA, nameB))
+	}
+	linesA, linesB := splitLines(a), splitLines(b)
+	edits := diffLines(linesA, linesB)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for i := 0; i < len(edits); {
+		for i < len(edits) && edits[i].op == ' ' {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+		// Merge changes which are separated by less than two contexts into one hunk.
+		end := i
+		for {
+			for end < len(edits) && edits[end].op != ' ' {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*DiffContext {
+				break
+			}
+			end = next
+		}
+		start := i - DiffContext
+		if start < 0 {
+			start = 0
+		}
+		stop := end + DiffContext
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+		writeHunk(&out, edits[start:stop], linesA, linesB)
+		i = stop
+	}
+	return out.Bytes()
+}
+
+// writeHunk writes the edits as a hunk of a unified diff.
+func writeHunk(out *bytes.Buffer, edits []edit, a []string, b []string) {
+	countA, countB := 0, 0
+	for _, e := range edits {
+		if e.op != '+' {
+			countA++
+		}
+		if e.op != '-' {
+			countB++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(edits[0].a, countA), hunkRange(edits[0].b, countB))
+	for _, e := range edits {
+		var line string
+		if e.op == '+' {
+			line = b[e.b]
+		} else {
+			line = a[e.a]
+		}
+		out.WriteByte(e.op)
+		out.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the range of a hunk which starts at the position and has count lines.
+func hunkRange(start int, count int) string {
+	switch count {
+	case 0:
+		return strconv.Itoa(start) + ",0"
+	case 1:
+		return strconv.Itoa(start + 1)
+	}
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(count)
+}
+
+// splitLines splits the data into lines which keep their line endings.
+func splitLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script from the lines a to the lines b with the algorithm of Myers.
+func diffLines(a []string, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	// Find the length of the shortest edit script and remember the furthest points of each round.
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		done := false
+		for k := -d; k <= d && !done; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			done = x >= n && y >= m
+		}
+		if done {
+			break
+		}
+	}
+
+	// Walk back from the end to the start.
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{' ', x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, edit{'+', x, prevY})
+			} else {
+				edits = append(edits, edit{'-', prevX, y})
+			}
+			x, y = prevX, prevY
+		}
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// diffJSON returns the structural diff of the JSON documents a and b. It reports false if one of them is invalid.
+func diffJSON(a []byte, b []byte) ([]byte, bool) {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return nil, false
+	}
+	var lines []string
+	compareJSON("", va, vb, &lines)
+	sort.Strings(lines)
+	var out bytes.Buffer
+	for _, l := range lines {
+		out.WriteString(l)
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), true
+}
+
+// compareJSON appends a line for each difference between the values at the JSON pointer path to the lines.
+func compareJSON(path string, a interface{}, b interface{}, lines *[]string) {
+	switch oa := a.(type) {
+	case map[string]interface{}:
+		if ob, ok := b.(map[string]interface{}); ok {
+			for key, va := range oa {
+				p := path + "/" + escapePointer(key)
+				if vb, ok := ob[key]; ok {
+					compareJSON(p, va, vb, lines)
+				} else {
+					*lines = append(*lines, "- "+p+": "+encodeJSON(va))
+				}
+			}
+			for key, vb := range ob {
+				if _, ok := oa[key]; !ok {
+					*lines = append(*lines, "+ "+path+"/"+escapePointer(key)+": "+encodeJSON(vb))
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if ob, ok := b.([]interface{}); ok {
+			for i := 0; i < len(oa) || i < len(ob); i++ {
+				p := path + "/" + strconv.Itoa(i)
+				switch {
+				case i >= len(ob):
+					*lines = append(*lines, "- "+p+": "+encodeJSON(oa[i]))
+				case i >= len(oa):
+					*lines = append(*lines, "+ "+p+": "+encodeJSON(ob[i]))
+				default:
+					compareJSON(p, oa[i], ob[i], lines)
+				}
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "/"
+		}
+		*lines = append(*lines, "~ "+path+": "+encodeJSON(a)+" -> "+encodeJSON(b))
+	}
+}
+
+// escapePointer escapes a key for a JSON pointer.
+func escapePointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// encodeJSON returns the compact JSON encoding of the value.
+func encodeJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
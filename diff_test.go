@@ -0,0 +1,115 @@
+package safe
+
+import (
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiffVersions(t *testing.T) {
+	t.Run("should return a unified diff of text", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n", "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn")
+
+		versions, err := ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DiffVersions("testdir/testfile", versions[0].ID, versions[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "--- testfile.v" + string(versions[0].ID) + "\n+++ testfile.v" + string(versions[1].ID) + "\n" +
+			"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+			"@@ -11,3 +11,4 @@\n k\n l\n m\n+n\n\\ No newline at end of file\n"
+		if string(got) != want {
+			t.Errorf("expect\n%s\nbut got\n%s", want, got)
+		}
+	})
+
+	t.Run("should return a structural diff of JSON", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/config.json",
+			`{"server": {"port": 8080}, "legacy": "x", "list": [1, 2]}`,
+			`{"server": {"port": 9090}, "debug": true, "list": [1, 2, 3]}`)
+
+		versions, err := ListVersions("testdir/config.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DiffVersions("testdir/config.json", versions[0].ID, versions[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "+ /debug: true\n+ /list/2: 3\n- /legacy: \"x\"\n~ /server/port: 8080 -> 9090\n"
+		if string(got) != want {
+			t.Errorf("expect\n%s\nbut got\n%s", want, got)
+		}
+	})
+
+	t.Run("should return an empty diff for equal versions", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "same\n", "same\n")
+
+		versions, err := ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DiffVersions("testdir/testfile", versions[0].ID, versions[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("expect an empty diff but got\n%s", got)
+		}
+	})
+
+	t.Run("should return a NotExist error for an unknown version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "data")
+
+		versions, err := ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DiffVersions("testdir/testfile", versions[0].ID, "zzzzzzzzzzzz"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+}
+
+func TestDiffLines(t *testing.T) {
+	t.Run("should return an edit script which turns the old lines into the new ones", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		random := func() []string {
+			lines := make([]string, r.Intn(20))
+			for i := range lines {
+				lines[i] = string(rune('a' + r.Intn(4)))
+			}
+			return lines
+		}
+		for i := 0; i < 500; i++ {
+			a, b := random(), random()
+			var gotA, gotB []string
+			for _, e := range diffLines(a, b) {
+				if e.op != '+' {
+					gotA = append(gotA, a[e.a])
+				}
+				if e.op != '-' {
+					gotB = append(gotB, b[e.b])
+				}
+				if e.op == ' ' && a[e.a] != b[e.b] {
+					t.Fatalf("unchanged lines differ in %v and %v", a, b)
+				}
+			}
+			if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+				t.Fatalf("the edit script of %v and %v is wrong", a, b)
+			}
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package safe
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// DirSync controls which directories are synced after a write so that the new directory entries survive a crash.
+type DirSync int
+
+const (
+	// DirSyncNone does not sync any directory. It makes writes faster, but after a power loss
+	// the new name of a file may be lost even though its contents were synced.
+	DirSyncNone DirSync = iota
+	// DirSyncParent syncs the directory which contains the file after the file was committed,
+	// so the new directory entry is durable when WriteFile returns. It is the default.
+	DirSyncParent
+	// DirSyncCreated additionally syncs the parent of every directory created by WithMkdirAll.
+	DirSyncCreated
+)
+
+// WithDirSync sets which directories are synced by WriteFile and Create. The default is DirSyncParent.
+// Use DirSyncNone to opt out, e.g. for caches which can be rebuilt.
+func WithDirSync(sync DirSync) Option {
+	return func(c *config) {
+		c.dirSync = sync
+	}
+}
+
+// WithMkdirAll makes WriteFile, Create and OpenLog create the missing parent directories of the file with the permissions,
+// so callers don't have to create a tree of files before the first write. The directory of WithTempDir is created as well.
+// Directories which are created concurrently by another process are accepted.
+func WithMkdirAll(perm os.FileMode) Option {
+	return func(c *config) {
+		c.mkdirAll = true
+		c.dirPerm = perm
+	}
+}
+
+// mkdirs creates the directory and its missing parents and returns the created directories from the top down.
+// Directories which are created concurrently by another process are not returned.
+func mkdirs(dir string, perm os.FileMode) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	var created []string
+	for i := len(missing) - 1; i >= 0; i-- {
+		err := os.Mkdir(missing[i], perm)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return created, err
+		}
+		created = append(created, missing[i])
+	}
+	return created, nil
+}
+
+// prepareDir creates the missing parents of the file with the resolved name and the missing temporary directory
+// and syncs them if configured.
+func (c *config) prepareDir(name string) error {
+	if !c.mkdirAll {
+		return nil
+	}
+	dirs := []string{filepath.Dir(name)}
+	if c.tempDir != "" {
+		dirs = append(dirs, c.tempDir)
+	}
+	for _, d := range dirs {
+		created, err := mkdirs(d, c.dirPerm)
+		if err != nil {
+			return err
+		}
+		if c.dirSync < DirSyncCreated {
+			continue
+		}
+		for _, dir := range created {
+			if err := syncDir(filepath.Dir(dir)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncDirs syncs the directories of the committed resolved name and its alt name.
+func (c *config) syncDirs(name string, alt string) error {
+	if err := c.syncParent(name); err != nil {
+		return err
+	}
+	if filepath.Dir(alt) == filepath.Dir(name) {
+		return nil
+	}
+	return c.syncParent(alt)
+}
+
+// syncParent syncs the directory of the resolved name in the Backend of WithBackend or like syncDir.
+func (c *config) syncParent(name string) error {
+	if c.backend != nil {
+		return c.backend.Sync(filepath.Dir(name))
+	}
+	return syncDir(filepath.Dir(name))
+}
+
+// syncDir syncs the directory so its entries are durable.
+// Windows does not support syncing directories, so nothing is done there.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,106 @@
+package safe
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWithMkdirAll(t *testing.T) {
+	t.Run("should create the missing parent directories and sync them", func(t *testing.T) {
+		defer clean(t, "testdir")
+
+		err := WriteFile("testdir/a/b/testfile", []byte("some data"), WithMkdirAll(0755), WithDirSync(DirSyncCreated))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a/b/testfile", "some data")
+		checkContents(t, "testdir/a/b/testfile.1", "some data")
+	})
+
+	t.Run("should create the missing temporary directory", func(t *testing.T) {
+		defer clean(t, "testdir")
+
+		err := WriteFile("testdir/a/testfile", []byte("some data"), WithMkdirAll(0755), WithTempDir("testdir/tmp"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a/testfile", "some data")
+	})
+
+	t.Run("should create the missing parent directories of a log", func(t *testing.T) {
+		defer clean(t, "testdir")
+
+		if err := AppendRecord("testdir/a/testfile", []byte("some data"), WithMkdirAll(0755)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat("testdir/a/testfile"); err != nil {
+			t.Errorf("expect the log to exist but got %v", err)
+		}
+	})
+
+	t.Run("should return the error if the directory does not exist and may not be created", func(t *testing.T) {
+		err := WriteFile("testdir/testfile", []byte("some data"), WithDirSync(DirSyncParent))
+		if !os.IsNotExist(err) {
+			t.Errorf("expect NotExist error but got %v", err)
+		}
+	})
+}
+
+func TestWithDirSync(t *testing.T) {
+	t.Run("should sync the parent directory by default", func(t *testing.T) {
+		if c := newConfig(nil); c.dirSync != DirSyncParent {
+			t.Errorf("expect DirSyncParent but got %v", c.dirSync)
+		}
+	})
+
+	t.Run("should allow to opt out", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if c := newConfig([]Option{WithDirSync(DirSyncNone)}); c.dirSync != DirSyncNone {
+			t.Errorf("expect DirSyncNone but got %v", c.dirSync)
+		}
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithDirSync(DirSyncNone)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+	})
+
+	t.Run("should sync the shadow directory", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithShadowDir()); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+	})
+}
+
+func TestMkdirs(t *testing.T) {
+	t.Run("should return the created directories from the top down", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		created, err := mkdirs("testdir/a/b", 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(created) != 2 || created[0] != "testdir/a" || created[1] != "testdir/a/b" {
+			t.Errorf("expect the created directories testdir/a and testdir/a/b but got %v", created)
+		}
+	})
+
+	t.Run("should not return directories which already exist", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		created, err := mkdirs("testdir", 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(created) != 0 {
+			t.Errorf("expect no created directories but got %v", created)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package safe
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Eject turns the file with the name back into a single plain file, e.g. before the directory is handed to tools
+// which get confused by the files of this package. The current version stays at the name,
+// which is restored from the alt file if the last write was interrupted. If the name is a symbolic link
+// to a version (see StrategySymlink), it is replaced with a copy of the version and the versions are removed.
+// The alt file and the temporary files of the name are removed. If neither the name nor the alt file exists,
+// a NotExist error is returned.
+func Eject(name string, opts ...Option) error {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	alt := c.altName(name)
+
+	target, err := symlinkTarget(name)
+	if err != nil {
+		return err
+	}
+	if target != "" {
+		if err := c.ejectVersion(name, target); err != nil {
+			return err
+		}
+	} else if _, err := os.Lstat(name); os.IsNotExist(err) {
+		if err := replaceFile(alt, name); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if err := remove(alt); err != nil {
+		return err
+	}
+	var errs MultiError
+	for _, n := range []string{name, alt} {
+		c.removeTemps(n, &errs)
+	}
+	if err := errs.single(); err != nil {
+		return err
+	}
+	if c.dirSync >= DirSyncParent {
+		if err := c.syncDirs(name, alt); err != nil {
+			return err
+		}
+	}
+	if c.index {
+		return c.removeFromIndex(name)
+	}
+	return nil
+}
+
+// ejectVersion replaces the symbolic link with the resolved name with a copy of the resolved target
+// and removes all versions of the name.
+func (c *config) ejectVersion(name string, target string) error {
+	tmp := c.tempName(name, time.Now())
+	if err := copyFile(target, tmp, !c.noSync); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := replaceFile(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return pruneVersions(name, "", "")
+}
+
+// removeTemps removes the temporary files of the resolved name and adds the errors to errs.
+func (c *config) removeTemps(name string, errs *MultiError) {
+	dir := filepath.Dir(c.tempName(name, time.Now()))
+	names, err := readDirNames(dir)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		errs.add(dir, err)
+		return
+	}
+	for _, n := range names {
+		if primary, _, ok := c.isTemp(n); ok && primary == filepath.Base(name) {
+			if err := os.RemoveAll(filepath.Join(dir, n)); err != nil {
+				errs.add(filepath.Join(dir, n), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEject(t *testing.T) {
+	t.Run("should remove the alt file and the temporary files", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/testfile"+time.Now().Format(TimestampFormat), "stale data")
+
+		if err := Eject("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 1 {
+			t.Errorf("expect only the file but got %d files", len(infos))
+		}
+	})
+
+	t.Run("should restore the file from the alt file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile.1", "some data")
+
+		if err := Eject("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkNotExist(t, "testdir/testfile.1")
+	})
+
+	t.Run("should replace a symbolic link with the version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		for _, data := range []string{"blue", "green"} {
+			if err := WriteFile("testdir/testfile", []byte(data), WithStrategy(StrategySymlink)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := Eject("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "green")
+		info, err := os.Lstat("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.Mode().IsRegular() {
+			t.Errorf("expect a regular file but got %v", info.Mode())
+		}
+		if n := countVersions(t, "testdir/testfile"); n != 0 {
+			t.Errorf("expect no versions but got %d", n)
+		}
+	})
+
+	t.Run("should fail if the file does not exist", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := Eject("testdir/testfile"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package safe
+
+import (
+	"errors"
+	"os"
+)
+
+// The errors of this package are *os.PathError (*fs.PathError) or *os.LinkError values which name the operation
+// and the file which failed, and they wrap one of the sentinels below or an error of the operating system.
+// Callers can branch with errors.Is. The general sentinels ErrNotExist, ErrConflict, ErrUnsupportedFS and ErrCorrupt
+// also match the more specific sentinels of their kind.
+
+// ErrNotExist is matched if a file does not exist. It is os.ErrNotExist, so errors.Is(err, ErrNotExist)
+// is true for every error which wraps a NotExist error of the operating system.
+var ErrNotExist = os.ErrNotExist
+
+// ErrUnsupportedFS is matched if the filesystem lacks a feature which is required,
+// e.g. by ErrNoHardLinks, ErrCrossDevice and *CapabilityError.
+var ErrUnsupportedFS = errors.New("safe: unsupported filesystem")
+
+// ErrCorrupt is matched if a file has unexpected contents or links, e.g. by ErrLinkMismatch, ErrCommitIncomplete,
+// ErrChecksumMismatch, invalid compressed contents and invalid transaction journals.
+var ErrCorrupt = errors.New("safe: corrupt file")
+
+// kindError is a sentinel error which also matches the general sentinel of its kind.
+type kindError struct {
+	msg  string
+	kind error
+}
+
+// Error returns the message of the error.
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+// Is reports whether the target is the general sentinel of the error.
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// kindWrapError wraps an error, e.g. of the operating system, so it also matches the general sentinel of its kind.
+type kindWrapError struct {
+	err  error
+	kind error
+}
+
+// Error returns the message of the wrapped error.
+func (e *kindWrapError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *kindWrapError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is the general sentinel of the error.
+func (e *kindWrapError) Is(target error) bool {
+	return target == e.kind
+}
+
+// ErrNotRegular is returned in strict mode if a link does not point to a regular file.
+var ErrNotRegular = errors.New("safe: not a regular file")
+
+// ErrLinkMismatch is returned in strict mode if a link does not point to the file it was linked from.
+var ErrLinkMismatch error = &kindError{msg: "safe: link points to a different file", kind: ErrCorrupt}
+
+// ErrCommitIncomplete is returned by WriteFile with WithAssertCommitted if the written file is not fully committed.
+var ErrCommitIncomplete error = &kindError{msg: "safe: commit incomplete", kind: ErrCorrupt}
+
+// ErrInvalidDigest is returned if a digest does not have the format $(algorithm):$(hex).
+var ErrInvalidDigest = errors.New("safe: invalid digest")
+
+// ErrUnknownHash is returned if a hashing algorithm is not registered.
+var ErrUnknownHash = errors.New("safe: unknown hash")
+
+// ErrIncludeCycle is returned by ReadFile with WithIncludes if a file directly or indirectly includes itself.
+var ErrIncludeCycle = errors.New("safe: include cycle")
+
+// ErrInvalidDirective is returned by ReadFile with WithIncludes if an include or extends directive is not
+// a string or a list of strings.
+var ErrInvalidDirective = errors.New("safe: invalid directive")
+
+// ErrUndefinedVariable is returned by ReadFileExpanded if a variable can not be looked up.
+var ErrUndefinedVariable = errors.New("safe: undefined variable")
+
+// ErrUnterminatedVariable is returned by ReadFileExpanded if a ${ is not closed by a }.
+var ErrUnterminatedVariable = errors.New("safe: unterminated variable")
+
+// ErrTooLarge is returned by ReadFile with WithDecompression if the decompressed contents exceed the maximum size.
+var ErrTooLarge = errors.New("safe: decompressed contents too large")
+
+// ErrInvalidPath is returned if a name escapes the prefix directory set with WithPrefix.
+var ErrInvalidPath = errors.New("safe: invalid path")
+
+// ErrThrashing is returned by WriteFile with WithWatchdog if a file is written too often.
+var ErrThrashing = errors.New("safe: file is rewritten too often")
+
+// ErrReservedName is returned by WriteFile if the name is the alt name or the name of a temporary file of another file.
+var ErrReservedName = errors.New("safe: reserved name")
+
+// ErrNotStreamable is returned by Create if transforms or validators are set, because they need all data at once.
+var ErrNotStreamable = errors.New("safe: transforms and validators can not be applied to a stream")
+
+// ErrClosed is returned if a File is used after it was closed.
+var ErrClosed = errors.New("safe: file already closed")
+
+// ErrCrossDevice is returned with WithShadowDir if the shadow directory is on another filesystem than the files,
+// which makes hard links between them impossible.
+var ErrCrossDevice error = &kindError{msg: "safe: shadow directory is on another filesystem", kind: ErrUnsupportedFS}
+
+// ErrNotDir is returned with WithShadowDir if the shadow directory is not a directory.
+var ErrNotDir = errors.New("safe: not a directory")
+
+// ErrStaleToken is returned by WriteFile with WithFencingToken if a write with a higher token already happened.
+var ErrStaleToken = errors.New("safe: stale fencing token")
+
+// ErrBusy is returned if a file is locked by another write and the busy handler gave up.
+var ErrBusy = errors.New("safe: file is busy")
+
+// ErrQueueFull is returned by AsyncWriter.WriteFile with OverflowError if the queue is full.
+var ErrQueueFull = errors.New("safe: write queue is full")
+
+// ErrDropped is reported by an AsyncWriter with OverflowDropOldest for a write which was dropped from the queue.
+var ErrDropped = errors.New("safe: write dropped from the queue")
+
+// ErrConflict is returned if a file was changed concurrently and the write was refused.
+var ErrConflict = errors.New("safe: file was changed concurrently")
+
+// ErrNoHardLinks is returned by SelfTest if the filesystem does not support hard links.
+// Use WithRenameFallback or WithStrategy on such filesystems.
+var ErrNoHardLinks error = &kindError{msg: "safe: filesystem does not support hard links", kind: ErrUnsupportedFS}
+
+// ErrTxDone is returned if a transaction is used after it was committed or rolled back.
+var ErrTxDone = errors.New("safe: transaction already committed or rolled back")
+
+// ErrNotSymlink is returned by Revert if the name or its alt name is not a symbolic link to a version.
+var ErrNotSymlink = errors.New("safe: not a symbolic link")
+
+// ErrUnsupportedFlag is returned by FS.OpenFile if a flag can not be applied to a safe write, e.g. os.O_APPEND.
+var ErrUnsupportedFlag = errors.New("safe: unsupported flag")
+
+// ErrInjected is returned by the operations of a FaultBackend after FailAfter operations.
+var ErrInjected = errors.New("safe: injected fault")
+
+// ErrReadOnly is returned by calls which would modify a file if WithReadOnly is set.
+var ErrReadOnly = errors.New("safe: read-only")
+
+// ErrNoAudit is returned by ReadAudit if no audit journal is set with WithAudit.
+var ErrNoAudit = errors.New("safe: no audit journal")
+
+// ErrAuditTampered is returned if an entry of an audit journal was modified or removed.
+var ErrAuditTampered error = &kindError{msg: "safe: audit journal was tampered with", kind: ErrCorrupt}
+
+// ErrChecksumMismatch is returned by ReadFile with WithChecksum if the contents do not match their sidecar checksum.
+var ErrChecksumMismatch error = &kindError{msg: "safe: checksum mismatch", kind: ErrCorrupt}
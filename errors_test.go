@@ -0,0 +1,47 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	t.Run("should match the general sentinels", func(t *testing.T) {
+		for _, tt := range []struct {
+			err  error
+			kind error
+		}{
+			{ErrLinkMismatch, ErrCorrupt},
+			{ErrCommitIncomplete, ErrCorrupt},
+			{ErrNoHardLinks, ErrUnsupportedFS},
+			{ErrCrossDevice, ErrUnsupportedFS},
+			{&CapabilityError{Dir: "testdir"}, ErrUnsupportedFS},
+		} {
+			if !errors.Is(tt.err, tt.kind) {
+				t.Errorf("expect %v to match %v", tt.err, tt.kind)
+			}
+			if errors.Is(tt.err, ErrConflict) {
+				t.Errorf("expect %v not to match %v", tt.err, ErrConflict)
+			}
+		}
+	})
+
+	t.Run("should report the name if neither file exists", func(t *testing.T) {
+		_, err := ReadFile("testfile", WithRetries(1))
+		if !errors.Is(err, ErrNotExist) {
+			t.Fatalf("expect ErrNotExist but got %v", err)
+		}
+		if err.Error() != "read testfile: file does not exist" {
+			t.Errorf("expect the name in the error but got %q", err.Error())
+		}
+	})
+
+	t.Run("should match ErrCorrupt for invalid compressed contents", func(t *testing.T) {
+		defer clean(t, "testfile")
+		createFile(t, "testfile", "\x1f\x8bnot gzip")
+
+		if _, err := ReadFile("testfile", WithDecompression()); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expect ErrCorrupt but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package safe
+
+import (
+	"os"
+	"time"
+)
+
+// osExchange swaps two files in a single step. It is a variable so tests can interrupt an exchange.
+var osExchange = renameExchange
+
+// Exchange atomically swaps the contents of the files with the names a and b, e.g. to promote a staged
+// configuration over the live one while the old one stays readable under the other name.
+// Where possible, the files are swapped in a single step (renameat2 with RENAME_EXCHANGE on Linux).
+// Their alt files are removed before and linked again after the swap, so an alt file never contains the contents
+// which were swapped away. If the process is interrupted in between, Repair restores the missing alt files.
+// Otherwise, the contents are staged as temporary files of each other's name and a journal like the one of a Tx
+// is written before they are committed, so RecoverDir completes an exchange which was interrupted.
+// For a moment, both names have the same contents. With WithBackend, the contents are staged in the Backend,
+// but no journal is written.
+// If one of the files does not exist, a NotExist error is returned. The options are applied to both files.
+func Exchange(a string, b string, opts ...Option) error {
+	c := newConfig(opts)
+	pa, err := c.path(a)
+	if err != nil {
+		return err
+	}
+	pb, err := c.path(b)
+	if err != nil {
+		return err
+	}
+	if pa == pb {
+		return nil
+	}
+	// Lock the files in a fixed order, so two exchanges of the same files can't deadlock.
+	if pb < pa {
+		a, b = b, a
+	}
+	pa, unlockA, err := c.begin(a)
+	if err != nil {
+		return err
+	}
+	defer unlockA()
+	pb, unlockB, err := c.begin(b)
+	if err != nil {
+		return err
+	}
+	defer unlockB()
+
+	srcA, err := c.current(pa)
+	if err != nil {
+		return err
+	}
+	srcB, err := c.current(pb)
+	if err != nil {
+		return err
+	}
+	if c.backend == nil && srcA == pa && srcB == pb {
+		err := c.exchangeLinked(pa, pb)
+		if err == nil {
+			if err := c.auditFile(pa); err != nil {
+				return err
+			}
+			return c.auditFile(pb)
+		}
+		if !exchangeUnsupported(err) {
+			return &os.LinkError{Op: "exchange", Old: pa, New: pb, Err: err}
+		}
+	}
+	return c.exchangeStaged(pa, srcA, pb, srcB)
+}
+
+// exchangeLinked swaps the resolved names in a single step and links their alt files to them again.
+func (c *config) exchangeLinked(pa string, pb string) error {
+	var linked []string
+	for _, name := range []string{pa, pb} {
+		if info, err := os.Lstat(c.altName(name)); err == nil && info.Mode()&os.ModeSymlink == 0 {
+			linked = append(linked, name)
+		}
+	}
+	for _, name := range linked {
+		if err := remove(c.altName(name)); err != nil {
+			return err
+		}
+	}
+	if len(linked) > 0 && c.dirSync >= DirSyncParent {
+		if err := c.syncDirs(c.altName(pa), c.altName(pb)); err != nil {
+			return err
+		}
+	}
+
+	err := osExchange(pa, pb)
+	// The names are unchanged if the exchange failed, so their alt files are restored either way.
+	for _, name := range linked {
+		if lerr := c.linkAlt(name); lerr != nil && err == nil {
+			err = lerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if c.dirSync >= DirSyncParent {
+		return c.syncDirs(pa, pb)
+	}
+	return nil
+}
+
+// exchangeStaged swaps the contents of the resolved names pa and pb, which are read from srcA and srcB,
+// by committing them to each other's name. The swap is recorded in a journal before the first commit.
+func (c *config) exchangeStaged(pa string, srcA string, pb string, srcB string) error {
+	dataA, err := c.storage().ReadFile(srcA)
+	if err != nil {
+		return err
+	}
+	dataB, err := c.storage().ReadFile(srcB)
+	if err != nil {
+		return err
+	}
+
+	t := time.Now()
+	tmpA, tmpB := c.tempName(pa, t), c.tempName(pb, t)
+	discard := func() {
+		c.removeTemp(pa, tmpA)
+		c.removeTemp(pb, tmpB)
+	}
+	if err := c.writeTemp(pa, tmpA, dataB); err != nil {
+		discard()
+		return err
+	}
+	if err := c.writeTemp(pb, tmpB, dataA); err != nil {
+		discard()
+		return err
+	}
+	var j journal
+	if c.backend == nil {
+		if j, err = c.writeJournals([][2]string{{pa, tmpA}, {pb, tmpB}}); err != nil {
+			discard()
+			return err
+		}
+	} else {
+		// Without a journal, nothing completes the swap, so the staged files are removed if it fails.
+		defer discard()
+	}
+
+	// From here on, the swap is decided. If a commit fails, the journal is kept for RecoverDir.
+	if err := c.commit(tmpA, pa, int64(len(dataB)), t, dataB); err != nil {
+		return err
+	}
+	if err := c.commit(tmpB, pb, int64(len(dataA)), t, dataA); err != nil {
+		return err
+	}
+	if c.backend != nil {
+		return nil
+	}
+	discard()
+	return removeJournal(j)
+}
+
+// current returns the name which contains the current version of the file with the resolved name,
+// which is the alt name if the last write was interrupted.
+func (c *config) current(name string) (string, error) {
+	for _, n := range []string{name, c.altName(name)} {
+		if c.backend != nil {
+			if _, err := c.backend.ReadFile(n); err == nil {
+				return n, nil
+			}
+		} else if _, err := os.Stat(n); err == nil {
+			return n, nil
+		}
+	}
+	return "", &os.PathError{Op: "exchange", Path: name, Err: os.ErrNotExist}
+}
+
+// matchAlt links the alt name of the resolved name to the same file as the name if an alt file exists,
+// so it does not contain the contents which were swapped away.
+func (c *config) matchAlt(name string) error {
+	alt := c.altName(name)
+	if info, err := os.Lstat(alt); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+	return c.linkAlt(name)
+}
+
+// linkAlt atomically replaces the alt name of the resolved name with a hard link to the name,
+// or a copy if the filesystem does not support hard links.
+func (c *config) linkAlt(name string) error {
+	return c.restoreFrom(name, c.altName(name))
+}
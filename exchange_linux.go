@@ -0,0 +1,46 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package safe
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// atFDCWD is the AT_FDCWD directory file descriptor.
+const atFDCWD = -0x64
+
+// renameExchangeFlag is the RENAME_EXCHANGE flag of renameat2.
+const renameExchangeFlag = 1 << 1
+
+// exchangeUnsupported reports whether the error of renameExchange means that the kernel
+// or the filesystem does not support exchanges or that the files are on different filesystems.
+func exchangeUnsupported(err error) bool {
+	return errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EOPNOTSUPP) ||
+		errors.Is(err, syscall.EXDEV)
+}
+
+// renameExchange atomically swaps the files with the names oldname and newname.
+func renameExchange(oldname string, newname string) error {
+	oldp, err := syscall.BytePtrFromString(oldname)
+	if err != nil {
+		return err
+	}
+	newp, err := syscall.BytePtrFromString(newname)
+	if err != nil {
+		return err
+	}
+	// Relative names are resolved in the working directory.
+	cwd := atFDCWD
+	_, _, errno := syscall.Syscall6(sysRenameat2,
+		uintptr(cwd), uintptr(unsafe.Pointer(oldp)),
+		uintptr(cwd), uintptr(unsafe.Pointer(newp)),
+		renameExchangeFlag, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+package safe
+
+// sysRenameat2 is the number of the renameat2 system call.
+const sysRenameat2 = 316
@@ -0,0 +1,16 @@
+//go:build !linux || (!amd64 && !arm64)
+// +build !linux !amd64,!arm64
+
+package safe
+
+import "errors"
+
+// exchangeUnsupported reports whether the error of renameExchange means that exchanges are not supported.
+func exchangeUnsupported(err error) bool {
+	return errors.Is(err, errNotSupported)
+}
+
+// renameExchange is not supported on this platform.
+func renameExchange(oldname string, newname string) error {
+	return errNotSupported
+}
@@ -0,0 +1,128 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExchange(t *testing.T) {
+	t.Run("should swap the contents of the files", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/live", []byte("live data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/staged", []byte("staged data")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Exchange("testdir/staged", "testdir/live"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/live", "staged data")
+		checkContents(t, "testdir/live.1", "staged data")
+		checkContents(t, "testdir/staged", "live data")
+		checkContents(t, "testdir/staged.1", "live data")
+	})
+
+	t.Run("should use the alt file of an interrupted write", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/live", "live data")
+		createFile(t, "testdir/staged.1", "staged data")
+
+		if err := Exchange("testdir/live", "testdir/staged"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/live", "staged data")
+		checkContents(t, "testdir/staged", "live data")
+	})
+
+	t.Run("should fail if a file does not exist", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/live", "live data")
+
+		if err := Exchange("testdir/live", "testdir/staged"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+		checkContents(t, "testdir/live", "live data")
+	})
+
+	t.Run("should not leave alt files with the swapped contents during the exchange", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		for _, name := range []string{"testdir/live", "testdir/staged"} {
+			if err := WriteFile(name, []byte(name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// The state at the moment of the exchange is the state after a crash right after it.
+		osExchange = func(oldname string, newname string) error {
+			checkNotExist(t, "testdir/live.1")
+			checkNotExist(t, "testdir/staged.1")
+			return renameExchange(oldname, newname)
+		}
+		defer func() {
+			osExchange = renameExchange
+		}()
+
+		if err := Exchange("testdir/live", "testdir/staged"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/live.1", "testdir/staged")
+		checkContents(t, "testdir/staged.1", "testdir/live")
+	})
+
+	t.Run("should complete an interrupted exchange with RecoverDir", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		// The alt file of an interrupted write makes the exchange stage the contents.
+		createFile(t, "testdir/live.1", "live data")
+		createFile(t, "testdir/staged", "staged data")
+		interrupted := errors.New("interrupted")
+		crash := WithCommitStrategy(CommitFunc(func(tmp string, alt string, final string) error {
+			if filepath.Base(final) == "staged" {
+				return interrupted
+			}
+			return HardlinkCommit{}.Commit(tmp, alt, final)
+		}))
+
+		if err := Exchange("testdir/live", "testdir/staged", crash); !errors.Is(err, interrupted) {
+			t.Fatalf("expect the error of the commit but got %v", err)
+		}
+		checkContents(t, "testdir/live", "staged data")
+		checkContents(t, "testdir/staged", "staged data")
+
+		report, err := RecoverDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Failed() {
+			t.Errorf("expect the recovery to succeed but got %+v", report.Files)
+		}
+		checkContents(t, "testdir/live", "staged data")
+		checkContents(t, "testdir/staged", "live data")
+	})
+
+	t.Run("should exchange the files of a Backend", func(t *testing.T) {
+		b := NewMemBackend()
+		for _, name := range []string{"live", "staged"} {
+			if err := WriteFile(name, []byte(name), WithBackend(b)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := Exchange("live", "staged", WithBackend(b)); err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range map[string]string{"live": "staged", "staged": "live"} {
+			if got, err := ReadFile(name, WithBackend(b)); err != nil || string(got) != want {
+				t.Errorf("expect %s to contain %q but got %q and %v", name, want, got, err)
+			}
+		}
+		checkNotExist(t, "live")
+	})
+}
@@ -0,0 +1,60 @@
+package safe
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// ReadFileExpanded reads the file like ReadFile and replaces every ${VAR} in its contents with the value returned
+// by the lookup function (e.g. os.LookupEnv).
+// A $$ is replaced by a single $, so $${VAR} can be used to keep a literal ${VAR}.
+// Any other $ is kept as it is.
+// If a variable is not defined, an error wrapping ErrUndefinedVariable is returned.
+func ReadFileExpanded(name string, lookup func(string) (string, bool), opts ...Option) ([]byte, error) {
+	data, err := ReadFile(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := expand(data, lookup)
+	if err != nil {
+		return nil, &os.PathError{Op: "expand", Path: name, Err: err}
+	}
+	return expanded, nil
+}
+
+// expand replaces the variables in the data.
+func expand(data []byte, lookup func(string) (string, bool)) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+
+	for {
+		i := bytes.IndexByte(data, '$')
+		if i < 0 || i == len(data)-1 {
+			buf.Write(data)
+			return buf.Bytes(), nil
+		}
+		buf.Write(data[:i])
+
+		switch data[i+1] {
+		case '$':
+			buf.WriteByte('$')
+			data = data[i+2:]
+		case '{':
+			end := bytes.IndexByte(data[i+2:], '}')
+			if end < 0 {
+				return nil, ErrUnterminatedVariable
+			}
+			key := string(data[i+2 : i+2+end])
+			value, ok := lookup(key)
+			if !ok {
+				return nil, fmt.Errorf("%w %s", ErrUndefinedVariable, key)
+			}
+			buf.WriteString(value)
+			data = data[i+2+end+1:]
+		default:
+			buf.WriteByte('$')
+			data = data[i+1:]
+		}
+	}
+}
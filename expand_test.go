@@ -0,0 +1,52 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+// lookupTestVars resolves the variables used in the tests.
+func lookupTestVars(key string) (string, bool) {
+	vars := map[string]string{
+		"HOST": "localhost",
+		"PORT": "8080",
+	}
+	v, ok := vars[key]
+	return v, ok
+}
+
+func TestReadFileExpanded(t *testing.T) {
+	t.Run("should replace the variables in the contents of the file", func(t *testing.T) {
+		createFile(t, "testfile", "http://${HOST}:${PORT}/ costs $5, $${HOST} is escaped")
+		defer clean(t, "testfile")
+
+		got, err := ReadFileExpanded("testfile", lookupTestVars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "http://localhost:8080/ costs $5, ${HOST} is escaped"
+		if string(got) != want {
+			t.Errorf("ReadFileExpanded does not return the expanded contents. Want %q but got %q", want, got)
+		}
+	})
+
+	t.Run("should return ErrUndefinedVariable if a variable is not defined", func(t *testing.T) {
+		createFile(t, "testfile", "${UNKNOWN}")
+		defer clean(t, "testfile")
+
+		_, err := ReadFileExpanded("testfile", lookupTestVars)
+		if !errors.Is(err, ErrUndefinedVariable) {
+			t.Errorf("expect ErrUndefinedVariable but got %v", err)
+		}
+	})
+
+	t.Run("should return ErrUnterminatedVariable if a variable is not closed", func(t *testing.T) {
+		createFile(t, "testfile", "${HOST")
+		defer clean(t, "testfile")
+
+		_, err := ReadFileExpanded("testfile", lookupTestVars)
+		if !errors.Is(err, ErrUnterminatedVariable) {
+			t.Errorf("expect ErrUnterminatedVariable but got %v", err)
+		}
+	})
+}
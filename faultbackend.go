@@ -0,0 +1,123 @@
+package safe
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// FaultBackend wraps a Backend and injects faults after a number of operations, so the recovery of an application
+// can be verified against every interruption point of a write. Every call of Create, Link, Rename, Remove and Sync
+// is an operation; reads are passed through. Use Ops after a successful run to learn how many interruption points
+// there are, e.g.
+//
+//	for n := 1; n <= total; n++ {
+//		mem := safe.NewMemBackend()
+//		// ... write the old version to mem ...
+//		fb := &safe.FaultBackend{Backend: mem, CrashAfter: n}
+//		safe.WriteFile("config.json", data, safe.WithBackend(fb))
+//		// ... check that ReadFile with WithBackend(mem) returns the old or the new version ...
+//	}
+type FaultBackend struct {
+	// Backend is the wrapped Backend.
+	Backend Backend
+	// FailAfter makes every operation after the first FailAfter operations fail with ErrInjected. 0 disables it.
+	FailAfter int
+	// CrashAfter simulates a crash after the first CrashAfter operations: the following operations
+	// report success, but have no effect on the wrapped Backend. 0 disables it.
+	CrashAfter int
+
+	mu  sync.Mutex
+	ops int
+}
+
+// Ops returns the number of operations so far.
+func (b *FaultBackend) Ops() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ops
+}
+
+// step counts an operation and reports whether it must fail or be dropped.
+func (b *FaultBackend) step() (fail bool, drop bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ops++
+	return b.FailAfter > 0 && b.ops > b.FailAfter, b.CrashAfter > 0 && b.ops > b.CrashAfter
+}
+
+// Create creates the file in the wrapped Backend unless a fault is injected.
+func (b *FaultBackend) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	fail, drop := b.step()
+	if fail {
+		return nil, &os.PathError{Op: "create", Path: name, Err: ErrInjected}
+	}
+	if drop {
+		return nopWriteCloser{Writer: ioutil.Discard}, nil
+	}
+	return b.Backend.Create(name, perm)
+}
+
+// Link links the file in the wrapped Backend unless a fault is injected.
+func (b *FaultBackend) Link(oldname string, newname string) error {
+	fail, drop := b.step()
+	if fail {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: ErrInjected}
+	}
+	if drop {
+		return nil
+	}
+	return b.Backend.Link(oldname, newname)
+}
+
+// Rename renames the file in the wrapped Backend unless a fault is injected.
+func (b *FaultBackend) Rename(oldname string, newname string) error {
+	fail, drop := b.step()
+	if fail {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrInjected}
+	}
+	if drop {
+		return nil
+	}
+	return b.Backend.Rename(oldname, newname)
+}
+
+// Remove removes the file from the wrapped Backend unless a fault is injected.
+func (b *FaultBackend) Remove(name string) error {
+	fail, drop := b.step()
+	if fail {
+		return &os.PathError{Op: "remove", Path: name, Err: ErrInjected}
+	}
+	if drop {
+		return nil
+	}
+	return b.Backend.Remove(name)
+}
+
+// ReadFile reads the file from the wrapped Backend.
+func (b *FaultBackend) ReadFile(name string) ([]byte, error) {
+	return b.Backend.ReadFile(name)
+}
+
+// Sync syncs the file in the wrapped Backend unless a fault is injected.
+func (b *FaultBackend) Sync(name string) error {
+	fail, drop := b.step()
+	if fail {
+		return &os.PathError{Op: "sync", Path: name, Err: ErrInjected}
+	}
+	if drop {
+		return nil
+	}
+	return b.Backend.Sync(name)
+}
+
+// nopWriteCloser is a Writer with a Close method which does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
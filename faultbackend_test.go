@@ -0,0 +1,57 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFaultBackend(t *testing.T) {
+	write := func(t *testing.T, b Backend, data string) error {
+		return WriteFile("testfile", []byte(data), WithBackend(b), WithRetries(1))
+	}
+
+	t.Run("should return the old or the new version after a crash at every step", func(t *testing.T) {
+		count := &FaultBackend{Backend: NewMemBackend()}
+		if err := write(t, count, "new data"); err != nil {
+			t.Fatal(err)
+		}
+		total := count.Ops()
+		if total == 0 {
+			t.Fatal("expect the write to have operations")
+		}
+
+		for n := 1; n <= total; n++ {
+			mem := NewMemBackend()
+			if err := write(t, mem, "old data"); err != nil {
+				t.Fatal(err)
+			}
+			if err := write(t, &FaultBackend{Backend: mem, CrashAfter: n}, "new data"); err != nil {
+				t.Fatal(err)
+			}
+			data, err := ReadFile("testfile", WithBackend(mem), WithRetries(1))
+			if err != nil {
+				t.Fatalf("crash after %d operations: %v", n, err)
+			}
+			if string(data) != "old data" && string(data) != "new data" {
+				t.Errorf("crash after %d operations: got %q", n, data)
+			}
+		}
+	})
+
+	t.Run("should fail after the operations", func(t *testing.T) {
+		mem := NewMemBackend()
+		if err := write(t, mem, "old data"); err != nil {
+			t.Fatal(err)
+		}
+		if err := write(t, &FaultBackend{Backend: mem, FailAfter: 2}, "new data"); !errors.Is(err, ErrInjected) {
+			t.Errorf("expect ErrInjected but got %v", err)
+		}
+		data, err := ReadFile("testfile", WithBackend(mem), WithRetries(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "old data" && string(data) != "new data" {
+			t.Errorf("expect the old or the new data but got %q", data)
+		}
+	})
+}
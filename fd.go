@@ -0,0 +1,35 @@
+package safe
+
+import (
+	"io"
+	"os"
+)
+
+// CommitFD installs the contents of the already written file with the descriptor fd as the new contents of the name.
+// It is meant for privilege separated writers, where an unprivileged process writes a temporary file
+// and passes its descriptor to a privileged broker (e.g. with SCM_RIGHTS), which only commits it.
+// The contents are copied from the start of the file into a new temporary file which is committed like with Create,
+// so the options of Create apply. CommitFD takes ownership of the fd and closes it.
+func CommitFD(fd uintptr, name string, opts ...Option) error {
+	f := os.NewFile(fd, "fd")
+	if f == nil {
+		return &os.PathError{Op: "commit", Path: name, Err: os.ErrInvalid}
+	}
+	defer f.Close()
+	return CommitFile(f, name, opts...)
+}
+
+// CommitFile works like CommitFD but takes an open file which is not closed.
+// The offset of the file is not changed.
+func CommitFile(src *os.File, name string, opts ...Option) error {
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return &os.PathError{Op: "commit", Path: name, Err: ErrNotRegular}
+	}
+
+	_, err = WriteFileFrom(name, io.NewSectionReader(src, 0, info.Size()), opts...)
+	return err
+}
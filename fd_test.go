@@ -0,0 +1,50 @@
+package safe
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestCommitFile(t *testing.T) {
+	t.Run("should commit the contents of the open file", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		defer clean(t, "testsource")
+		createFile(t, "testsource", "data")
+
+		src, err := os.Open("testsource")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer src.Close()
+		if _, err := src.Seek(2, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := CommitFile(src, "testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "data")
+		if offset, _ := src.Seek(0, io.SeekCurrent); offset != 2 {
+			t.Errorf("expect the offset to stay at 2 but got %d", offset)
+		}
+	})
+
+	t.Run("should reject a directory", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		src, err := os.Open("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer src.Close()
+
+		if err := CommitFile(src, "testfile"); !errors.Is(err, ErrNotRegular) {
+			t.Errorf("expect ErrNotRegular but got %v", err)
+		}
+		checkNotExist(t, "testfile")
+	})
+}
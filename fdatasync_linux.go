@@ -0,0 +1,14 @@
+package safe
+
+import (
+	"os"
+	"syscall"
+)
+
+// fdatasync syncs the contents of the file without its metadata.
+func fdatasync(f *os.File) error {
+	if err := syscall.Fdatasync(int(f.Fd())); err != nil {
+		return &os.PathError{Op: "fdatasync", Path: f.Name(), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package safe
+
+import "os"
+
+// fdatasync is not available on this platform, so the file is synced with its metadata.
+func fdatasync(f *os.File) error {
+	return f.Sync()
+}
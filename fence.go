@@ -0,0 +1,60 @@
+package safe
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FencePostfix is the extension appended to the name of a file to get the name of the file
+// which contains the highest fencing token used for it.
+const FencePostfix = ".fence"
+
+// fenceMu serializes the fenced commits so a check and the following commit can not interleave.
+var fenceMu sync.Mutex
+
+// WithFencingToken makes WriteFile and Create reject the commit with ErrStaleToken if the file was already
+// written with a higher token. The token is provided by an external coordinator (e.g. a lock service) and must
+// increase with every new lease, so delayed writes of an agent which lost its lease are refused.
+// The highest token is stored in the file $(name).fence
+// Within a process, the check and the commit are atomic. Across processes, combine this with a lock.
+func WithFencingToken(token uint64) Option {
+	return func(c *config) {
+		c.fenced = true
+		c.fencingToken = token
+	}
+}
+
+// claimFence checks the token against the highest token of the file with the resolved name and stores it
+// if it is higher. On success, the returned function must be called after the commit.
+func claimFence(name string, token uint64) (func(), error) {
+	fenceMu.Lock()
+
+	fence := name + FencePostfix
+	data, err := newConfig(nil).read(fence, fence+AltNamePostfix)
+	if err != nil && !os.IsNotExist(err) {
+		fenceMu.Unlock()
+		return nil, err
+	}
+	if err == nil {
+		highest, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			fenceMu.Unlock()
+			return nil, &os.PathError{Op: "fence", Path: fence, Err: err}
+		}
+		if token < highest {
+			fenceMu.Unlock()
+			return nil, &os.PathError{Op: "fence", Path: name, Err: ErrStaleToken}
+		}
+		if token == highest {
+			return fenceMu.Unlock, nil
+		}
+	}
+
+	if err := WriteFile(fence, []byte(strconv.FormatUint(token, 10))); err != nil {
+		fenceMu.Unlock()
+		return nil, err
+	}
+	return fenceMu.Unlock, nil
+}
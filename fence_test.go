@@ -0,0 +1,72 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithFencingToken(t *testing.T) {
+	t.Run("should reject writes with a lower token", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		defer clean(t, "testfile.fence")
+		defer clean(t, "testfile.fence.1")
+
+		if err := WriteFile("testfile", []byte("first"), WithFencingToken(5)); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testfile", []byte("second"), WithFencingToken(5)); err != nil {
+			t.Fatal(err)
+		}
+		err := WriteFile("testfile", []byte("stale"), WithFencingToken(4))
+		if !errors.Is(err, ErrStaleToken) {
+			t.Errorf("expect ErrStaleToken but got %v", err)
+		}
+		checkContents(t, "testfile", "second")
+
+		if err := WriteFile("testfile", []byte("third"), WithFencingToken(6)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "third")
+		checkContents(t, "testfile.fence", "6")
+	})
+
+	t.Run("should remove the fencing token with the file", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("data"), WithFencingToken(5)); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFile("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/testfile.fence")
+		checkNotExist(t, "testdir/testfile.fence.1")
+	})
+
+	t.Run("should move the fencing token with the file", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/a", []byte("data"), WithFencingToken(5)); err != nil {
+			t.Fatal(err)
+		}
+		if err := MoveFile("testdir/a", "testdir/b"); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/a.fence")
+		checkNotExist(t, "testdir/a.fence.1")
+		if err := WriteFile("testdir/b", []byte("stale"), WithFencingToken(4)); !errors.Is(err, ErrStaleToken) {
+			t.Errorf("expect ErrStaleToken after the move but got %v", err)
+		}
+
+		if err := RenamePrefix("testdir", "b", "c"); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/b.fence")
+		if err := WriteFile("testdir/c", []byte("stale"), WithFencingToken(4)); !errors.Is(err, ErrStaleToken) {
+			t.Errorf("expect ErrStaleToken after the rename but got %v", err)
+		}
+	})
+}
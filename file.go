@@ -0,0 +1,168 @@
+package safe
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// File is a file which is written incrementally and committed with the same procedure as WriteFile when it is closed.
+// Until then, the data is written to a temporary file and the previous contents of the file stay untouched.
+// A File is not safe for concurrent use.
+type File struct {
+	c    *config
+	name string
+	tmp  string
+	t    time.Time
+	f    *os.File
+	gz   *gzip.Writer
+	size int64
+	done bool
+
+	unlock func()
+}
+
+// File implements io.WriteCloser, so it can be used wherever the data is produced by a writer.
+var _ io.WriteCloser = (*File)(nil)
+
+// Create starts writing the file with the name. The contents are committed when the returned File is closed.
+// The options of WriteFile apply, except that transforms and validators are rejected with ErrNotStreamable.
+func Create(name string, opts ...Option) (*File, error) {
+	c := newConfig(opts)
+	if len(c.transforms) > 0 || len(c.validators) > 0 {
+		return nil, &os.PathError{Op: "create", Path: name, Err: ErrNotStreamable}
+	}
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return nil, err
+	}
+	t := time.Now()
+	tmp := c.tempName(name, t)
+
+	// The temporary file must be new, so the staged data of another write is never truncated.
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, c.perm)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if err := f.Chmod(c.perm); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		unlock()
+		return nil, err
+	}
+	file := &File{c: c, name: name, tmp: tmp, t: t, f: f, unlock: unlock}
+	if c.compress {
+		file.gz = gzip.NewWriter(&countingWriter{w: f, n: &file.size})
+	}
+	return file, nil
+}
+
+// WriteFileFrom copies the data from the reader into a new File and commits it, e.g. to store an HTTP body
+// without reading it into memory first. It returns the number of bytes copied.
+// If the reader fails, nothing is committed and the previous contents of the file stay untouched.
+// Use WithPerm to set the permissions of the file.
+func WriteFileFrom(name string, r io.Reader, opts ...Option) (int64, error) {
+	f, err := Create(name, opts...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Abort()
+		return n, err
+	}
+	return n, f.Close()
+}
+
+// Name returns the resolved name of the file which is written.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Write writes the data to the temporary file.
+func (f *File) Write(p []byte) (int, error) {
+	if f.done {
+		return 0, ErrClosed
+	}
+	if f.gz != nil {
+		return f.gz.Write(p)
+	}
+	n, err := f.f.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// ReadFrom copies the data from the reader to the temporary file.
+// It implements io.ReaderFrom, so io.Copy lets the kernel copy the data directly (e.g. with copy_file_range
+// or splice) if the reader is a *os.File or a network connection and the platform supports it.
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	if f.done {
+		return 0, ErrClosed
+	}
+	if f.gz != nil {
+		return io.Copy(f.gz, r)
+	}
+	n, err := f.f.ReadFrom(r)
+	f.size += n
+	return n, err
+}
+
+// Close syncs the temporary file and commits it to the name.
+// If the commit fails, the previous contents of the file stay untouched.
+func (f *File) Close() error {
+	if f.done {
+		return ErrClosed
+	}
+	f.done = true
+	defer f.unlock()
+	defer os.Remove(f.tmp)
+
+	if f.gz != nil {
+		if err := f.gz.Close(); err != nil {
+			f.f.Close()
+			return err
+		}
+	}
+	if err := f.c.syncFile(f.f); err != nil {
+		f.f.Close()
+		return err
+	}
+	if err := f.f.Close(); err != nil {
+		return err
+	}
+
+	var data []byte
+	if f.c.index || f.c.writeStats || f.c.checksum || f.c.auditLog != "" {
+		var err error
+		if data, err = ioutil.ReadFile(f.tmp); err != nil {
+			return err
+		}
+	}
+	return f.c.commit(f.tmp, f.name, f.size, f.t, data)
+}
+
+// Abort discards the temporary file without committing it, so the previous contents of the file stay untouched.
+func (f *File) Abort() error {
+	if f.done {
+		return ErrClosed
+	}
+	f.done = true
+	defer f.unlock()
+	defer os.Remove(f.tmp)
+	return f.f.Close()
+}
+
+// countingWriter counts the bytes which are written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	*w.n += int64(n)
+	return n, err
+}
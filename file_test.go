@@ -0,0 +1,180 @@
+package safe
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedTempNamer returns the same temporary name for every write.
+type fixedTempNamer struct {
+	prefixNamer
+}
+
+func (fixedTempNamer) TempName(name string, t time.Time) string {
+	return "tmp-0-" + name
+}
+
+func TestCreate(t *testing.T) {
+	t.Run("should commit the written data when the file is closed", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		f, err := Create("testfile", WithAssertCommitted())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(f, strings.NewReader("new ")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "old data")
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "new data")
+		checkContents(t, "testfile.1", "new data")
+	})
+
+	t.Run("should copy from a *os.File", func(t *testing.T) {
+		createFile(t, "testsource", "some important data")
+		defer clean(t, "testsource")
+		src, err := os.Open("testsource")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer src.Close()
+
+		f, err := Create("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		n, err := io.Copy(f, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 19 {
+			t.Errorf("expect 19 copied bytes but got %d", n)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "some important data")
+	})
+
+	t.Run("should compress the stream", func(t *testing.T) {
+		f, err := Create("testfile", WithCompression(), WithAssertCommitted())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		if _, err := io.Copy(f, strings.NewReader("some important data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ReadFile("testfile", WithDecompression())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "some important data" {
+			t.Errorf("ReadFile does not return the correct file contents. Want %q but got %q", "some important data", got)
+		}
+	})
+
+	t.Run("should return ErrNotStreamable if a validator is set", func(t *testing.T) {
+		_, err := Create("testfile", WithValidator(ValidJSON))
+		if !errors.Is(err, ErrNotStreamable) {
+			t.Errorf("expect ErrNotStreamable but got %v", err)
+		}
+	})
+
+	t.Run("should keep the previous contents if the file is aborted", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		f, err := Create("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("new data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Abort(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "old data")
+		checkNotExist(t, f.tmp)
+		if err := f.Close(); !errors.Is(err, ErrClosed) {
+			t.Errorf("expect ErrClosed but got %v", err)
+		}
+	})
+
+	t.Run("should not truncate the temporary file of another write", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createFile(t, "testdir/tmp-0-testfile", "staged data")
+
+		if _, err := Create("testdir/testfile", WithNamer(fixedTempNamer{})); !os.IsExist(err) {
+			t.Errorf("expect an Exist error but got %v", err)
+		}
+		checkContents(t, "testdir/tmp-0-testfile", "staged data")
+	})
+}
+
+func TestWriteFileFrom(t *testing.T) {
+	t.Run("should commit the data of the reader", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		n, err := WriteFileFrom("testfile", strings.NewReader("some important data"), WithPerm(0600))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 19 {
+			t.Errorf("expect 19 bytes but got %d", n)
+		}
+		checkContents(t, "testfile", "some important data")
+	})
+
+	t.Run("should keep the previous contents if the reader fails", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		fail := errors.New("fail")
+		r := io.MultiReader(strings.NewReader("new data"), errReader{fail})
+		if _, err := WriteFileFrom("testfile", r); !errors.Is(err, fail) {
+			t.Errorf("expect %v but got %v", fail, err)
+		}
+		checkContents(t, "testfile", "old data")
+	})
+}
+
+// errReader fails every read with the error.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,83 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// LockPostfix is the extension appended to the name of a file to get the name of its lock file for WithFlock.
+const LockPostfix = ".lock"
+
+// WithFlock makes WriteFile and Create take an exclusive advisory lock (flock, or LockFileEx on Windows)
+// on the file $(name).lock for the duration of the write, and ReadFile take a shared one,
+// so several processes on the same host can write the same file.
+// The lock file contains the ID of the process which holds the exclusive lock.
+// The lock is released by the operating system when the process exits, so the lock of a crashed process
+// never blocks other processes and the lock file can stay on the disk.
+// While the file is locked, the BusyHandler of WithBusyHandler is consulted. Without one,
+// the lock is retried every SleepTime until the context of the call is done.
+func WithFlock() Option {
+	return func(c *config) {
+		c.flocking = true
+	}
+}
+
+// flock takes the advisory lock of the file with the resolved name if WithFlock is set.
+// The returned function releases the lock.
+func (c *config) flock(name string, exclusive bool) (func(), error) {
+	if !c.flocking {
+		return func() {}, nil
+	}
+	lockname := name + LockPostfix
+	f, err := os.OpenFile(lockname, os.O_RDWR|os.O_CREATE, c.perm)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.acquireFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if exclusive {
+		// The holder is only recorded for diagnostics, so errors are ignored.
+		if err := f.Truncate(0); err == nil {
+			f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+		}
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// acquireFile takes the advisory lock of the open lock file and consults the BusyHandler while it is held
+// by another process.
+func (c *config) acquireFile(f *os.File, exclusive bool) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := tryLockFile(f, exclusive)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errLocked) {
+			return &os.PathError{Op: "flock", Path: f.Name(), Err: err}
+		}
+		if err := c.ctx.Err(); err != nil {
+			return &os.PathError{Op: "flock", Path: f.Name(), Err: err}
+		}
+		if c.busy != nil {
+			if !c.busy(attempt, time.Since(start)) {
+				return &os.PathError{Op: "flock", Path: f.Name(), Err: ErrBusy}
+			}
+			continue
+		}
+		t := time.NewTimer(SleepTime)
+		select {
+		case <-c.ctx.Done():
+			t.Stop()
+			return &os.PathError{Op: "flock", Path: f.Name(), Err: c.ctx.Err()}
+		case <-t.C:
+		}
+	}
+}
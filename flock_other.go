@@ -0,0 +1,22 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!windows
+
+package safe
+
+import (
+	"errors"
+	"os"
+)
+
+// errLocked is never returned on this platform.
+var errLocked = errors.New("safe: file is locked")
+
+// tryLockFile is not supported on this platform.
+func tryLockFile(f *os.File, exclusive bool) error {
+	return errNotSupported
+}
+
+// unlockFile is not supported on this platform.
+func unlockFile(f *os.File) error {
+	return errNotSupported
+}
@@ -0,0 +1,81 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWithFlock(t *testing.T) {
+	t.Run("should lock the file for other processes", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		unlock, err := newConfig([]Option{WithFlock()}).flock("testdir/testfile", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile.lock", strconv.Itoa(os.Getpid()))
+
+		giveUp := WithBusyHandler(func(attempt int, elapsed time.Duration) bool {
+			return false
+		})
+		err = WriteFile("testdir/testfile", []byte("some data"), WithFlock(), giveUp)
+		if !errors.Is(err, ErrBusy) {
+			t.Errorf("expect ErrBusy but got %v", err)
+		}
+		if _, err := ReadFile("testdir/testfile", WithFlock(), giveUp); !errors.Is(err, ErrBusy) {
+			t.Errorf("expect ErrBusy but got %v", err)
+		}
+
+		unlock()
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithFlock()); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+	})
+
+	t.Run("should allow concurrent reads", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile", "some data")
+
+		unlock, err := newConfig([]Option{WithFlock()}).flock("testdir/testfile", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer unlock()
+
+		data, err := ReadFile("testdir/testfile", WithFlock())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "some data" {
+			t.Errorf("expect some data but got %q", data)
+		}
+	})
+	t.Run("should not wait for its own lock while checking the version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		opts := []Option{WithFlock(), WithBusyHandler(BusyTimeout(time.Second))}
+
+		if err := WriteFile("testdir/testfile", []byte("v1"), opts...); err != nil {
+			t.Fatal(err)
+		}
+		_, v, err := ReadVersioned("testdir/testfile", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFileIf("testdir/testfile", []byte("v2"), v, opts...); err != nil {
+			t.Fatal(err)
+		}
+		if err := Update("testdir/testfile", func(old []byte) ([]byte, error) {
+			return append(old, '+'), nil
+		}, opts...); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "v2+")
+	})
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package safe
+
+import (
+	"os"
+	"syscall"
+)
+
+// errLocked is returned by tryLockFile if the file is locked by another process.
+var errLocked error = syscall.EWOULDBLOCK
+
+// tryLockFile takes the advisory lock of the file without waiting.
+func tryLockFile(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	return syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+}
+
+// unlockFile releases the advisory lock of the file.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,227 @@
+//go:build go1.16
+// +build go1.16
+
+package safe
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FS is an fs.FS of the managed files in a directory, e.g. for template.ParseFS or http.FileServer.
+// Files are read with the same fallback to $(name).1 as ReadFile, and the files of this package
+// (alt files, temporary files, versions, backups, checksums, journals, lock files, the manifest and the shadow directory)
+// are hidden from ReadDir and Glob. A file whose write was interrupted is listed under its name.
+// Transforms, decompression, includes and resolvers are not applied by Open, but by ReadFile.
+// Files are written with the methods of WriteFS.
+type FS struct {
+	dir  string
+	opts []Option
+	c    *config
+}
+
+// NewFS returns an FS for the directory. The options apply to every file.
+func NewFS(dir string, opts ...Option) *FS {
+	return &FS{dir: dir, opts: opts, c: newConfig(opts)}
+}
+
+// path returns the name of the file on disk for a name of the FS or an error if the name is invalid.
+func (fsys *FS) path(op string, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(fsys.dir, filepath.FromSlash(name)), nil
+}
+
+// Open opens the file or directory with the name. Directories list only the managed files.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	path, err := fsys.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return &fsDir{File: f, fsys: fsys, name: name}, nil
+	}
+	f, err := Open(path, fsys.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &fsFile{File: f, name: filepath.Base(path)}, nil
+}
+
+// ReadFile reads the file with the name like the ReadFile function of this package.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	path, err := fsys.path("read", name)
+	if err != nil {
+		return nil, err
+	}
+	return ReadFile(path, fsys.opts...)
+}
+
+// Stat returns the FileInfo of the file with the name like the Stat function of this package.
+// The FileInfo has the name even if the alt file was found.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	path, err := fsys.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := Stat(path, fsys.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: filepath.Base(path)}, nil
+}
+
+// ReadDir lists the managed files in the directory with the name sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := fsys.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := f.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.filter(entries), nil
+}
+
+// filter removes the files of this package from the entries and lists orphaned alt files under their names.
+func (fsys *FS) filter(entries []fs.DirEntry) []fs.DirEntry {
+	names := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		names[e.Name()] = true
+	}
+	var visible []fs.DirEntry
+	for _, e := range entries {
+		n := e.Name()
+		if fsys.hidden(n, names) {
+			continue
+		}
+		if primary, ok := fsys.c.isAlt(n); ok && !e.IsDir() {
+			if !names[primary] {
+				visible = append(visible, renamedEntry{DirEntry: e, name: primary})
+			}
+			continue
+		}
+		visible = append(visible, e)
+	}
+	sort.Slice(visible, func(i, j int) bool { return visible[i].Name() < visible[j].Name() })
+	return visible
+}
+
+// hidden reports whether the base name is a file of this package which is not an alt file.
+func (fsys *FS) hidden(name string, names map[string]bool) bool {
+	if _, _, ok := fsys.c.isTemp(name); ok {
+		return true
+	}
+	if name == ShadowDirName || strings.HasPrefix(name, IndexName) || fsys.c.isJournal(name) {
+		return true
+	}
+	if fsys.c.flocking && strings.HasSuffix(name, LockPostfix) && names[strings.TrimSuffix(name, LockPostfix)] {
+		return true
+	}
+	if fsys.c.backup != "" && strings.HasSuffix(name, fsys.c.backup) && names[strings.TrimSuffix(name, fsys.c.backup)] {
+		return true
+	}
+	if fsys.c.checksum && strings.HasSuffix(name, ChecksumPostfix) && names[strings.TrimSuffix(name, ChecksumPostfix)] {
+		return true
+	}
+	if i := strings.LastIndex(name, VersionPostfix); i > 0 && names[name[:i]] && isVersion(name, name[:i]) {
+		return true
+	}
+	return false
+}
+
+// fsFile is a file of an FS. Its FileInfo has the name even if the alt file was opened.
+type fsFile struct {
+	*os.File
+	name string
+}
+
+// Stat returns the FileInfo of the file with the name which was opened.
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: f.name}, nil
+}
+
+// fsDir is a directory of an FS which lists only the managed files.
+type fsDir struct {
+	*os.File
+	fsys    *FS
+	name    string
+	entries []fs.DirEntry
+	read    bool
+}
+
+// ReadDir returns the next n managed files of the directory like fs.ReadDirFile.
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// renamedEntry is a DirEntry of an orphaned alt file which is listed under the name of its file.
+type renamedEntry struct {
+	fs.DirEntry
+	name string
+}
+
+// Name returns the name of the file.
+func (e renamedEntry) Name() string {
+	return e.name
+}
+
+// Info returns the FileInfo of the alt file with the name of the file.
+func (e renamedEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: e.name}, nil
+}
+
+// renamedInfo is a FileInfo with another name.
+type renamedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+// Name returns the name of the file.
+func (i renamedInfo) Name() string {
+	return i.name
+}
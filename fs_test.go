@@ -0,0 +1,53 @@
+//go:build go1.16
+// +build go1.16
+
+package safe
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFS(t *testing.T) {
+	t.Run("should hide the files of this package", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createDir(t, "testdir/sub")
+		if err := WriteFile("testdir/a.json", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/sub/c.json", []byte("c")); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/b.json.1", "b")
+		createFile(t, "testdir/a.json"+time.Now().Format(TimestampFormat), "stale")
+
+		fsys := NewFS("testdir")
+		if err := fstest.TestFS(fsys, "a.json", "b.json", "sub/c.json"); err != nil {
+			t.Fatal(err)
+		}
+		matches, err := fs.Glob(fsys, "*.json*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"a.json", "b.json"}; !reflect.DeepEqual(matches, want) {
+			t.Errorf("expect %v but got %v", want, matches)
+		}
+		data, err := fs.ReadFile(fsys, "b.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "b" {
+			t.Errorf("expect %q but got %q", "b", data)
+		}
+	})
+
+	t.Run("should reject invalid names", func(t *testing.T) {
+		if _, err := NewFS("testdir").Open("../testfile"); err == nil {
+			t.Error("expect an error but got nil")
+		}
+	})
+}
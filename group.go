@@ -0,0 +1,193 @@
+package safe
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Group runs producers which generate the contents of several files concurrently
+// and commits the files only if all of them succeeded. Create a Group with Pipeline.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	opts   []Option
+	m      *Manager
+
+	wg    sync.WaitGroup
+	sem   chan struct{}
+	mu    sync.Mutex
+	err   error
+	files []*staged
+}
+
+// staged is the produced contents of a file of a Group.
+type staged struct {
+	name string
+	path string
+	c    *config
+	data []byte
+}
+
+// Pipeline creates a Group whose producers are canceled with the ctx.
+// The options apply to every file of the Group.
+//
+//	g := safe.Pipeline(ctx)
+//	g.SetLimit(4)
+//	for _, svc := range model.Services {
+//		svc := svc
+//		g.Go(svc.Name+".json", func(w io.Writer) error {
+//			return json.NewEncoder(w).Encode(svc)
+//		})
+//	}
+//	err := g.Wait()
+func Pipeline(ctx context.Context, opts ...Option) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel, opts: opts}
+}
+
+// Context returns the context of the Group which is canceled as soon as a producer fails or Wait returns.
+// Producers which take a long time should stop when it is done.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// SetLimit limits the number of producers which run at the same time. A limit below 1 removes the limit.
+// SetLimit must be called before Go.
+func (g *Group) SetLimit(n int) {
+	if n < 1 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs the produce function in a new goroutine. Everything it writes to w becomes the new contents of the name.
+// The options are applied after the options of the Group.
+func (g *Group) Go(name string, produce func(w io.Writer) error, opts ...Option) {
+	all := make([]Option, 0, len(g.opts)+len(opts))
+	all = append(all, g.opts...)
+	all = append(all, opts...)
+	if g.m != nil {
+		all = g.m.options(name, all)
+	}
+	f := &staged{name: name, c: newConfig(all)}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			select {
+			case g.sem <- struct{}{}:
+				defer func() { <-g.sem }()
+			case <-g.ctx.Done():
+				g.fail(g.ctx.Err())
+				return
+			}
+		}
+		if err := g.ctx.Err(); err != nil {
+			g.fail(err)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := produce(&buf); err != nil {
+			g.fail(&os.PathError{Op: "produce", Path: name, Err: err})
+			return
+		}
+		data, err := f.c.prepare(name, buf.Bytes())
+		if err != nil {
+			g.fail(err)
+			return
+		}
+		f.data = data
+
+		g.mu.Lock()
+		g.files = append(g.files, f)
+		g.mu.Unlock()
+	}()
+}
+
+// fail records the first error and cancels the other producers.
+func (g *Group) fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err == nil {
+		g.err = err
+		g.cancel()
+	}
+}
+
+// Wait waits for all producers and commits the files if all of them succeeded.
+// Otherwise, it returns the first error and nothing is written.
+// If a commit fails, the files which were already committed are restored to their previous contents.
+// The files are replaced one after another, so a crash during the commit can leave some of them replaced.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	defer g.cancel()
+	if g.err != nil {
+		return g.err
+	}
+
+	// Lock the files in a fixed order, so two Groups with the same files can't deadlock.
+	files := g.files
+	for _, f := range files {
+		name, err := f.c.path(f.name)
+		if err != nil {
+			return err
+		}
+		f.path = name
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	for i := 1; i < len(files); i++ {
+		if files[i].path == files[i-1].path {
+			return &os.PathError{Op: "pipeline", Path: files[i].path, Err: os.ErrExist}
+		}
+	}
+	for _, f := range files {
+		_, unlock, err := f.c.begin(f.name)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	var done []prior
+	for _, f := range files {
+		data, err := f.c.read(f.path, f.c.altName(f.path))
+		if err != nil && !os.IsNotExist(err) {
+			restore(done)
+			return err
+		}
+		p := prior{f: f, data: data, existed: err == nil}
+		if err := f.c.replace(f.path, f.data); err != nil {
+			restore(done)
+			return err
+		}
+		done = append(done, p)
+	}
+	return nil
+}
+
+// prior are the contents of a file of a Group before it was committed.
+type prior struct {
+	f       *staged
+	data    []byte
+	existed bool
+}
+
+// restore reverts the committed files to their prior contents in the reverse order.
+func restore(done []prior) {
+	for i := len(done) - 1; i >= 0; i-- {
+		p := done[i]
+		if p.existed {
+			p.f.c.replace(p.f.path, p.data)
+			continue
+		}
+		remove(p.f.path)
+		remove(p.f.c.altName(p.f.path))
+	}
+}
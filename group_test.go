@@ -0,0 +1,114 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Run("should commit the files of all producers", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		g := Pipeline(context.Background(), WithPrefix("testdir"))
+		g.SetLimit(2)
+		for _, name := range []string{"a", "b", "c"} {
+			name := name
+			g.Go(name, func(w io.Writer) error {
+				_, err := io.WriteString(w, "data "+name)
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a", "data a")
+		checkContents(t, "testdir/b", "data b")
+		checkContents(t, "testdir/c", "data c")
+	})
+
+	t.Run("should write nothing if a producer fails", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/a", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+
+		fail := errors.New("fail")
+		g := Pipeline(context.Background(), WithPrefix("testdir"))
+		g.Go("a", func(w io.Writer) error {
+			_, err := io.WriteString(w, "new data")
+			return err
+		})
+		g.Go("b", func(w io.Writer) error {
+			return fail
+		})
+		if err := g.Wait(); !errors.Is(err, fail) {
+			t.Errorf("expect %v but got %v", fail, err)
+		}
+		if g.Context().Err() == nil {
+			t.Error("expect the context to be canceled")
+		}
+		checkContents(t, "testdir/a", "old data")
+		checkNotExist(t, "testdir/b")
+	})
+
+	t.Run("should write nothing if a file is invalid", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		g := Pipeline(context.Background(), WithPrefix("testdir"))
+		g.Go("a.json", func(w io.Writer) error {
+			_, err := io.WriteString(w, "{}")
+			return err
+		})
+		g.Go("b.json", func(w io.Writer) error {
+			_, err := io.WriteString(w, "{")
+			return err
+		}, WithValidator(ValidJSON))
+		if err := g.Wait(); err == nil {
+			t.Error("expect an error")
+		}
+		checkNotExist(t, "testdir/a.json")
+	})
+
+	t.Run("should restore the committed files if a commit fails", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createDir(t, "testdir/b")
+		if err := WriteFile("testdir/a", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/b/c", "")
+
+		g := Pipeline(context.Background(), WithPrefix("testdir"))
+		g.Go("a", func(w io.Writer) error {
+			_, err := io.WriteString(w, "new data")
+			return err
+		})
+		g.Go("b", func(w io.Writer) error {
+			_, err := io.WriteString(w, "new data")
+			return err
+		})
+		if err := g.Wait(); err == nil {
+			t.Error("expect an error")
+		}
+		checkContents(t, "testdir/a", "old data")
+	})
+
+	t.Run("should reject the same file twice", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		g := Pipeline(context.Background(), WithPrefix("testdir"), WithLock())
+		for i := 0; i < 2; i++ {
+			g.Go("a", func(w io.Writer) error { return nil })
+		}
+		if err := g.Wait(); !errors.Is(err, os.ErrExist) {
+			t.Errorf("expect os.ErrExist but got %v", err)
+		}
+	})
+}
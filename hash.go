@@ -0,0 +1,81 @@
+package safe
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"strings"
+	"sync"
+)
+
+// DefaultHash is the name of the hashing algorithm which is used for digests if no other algorithm is selected.
+const DefaultHash = "sha256"
+
+// hashes contains the registered hashing algorithms by name.
+var hashes = struct {
+	sync.RWMutex
+	m map[string]func() hash.Hash
+}{m: map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"crc32c": func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) },
+}}
+
+// RegisterHash makes a hashing algorithm available under the name.
+// Digests are prefixed with the name of the algorithm, so the name must not contain a colon.
+// Registering a name twice replaces the previous algorithm.
+func RegisterHash(name string, fn func() hash.Hash) {
+	if name == "" || strings.Contains(name, ":") {
+		panic("safe: invalid hash name " + name)
+	}
+	hashes.Lock()
+	defer hashes.Unlock()
+	hashes.m[name] = fn
+}
+
+// lookupHash returns the hashing algorithm registered under the name.
+func lookupHash(name string) (func() hash.Hash, error) {
+	hashes.RLock()
+	defer hashes.RUnlock()
+	fn, ok := hashes.m[name]
+	if !ok {
+		return nil, ErrUnknownHash
+	}
+	return fn, nil
+}
+
+// Digest computes the digest of the data using the algorithm with the name.
+// The digest is self-describing and has the format $(algorithm):$(hex).
+func Digest(algorithm string, data []byte) (string, error) {
+	fn, err := lookupHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	h := fn()
+	h.Write(data)
+	return algorithm + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyDigest checks that the digest matches the data.
+// The algorithm is taken from the prefix of the digest.
+func VerifyDigest(digest string, data []byte) (bool, error) {
+	i := strings.IndexByte(digest, ':')
+	if i < 0 {
+		return false, ErrInvalidDigest
+	}
+	got, err := Digest(digest[:i], data)
+	if err != nil {
+		return false, err
+	}
+	return got == digest, nil
+}
+
+// WithHash selects the hashing algorithm which is used for digests.
+// The algorithm must be registered with RegisterHash or be one of the built-in algorithms sha256, sha512 and crc32c.
+func WithHash(algorithm string) Option {
+	return func(c *config) {
+		c.hash = algorithm
+	}
+}
@@ -0,0 +1,65 @@
+package safe
+
+import (
+	"crypto/md5"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	t.Run("should prefix the digest with the name of the algorithm", func(t *testing.T) {
+		got, err := Digest("sha256", []byte("some important data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "sha256:44eb9cfafb3b81e3c4651d3ab35e794a95b4a6af62bd9ec19c9ebfe63367a8b1"
+		if got != want {
+			t.Errorf("Digest does not return the correct digest. Want %q but got %q", want, got)
+		}
+	})
+
+	t.Run("should return ErrUnknownHash if the algorithm is not registered", func(t *testing.T) {
+		_, err := Digest("unknown", []byte("some important data"))
+		if err != ErrUnknownHash {
+			t.Errorf("expect ErrUnknownHash but got %v", err)
+		}
+	})
+
+	t.Run("should use registered algorithms", func(t *testing.T) {
+		RegisterHash("md5", md5.New)
+
+		got, err := Digest("md5", []byte("some important data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := VerifyDigest(got, []byte("some important data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("VerifyDigest does not accept the digest %q", got)
+		}
+	})
+}
+
+func TestVerifyDigest(t *testing.T) {
+	t.Run("should reject a digest of different data", func(t *testing.T) {
+		digest, err := Digest("crc32c", []byte("some important data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := VerifyDigest(digest, []byte("other data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Errorf("VerifyDigest accepts the digest %q for different data", digest)
+		}
+	})
+
+	t.Run("should return ErrInvalidDigest if the digest has no prefix", func(t *testing.T) {
+		_, err := VerifyDigest("e1fff1f2", []byte("some important data"))
+		if err != ErrInvalidDigest {
+			t.Errorf("expect ErrInvalidDigest but got %v", err)
+		}
+	})
+}
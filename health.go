@@ -0,0 +1,146 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StaleTempAge is the age after which a temporary file is considered to be left over by an interrupted write.
+const StaleTempAge = time.Minute
+
+// recoveries contains the time of the last recovery of an interrupted write per directory.
+var recoveries = struct {
+	sync.Mutex
+	m map[string]time.Time
+}{m: make(map[string]time.Time)}
+
+// recordRecovery notes that WriteFile completed an interrupted write of the file with the name.
+func recordRecovery(name string) {
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return
+	}
+	recoveries.Lock()
+	defer recoveries.Unlock()
+	recoveries.m[dir] = time.Now()
+}
+
+// Health summarizes the state of the managed files in a directory.
+type Health struct {
+	// Consistent is the number of files where the alt name matches the name as expected with the strategy
+	// (see Repair): with StrategyHardlink, they point to the same file, and with StrategyRename, the alt file
+	// is a copy of the name. With the other strategies, the alt file is not expected to match the name.
+	Consistent int
+	// Pending is the number of files where the alt name does not match the name as expected,
+	// which happens if a write was interrupted after the alt link was created.
+	Pending int
+	// Degraded is the number of files where only the alt name exists.
+	Degraded int
+	// StaleTemps is the number of temporary files which are older than StaleTempAge (or the age of RecoverDir).
+	StaleTemps int
+	// LastRecovery is the time when this process last completed an interrupted write in the directory.
+	// It is zero if no recovery happened.
+	LastRecovery time.Time
+}
+
+// Gauges returns the values of the Health by metric name, ready to be exported as gauges.
+func (h Health) Gauges() map[string]float64 {
+	var last float64
+	if !h.LastRecovery.IsZero() {
+		last = float64(h.LastRecovery.Unix())
+	}
+	return map[string]float64{
+		"safe_consistent_files":            float64(h.Consistent),
+		"safe_pending_files":               float64(h.Pending),
+		"safe_degraded_files":              float64(h.Degraded),
+		"safe_stale_temp_files":            float64(h.StaleTemps),
+		"safe_last_recovery_timestamp_sec": last,
+	}
+}
+
+// DirHealth inspects the files in the directory and summarizes their state.
+// Files which have no alt name are not managed by this package and are ignored.
+func DirHealth(dir string, opts ...Option) (Health, error) {
+	var h Health
+	c := newConfig(opts)
+	dir, err := c.path(dir)
+	if err != nil {
+		return h, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return h, err
+	}
+	alts, err := c.readAltDir(dir, infos)
+	if err != nil {
+		return h, err
+	}
+
+	files := make(map[string]os.FileInfo, len(infos))
+	for _, info := range infos {
+		files[info.Name()] = info
+	}
+	now := time.Now()
+	for _, info := range infos {
+		if _, created, ok := c.isTemp(info.Name()); ok && now.Sub(created) > c.staleAge {
+			h.StaleTemps++
+		}
+	}
+	for _, info := range alts {
+		if _, _, ok := c.isTemp(info.Name()); ok {
+			continue
+		}
+		name, ok := c.isAlt(info.Name())
+		if !ok {
+			continue
+		}
+		primary, ok := files[name]
+		if !ok {
+			h.Degraded++
+			continue
+		}
+		consistent, err := c.consistent(filepath.Join(dir, name), primary, filepath.Join(c.altDir(dir), info.Name()), info)
+		if err != nil {
+			return h, err
+		}
+		if consistent {
+			h.Consistent++
+		} else {
+			h.Pending++
+		}
+	}
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		recoveries.Lock()
+		h.LastRecovery = recoveries.m[abs]
+		recoveries.Unlock()
+	}
+	return h, nil
+}
+
+// consistent reports whether the alt file of the resolved name matches the name as expected with the strategy,
+// like Repair.
+func (c *config) consistent(name string, info os.FileInfo, alt string, altInfo os.FileInfo) (bool, error) {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return true, nil
+	}
+	s, err := c.strategy(name)
+	if err != nil {
+		return false, err
+	}
+	switch s {
+	case StrategyReplace, StrategyExchange, StrategySymlink:
+		return true, nil
+	}
+	if os.SameFile(info, altInfo) {
+		return true, nil
+	}
+	if s != StrategyRename {
+		return false, nil
+	}
+	// The alt file is a copy with this strategy.
+	return sameContents(name, alt)
+}
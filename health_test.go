@@ -0,0 +1,98 @@
+package safe
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDirHealth(t *testing.T) {
+	t.Run("should count consistent, pending and degraded files and stale temps", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/consistent", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/pending", "old data")
+		createFile(t, "testdir/pending.1", "new data")
+		createFile(t, "testdir/degraded.1", "data")
+		createFile(t, "testdir/unmanaged", "data")
+		createFile(t, "testdir/stale"+time.Now().Add(-time.Hour).Format(TimestampFormat), "data")
+		createFile(t, "testdir/fresh"+time.Now().Format(TimestampFormat), "data")
+
+		h, err := DirHealth("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Health{Consistent: 1, Pending: 1, Degraded: 1, StaleTemps: 1, LastRecovery: h.LastRecovery}
+		if h != want {
+			t.Errorf("DirHealth does not return the correct counts. Want %+v but got %+v", want, h)
+		}
+	})
+
+	t.Run("should report the time of the last recovery", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createFile(t, "testdir/testfile.1", "data")
+
+		before := time.Now()
+		if err := WriteFile("testdir/testfile", []byte("new data")); err != nil {
+			t.Fatal(err)
+		}
+
+		h, err := DirHealth("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.LastRecovery.Before(before) {
+			t.Errorf("DirHealth does not report the recovery. Got %v", h.LastRecovery)
+		}
+	})
+
+	t.Run("should classify the files by their strategy", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		for _, s := range []Strategy{StrategyExchange, StrategyRename} {
+			opts := []Option{WithStrategy(s)}
+			for _, data := range []string{"old data", "new data"} {
+				if err := WriteFile("testdir/"+s.String(), []byte(data), opts...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h, err := DirHealth("testdir", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Consistent != 1 || h.Pending != 0 {
+				t.Errorf("%s: expect 1 consistent file but got %+v", s, h)
+			}
+			os.Remove("testdir/" + s.String())
+			os.Remove("testdir/" + s.String() + ".1")
+		}
+	})
+
+	t.Run("should count the temporary files older than the stale age", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createFile(t, "testdir/stale"+time.Now().Add(-time.Hour).Format(TimestampFormat), "data")
+
+		h, err := DirHealth("testdir", func(c *config) {
+			c.staleAge = 2 * time.Hour
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.StaleTemps != 0 {
+			t.Errorf("expect no stale temporary files but got %d", h.StaleTemps)
+		}
+	})
+
+	t.Run("should return the error if the directory does not exist", func(t *testing.T) {
+		_, err := DirHealth("testdir")
+		if !os.IsNotExist(err) {
+			t.Errorf("expect NotExist error but got %v", err)
+		}
+	})
+}
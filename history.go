@@ -0,0 +1,187 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionID identifies a version of a file which was kept by WriteFileVersioned.
+// It is the suffix of the name of the version after VersionPostfix and encodes the time of the write.
+type VersionID string
+
+// Time returns the time when the version was written.
+func (id VersionID) Time() time.Time {
+	n, _ := strconv.ParseInt(string(id), 36, 64)
+	return time.Unix(0, n)
+}
+
+// VersionInfo describes a version of a file.
+type VersionInfo struct {
+	ID   VersionID
+	Time time.Time
+	Size int64
+}
+
+// WriteFileVersioned writes the file like WriteFile and keeps its contents as a new version $(name).v$(version)
+// (see VersionPostfix), which can be listed with ListVersions and read with ReadVersion.
+// The version is a hard link to the new contents, or a copy if the filesystem does not support hard links.
+// Afterwards, the oldest versions are removed according to the RetentionPolicy (see WithRetention and KeepLast).
+// RemoveFile removes all versions. Versioned writes can not be combined with StrategySymlink and WithBackend.
+func WriteFileVersioned(name string, data []byte, opts ...Option) error {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	data, err = c.prepare(name, data)
+	if err != nil {
+		return err
+	}
+	if err := c.replace(name, data); err != nil {
+		return err
+	}
+	if _, err := c.keepVersion(name, time.Now()); err != nil {
+		return err
+	}
+	return c.applyRetention(name, c.retention)
+}
+
+// keepVersion links the contents of the resolved name to a new version which is written at the time t.
+func (c *config) keepVersion(name string, t time.Time) (string, error) {
+	for {
+		version := versionName(name, t)
+		err := osLink(name, version)
+		if os.IsExist(err) {
+			// A version was written in the same nanosecond.
+			t = t.Add(1)
+			continue
+		}
+		if err != nil && !os.IsNotExist(err) {
+			err = copyFile(name, version, !c.noSync)
+		}
+		if err != nil {
+			os.Remove(version)
+			return "", err
+		}
+		if c.dirSync >= DirSyncParent {
+			return version, syncDir(filepath.Dir(name))
+		}
+		return version, nil
+	}
+}
+
+// ListVersions returns the versions of the file with the name sorted from the oldest to the newest.
+// If there are no versions, it returns an empty list.
+func ListVersions(name string, opts ...Option) ([]VersionInfo, error) {
+	c := newConfig(opts)
+	name, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return listVersions(name)
+}
+
+// listVersions returns the versions of the resolved name sorted from the oldest to the newest.
+func listVersions(name string) ([]VersionInfo, error) {
+	infos, err := ioutil.ReadDir(filepath.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(name)
+	var versions []VersionInfo
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || !isVersion(info.Name(), base) {
+			continue
+		}
+		id := VersionID(strings.TrimPrefix(info.Name(), base+VersionPostfix))
+		versions = append(versions, VersionInfo{ID: id, Time: id.Time(), Size: info.Size()})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Time.Before(versions[j].Time) })
+	return versions, nil
+}
+
+// ReadVersion reads the version of the file with the name. Decompression applies like for ReadFile.
+// If the version does not exist, a NotExist error is returned.
+func ReadVersion(name string, id VersionID, opts ...Option) ([]byte, error) {
+	c := newConfig(opts)
+	name, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	version, err := c.versionPath(name, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(version)
+	if err != nil || !c.decompress {
+		return data, err
+	}
+	return decompress(version, data, c.maxDecompressedSize)
+}
+
+// ReadFileAsOf reads the newest version of the file with the name which was written at or before the time t,
+// e.g. to find out which configuration was active during an incident. It only finds the versions which were kept
+// by WriteFileVersioned. If there is no such version, a NotExist error is returned.
+func ReadFileAsOf(name string, t time.Time, opts ...Option) ([]byte, error) {
+	c := newConfig(opts)
+	resolved, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := listVersions(resolved)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].Time.After(t) {
+			return ReadVersion(name, versions[i].ID, opts...)
+		}
+	}
+	return nil, &os.PathError{Op: "read", Path: resolved, Err: ErrNotExist}
+}
+
+// RestoreVersion atomically replaces the contents of the file with the name with the version, e.g. to roll back
+// a bad configuration in a single call. The version is committed like a write with WriteFile,
+// so the name always points to a complete version. The restored contents are kept as the newest version,
+// so the rollback can be undone, and the oldest versions are removed according to the RetentionPolicy.
+// If the version does not exist, a NotExist error is returned. Restore brings back a removed file instead.
+func RestoreVersion(name string, id VersionID, opts ...Option) error {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	version, err := c.versionPath(name, id)
+	if err != nil {
+		return err
+	}
+	// The version contains the prepared contents, so they are committed as they are.
+	data, err := ioutil.ReadFile(version)
+	if err != nil {
+		return err
+	}
+	if err := c.replace(name, data); err != nil {
+		return err
+	}
+	if _, err := c.keepVersion(name, time.Now()); err != nil {
+		return err
+	}
+	return c.applyRetention(name, c.retention)
+}
+
+// versionPath returns the name of the version of the resolved name with the id
+// or a NotExist error if the id is invalid.
+func (c *config) versionPath(name string, id VersionID) (string, error) {
+	version := name + VersionPostfix + string(id)
+	if !isVersion(filepath.Base(version), filepath.Base(name)) {
+		return "", &os.PathError{Op: "read", Path: version, Err: ErrNotExist}
+	}
+	return version, nil
+}
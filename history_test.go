@@ -0,0 +1,205 @@
+package safe
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWriteFileVersioned(t *testing.T) {
+	t.Run("should keep every version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, contents := range []string{"first", "second", "third"} {
+			if err := WriteFileVersioned("testdir/testfile", []byte(contents)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkContents(t, "testdir/testfile", "third")
+
+		versions, err := ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 3 {
+			t.Fatalf("expect 3 versions but got %+v", versions)
+		}
+		for i, want := range []string{"first", "second", "third"} {
+			got, err := ReadVersion("testdir/testfile", versions[i].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("expect version %d to be %q but got %q", i, want, got)
+			}
+			if versions[i].Size != int64(len(want)) {
+				t.Errorf("expect the size %d but got %d", len(want), versions[i].Size)
+			}
+		}
+		if !versions[0].Time.Before(versions[2].Time) {
+			t.Errorf("expect the versions to be sorted by time but got %+v", versions)
+		}
+	})
+
+	t.Run("should keep the last versions", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, contents := range []string{"first", "second", "third"} {
+			if err := WriteFileVersioned("testdir/testfile", []byte(contents), KeepLast(2)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		versions, err := ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("expect 2 versions but got %+v", versions)
+		}
+		got, err := ReadVersion("testdir/testfile", versions[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "second" {
+			t.Errorf("expect the oldest version to be %q but got %q", "second", got)
+		}
+	})
+
+	t.Run("should copy the version without hard links", func(t *testing.T) {
+		defer withoutLinks()()
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFileVersioned("testdir/testfile", []byte("data"), WithStrategy(StrategyRename)); err != nil {
+			t.Fatal(err)
+		}
+		versions, err := ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 1 {
+			t.Fatalf("expect 1 version but got %+v", versions)
+		}
+	})
+
+	t.Run("should remove the versions with the file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFileVersioned("testdir/testfile", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFile("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		if n := countVersions(t, "testdir/testfile"); n != 0 {
+			t.Errorf("expect no versions but got %d", n)
+		}
+	})
+}
+
+func TestReadVersion(t *testing.T) {
+	t.Run("should return a NotExist error for an unknown version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, id := range []VersionID{"zzzzzzzzzzzz", "../../etc/passwd", ""} {
+			if _, err := ReadVersion("testdir/testfile", id); !os.IsNotExist(err) {
+				t.Errorf("expect a NotExist error for %q but got %v", id, err)
+			}
+		}
+	})
+}
+
+func TestRestoreVersion(t *testing.T) {
+	t.Run("should restore the version and keep it as the newest version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, contents := range []string{"good", "bad"} {
+			if err := WriteFileVersioned("testdir/testfile", []byte(contents)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		versions, err := ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RestoreVersion("testdir/testfile", versions[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "good")
+		checkContents(t, "testdir/testfile.1", "good")
+
+		versions, err = ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 3 {
+			t.Fatalf("expect 3 versions but got %+v", versions)
+		}
+		got, err := ReadVersion("testdir/testfile", versions[2].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "good" {
+			t.Errorf("expect the newest version to be %q but got %q", "good", got)
+		}
+	})
+
+	t.Run("should return a NotExist error for an unknown version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFileVersioned("testdir/testfile", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := RestoreVersion("testdir/testfile", "zzzzzzzzzzzz"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+		checkContents(t, "testdir/testfile", "data")
+	})
+}
+
+func TestReadFileAsOf(t *testing.T) {
+	t.Run("should read the newest version at the time", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "first", "second", "third")
+
+		versions, err := ListVersions("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			t    time.Time
+			want string
+		}{
+			{versions[0].Time, "first"},
+			{versions[1].Time.Add(-1), "first"},
+			{versions[1].Time, "second"},
+			{time.Now(), "third"},
+		}
+		for _, tt := range tests {
+			got, err := ReadFileAsOf("testdir/testfile", tt.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expect %q at %v but got %q", tt.want, tt.t, got)
+			}
+		}
+	})
+
+	t.Run("should return a NotExist error before the first version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "first")
+
+		if _, err := ReadFileAsOf("testdir/testfile", time.Now().Add(-time.Hour)); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+}
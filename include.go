@@ -0,0 +1,129 @@
+package safe
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// The directives which are resolved by ReadFile with WithIncludes.
+const (
+	// ExtendsDirective lists the files which are merged before the contents of the file.
+	ExtendsDirective = "extends"
+	// IncludeDirective lists the files which are merged after the contents of the file.
+	IncludeDirective = "include"
+)
+
+// WithIncludes makes ReadFile treat the file as a JSON object and resolve its extends and include directives.
+// The value of a directive is a name or a list of names relative to the directory of the file.
+// The files listed in "extends" are merged before the file itself, so the file overrides their values.
+// The files listed in "include" are merged after the file, so they override its values.
+// Nested objects are merged recursively. Every file is read with the same fallback to $(name).1 as ReadFile.
+// The result is the merged object without the directives, encoded as JSON.
+func WithIncludes() Option {
+	return func(c *config) {
+		c.includes = true
+	}
+}
+
+// readMerged reads the file with the name and resolves its directives.
+func readMerged(name string, c *config) ([]byte, error) {
+	doc, err := resolve(name, c, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// resolve reads the file with the name and merges it with the files listed in its directives.
+// The visiting set contains the files which are currently being resolved and is used to detect cycles.
+func resolve(name string, c *config, visiting map[string]bool) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	if visiting[abs] {
+		return nil, &os.PathError{Op: "include", Path: name, Err: ErrIncludeCycle}
+	}
+	visiting[abs] = true
+	defer delete(visiting, abs)
+
+	data, err := c.load(name)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, &os.PathError{Op: "include", Path: name, Err: err}
+	}
+
+	extends, err := directive(doc, ExtendsDirective)
+	if err != nil {
+		return nil, &os.PathError{Op: "include", Path: name, Err: err}
+	}
+	includes, err := directive(doc, IncludeDirective)
+	if err != nil {
+		return nil, &os.PathError{Op: "include", Path: name, Err: err}
+	}
+
+	dir := filepath.Dir(name)
+	merged := make(map[string]interface{})
+	for _, base := range extends {
+		m, err := resolve(filepath.Join(dir, base), c, visiting)
+		if err != nil {
+			return nil, err
+		}
+		merge(merged, m)
+	}
+	merge(merged, doc)
+	for _, inc := range includes {
+		m, err := resolve(filepath.Join(dir, inc), c, visiting)
+		if err != nil {
+			return nil, err
+		}
+		merge(merged, m)
+	}
+	return merged, nil
+}
+
+// directive removes the directive with the key from the doc and returns the names it lists.
+func directive(doc map[string]interface{}, key string) ([]string, error) {
+	v, ok := doc[key]
+	if !ok {
+		return nil, nil
+	}
+	delete(doc, key)
+
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		names := make([]string, len(v))
+		for i, n := range v {
+			s, ok := n.(string)
+			if !ok {
+				return nil, ErrInvalidDirective
+			}
+			names[i] = s
+		}
+		return names, nil
+	default:
+		return nil, ErrInvalidDirective
+	}
+}
+
+// merge copies the values of the src into the dst. Nested objects are merged recursively.
+func merge(dst map[string]interface{}, src map[string]interface{}) {
+	for k, v := range src {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if prev, ok := dst[k].(map[string]interface{}); ok {
+				merge(prev, sub)
+				continue
+			}
+			cp := make(map[string]interface{})
+			merge(cp, sub)
+			v = cp
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,48 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadFileWithIncludes(t *testing.T) {
+	t.Run("should merge extended files before and included files after the file", func(t *testing.T) {
+		createFile(t, "testbase", `{ "a": "base", "b": "base", "nested": { "x": 1, "y": 1 } }`)
+		defer clean(t, "testbase")
+		createFile(t, "testinclude.1", `{ "c": "include" }`)
+		defer clean(t, "testinclude.1")
+		createFile(t, "testfile", `{ "extends": "testbase", "include": ["testinclude"], "b": "file", "c": "file", "nested": { "y": 2 } }`)
+		defer clean(t, "testfile")
+
+		got, err := ReadFile("testfile", WithIncludes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"a":"base","b":"file","c":"include","nested":{"x":1,"y":2}}`
+		if string(got) != want {
+			t.Errorf("ReadFile does not return the merged contents. Want %q but got %q", want, got)
+		}
+	})
+
+	t.Run("should return ErrIncludeCycle if a file includes itself", func(t *testing.T) {
+		createFile(t, "testfile", `{ "include": "testbase" }`)
+		defer clean(t, "testfile")
+		createFile(t, "testbase", `{ "extends": "testfile" }`)
+		defer clean(t, "testbase")
+
+		_, err := ReadFile("testfile", WithIncludes())
+		if !errors.Is(err, ErrIncludeCycle) {
+			t.Errorf("expect ErrIncludeCycle but got %v", err)
+		}
+	})
+
+	t.Run("should return ErrInvalidDirective if a directive is not a list of names", func(t *testing.T) {
+		createFile(t, "testfile", `{ "include": 42 }`)
+		defer clean(t, "testfile")
+
+		_, err := ReadFile("testfile", WithIncludes())
+		if !errors.Is(err, ErrInvalidDirective) {
+			t.Errorf("expect ErrInvalidDirective but got %v", err)
+		}
+	})
+}
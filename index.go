@@ -0,0 +1,144 @@
+package safe
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// IndexName is the name of the manifest which lists the managed files of a directory.
+const IndexName = ".safewrite-index"
+
+// indexMu serializes the updates of the manifests.
+var indexMu sync.Mutex
+
+// Index is the manifest of the managed files in a directory.
+type Index struct {
+	// Files maps the base names of the managed files to their entries.
+	Files map[string]IndexEntry `json:"files"`
+}
+
+// IndexEntry describes a managed file in the Index.
+type IndexEntry struct {
+	// Digest of the contents of the file as returned by Digest.
+	Digest string `json:"digest"`
+	// Written is the time of the last write.
+	Written time.Time `json:"written"`
+}
+
+// WithIndex makes WriteFile and RemoveFile maintain the manifest IndexName in the directory of the file.
+// The manifest lists every managed file with the digest of its contents and the time of the last write,
+// so tools like backups can tell managed files apart from other files.
+// The manifest itself is written like WriteFile with the Namer and the permissions of the files, so it is
+// replaced atomically. Its updates are serialized with an advisory lock (see WithFlock) where the platform
+// supports it, so several processes can share a directory. The manifest is updated after the file was
+// committed, so if a process is interrupted in between, the entry of the file is outdated or missing until
+// the file is written again.
+func WithIndex() Option {
+	return func(c *config) {
+		c.index = true
+	}
+}
+
+// ReadIndex reads the manifest of the directory.
+// If the directory has no manifest, an empty Index is returned.
+func ReadIndex(dir string, opts ...Option) (*Index, error) {
+	c := newConfig(opts)
+	dir, err := c.path(dir)
+	if err != nil {
+		return nil, err
+	}
+	return c.indexConfig().readIndex(dir)
+}
+
+// indexConfig returns the config which reads and writes the manifests of the files of c.
+// It keeps the naming, the permissions and the syncs of c, but none of the options for the contents of the files.
+func (c *config) indexConfig() *config {
+	ic := newConfig(nil)
+	ic.namer, ic.shadow, ic.perm = c.namer, c.shadow, c.perm
+	ic.noSync, ic.dirSync = c.noSync, c.dirSync
+	ic.flocking, ic.busy = true, c.busy
+	return ic
+}
+
+// readIndex reads the manifest of the resolved directory.
+func (c *config) readIndex(dir string) (*Index, error) {
+	index := &Index{Files: make(map[string]IndexEntry)}
+	name := filepath.Join(dir, IndexName)
+	data, err := c.read(name, c.altName(name))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, &os.PathError{Op: "read index", Path: dir, Err: err}
+	}
+	if index.Files == nil {
+		index.Files = make(map[string]IndexEntry)
+	}
+	return index, nil
+}
+
+// updateIndex applies the update to the manifest of the resolved directory and writes it
+// if the update reports a change.
+func (c *config) updateIndex(dir string, update func(index *Index) bool) error {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	return c.updateIndexLocked(dir, update)
+}
+
+// updateIndexLocked works like updateIndex. The caller must hold the indexMu.
+func (c *config) updateIndexLocked(dir string, update func(index *Index) bool) error {
+	ic := c.indexConfig()
+	name := filepath.Join(dir, IndexName)
+	unlock, err := ic.flock(name, true)
+	if errors.Is(err, errNotSupported) {
+		// Without advisory locks, the updates are only serialized within the process.
+		unlock, err = func() {}, nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index, err := ic.readIndex(dir)
+	if err != nil {
+		return err
+	}
+	if !update(index) {
+		return nil
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return ic.replace(name, data)
+}
+
+// addToIndex records the write of the resolved name in the manifest of its directory.
+func (c *config) addToIndex(name string, data []byte, t time.Time) error {
+	digest, err := Digest(c.hash, data)
+	if err != nil {
+		return err
+	}
+	return c.updateIndex(filepath.Dir(name), func(index *Index) bool {
+		index.Files[filepath.Base(name)] = IndexEntry{Digest: digest, Written: t.UTC()}
+		return true
+	})
+}
+
+// removeFromIndex removes the resolved name from the manifest of its directory.
+func (c *config) removeFromIndex(name string) error {
+	return c.updateIndex(filepath.Dir(name), func(index *Index) bool {
+		if _, ok := index.Files[filepath.Base(name)]; !ok {
+			return false
+		}
+		delete(index.Files, filepath.Base(name))
+		return true
+	})
+}
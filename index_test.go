@@ -0,0 +1,100 @@
+package safe
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWithIndex(t *testing.T) {
+	t.Run("should record written files in the manifest", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some important data"), WithIndex()); err != nil {
+			t.Fatal(err)
+		}
+
+		index, err := ReadIndex("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, ok := index.Files["testfile"]
+		if !ok {
+			t.Fatal("the manifest does not contain testfile")
+		}
+		want, _ := Digest(DefaultHash, []byte("some important data"))
+		if entry.Digest != want {
+			t.Errorf("the manifest contains the wrong digest. Want %q but got %q", want, entry.Digest)
+		}
+		if entry.Written.IsZero() {
+			t.Errorf("the manifest does not contain the time of the write")
+		}
+	})
+
+	t.Run("should remove removed files from the manifest", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some important data"), WithIndex()); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFile("testdir/testfile", WithIndex()); err != nil {
+			t.Fatal(err)
+		}
+
+		index, err := ReadIndex("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := index.Files["testfile"]; ok {
+			t.Errorf("the manifest still contains testfile")
+		}
+	})
+
+	t.Run("should return an empty index if the directory has no manifest", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		index, err := ReadIndex("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(index.Files) != 0 {
+			t.Errorf("expect an empty index but got %v", index.Files)
+		}
+	})
+
+	t.Run("should use the alt name of the options for the manifest", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some important data"), WithIndex(), WithAltSuffix(".bak")); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/"+IndexName+AltNamePostfix)
+		// Only the alt file of an interrupted write of the manifest is left.
+		if err := os.Remove("testdir/" + IndexName); err != nil {
+			t.Fatal(err)
+		}
+
+		index, err := ReadIndex("testdir", WithAltSuffix(".bak"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := index.Files["testfile"]; !ok {
+			t.Errorf("expect the manifest to be read from its alt file but got %+v", index)
+		}
+	})
+
+	t.Run("should serialize the updates with a lock", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some important data"), WithIndex()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat("testdir/" + IndexName + LockPostfix); err != nil {
+			t.Errorf("expect the manifest to be locked but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,16 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package safe
+
+import "os"
+
+// inode returns zero because the platform does not provide inode numbers and link counts.
+func inode(info os.FileInfo) (uint64, uint64) {
+	return 0, 0
+}
+
+// device returns false because the platform does not provide device IDs.
+func device(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
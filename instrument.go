@@ -0,0 +1,53 @@
+package safe
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Measurement describes a single call of ReadFile or WriteFile which is reported to the instruments.
+type Measurement struct {
+	// Op is "read" or "write".
+	Op string
+	// Name is the name of the file as it was passed to the call.
+	Name string
+	// Size is the number of bytes which were read or passed to WriteFile.
+	Size int
+	// Duration of the call.
+	Duration time.Duration
+	// Err is the error returned by the call.
+	Err error
+}
+
+// WithInstrument makes ReadFile and WriteFile report a Measurement to the instrument function after each call,
+// e.g. to record metrics or to emit a tracing span. It can be passed several times to add more instruments.
+func WithInstrument(instrument func(Measurement)) Option {
+	return func(c *config) {
+		c.instruments = append(c.instruments, instrument)
+	}
+}
+
+// WithSampling makes the instruments of WithInstrument only measure the given fraction of the calls
+// (e.g. 1.0/1000), so the overhead stays negligible on hot paths while the latency distribution is still visible.
+// The calls are sampled at random. The default rate of 1 measures every call.
+func WithSampling(rate float64) Option {
+	return func(c *config) {
+		c.sampleRate = rate
+	}
+}
+
+// sampled reports whether the current call should be measured.
+func (c *config) sampled() bool {
+	if len(c.instruments) == 0 {
+		return false
+	}
+	return c.sampleRate >= 1 || rand.Float64() < c.sampleRate
+}
+
+// measure reports the call which started at the start time to the instruments.
+func (c *config) measure(op string, name string, size int, start time.Time, err error) {
+	m := Measurement{Op: op, Name: name, Size: size, Duration: time.Since(start), Err: err}
+	for _, instrument := range c.instruments {
+		instrument(m)
+	}
+}
@@ -0,0 +1,64 @@
+package safe
+
+import (
+	"testing"
+)
+
+func TestWithInstrument(t *testing.T) {
+	t.Run("should report every read and write", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		var got []Measurement
+		instrument := WithInstrument(func(m Measurement) {
+			got = append(got, m)
+		})
+		if err := WriteFile("testfile", []byte("data"), instrument); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadFile("testfile", instrument); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 2 {
+			t.Fatalf("expect 2 measurements but got %v", got)
+		}
+		if got[0].Op != "write" || got[0].Name != "testfile" || got[0].Size != 4 || got[0].Err != nil {
+			t.Errorf("unexpected measurement %+v", got[0])
+		}
+		if got[1].Op != "read" || got[1].Size != 4 || got[1].Err != nil {
+			t.Errorf("unexpected measurement %+v", got[1])
+		}
+	})
+}
+
+func TestWithSampling(t *testing.T) {
+	t.Run("should report a fraction of the calls", func(t *testing.T) {
+		defer clean(t, "testfile")
+		createFile(t, "testfile", "data")
+
+		n := 0
+		opts := []Option{WithInstrument(func(m Measurement) { n++ }), WithSampling(0.1)}
+		for i := 0; i < 1000; i++ {
+			if _, err := ReadFile("testfile", opts...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n < 30 || n > 300 {
+			t.Errorf("expect about 100 measurements but got %d", n)
+		}
+	})
+
+	t.Run("should report nothing with a rate of 0", func(t *testing.T) {
+		defer clean(t, "testfile")
+		createFile(t, "testfile", "data")
+
+		n := 0
+		if _, err := ReadFile("testfile", WithInstrument(func(m Measurement) { n++ }), WithSampling(0)); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("expect no measurements but got %d", n)
+		}
+	})
+}
@@ -0,0 +1,16 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package safe
+
+import "os"
+
+// errNotSupported is never returned on this platform; a missing hard link support shows up as a permission error.
+var errNotSupported error = &kindError{msg: "safe: hard links not supported", kind: ErrUnsupportedFS}
+
+// linkUnsupportedErrs are the errors of link which always mean that a hard link can not be created there.
+var linkUnsupportedErrs = []error{errNotSupported}
+
+// errLinkDenied is returned by link if the filesystem does not support hard links, but also if the link
+// is not permitted.
+var errLinkDenied error = os.ErrPermission
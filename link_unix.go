@@ -0,0 +1,16 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package safe
+
+import "syscall"
+
+// errNotSupported is returned by link if the filesystem does not support hard links.
+var errNotSupported error = syscall.ENOTSUP
+
+// linkUnsupportedErrs are the errors of link which always mean that a hard link can not be created there.
+var linkUnsupportedErrs = []error{syscall.ENOTSUP, syscall.EOPNOTSUPP, syscall.EXDEV}
+
+// errLinkDenied is returned by link if the filesystem does not support hard links (e.g. FAT),
+// but also if the link is not permitted (e.g. because of protected_hardlinks).
+var errLinkDenied error = syscall.EPERM
@@ -0,0 +1,130 @@
+package safe
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BusyHandler decides what happens if a file is locked by another write.
+// It is called with the number of the failed attempt (starting at 1) and the time since the first attempt.
+// If it returns true, the lock is tried again. Otherwise, the write fails with ErrBusy.
+// The handler is responsible for waiting between the attempts, e.g. with time.Sleep.
+type BusyHandler func(attempt int, elapsed time.Duration) bool
+
+// BusyTimeout returns a BusyHandler which retries every SleepTime until the timeout has elapsed.
+func BusyTimeout(timeout time.Duration) BusyHandler {
+	return func(attempt int, elapsed time.Duration) bool {
+		if elapsed >= timeout {
+			return false
+		}
+		time.Sleep(SleepTime)
+		return true
+	}
+}
+
+// WithLock makes WriteFile and Create lock the file for the duration of the write, so concurrent writes
+// to the same file within the process are serialized. Locking is enabled by default, so WithLock only
+// undoes a previous WithNoLock (e.g. of a Manager). A write waits until the lock is released.
+// Use WithBusyHandler to control the behaviour under contention.
+func WithLock() Option {
+	return func(c *config) {
+		c.locking = true
+	}
+}
+
+// WithNoLock disables the per-file lock of WriteFile and Create.
+// It is meant for callers which already serialize the writes of a file themselves.
+func WithNoLock() Option {
+	return func(c *config) {
+		c.locking = false
+	}
+}
+
+// WithBusyHandler enables locking like WithLock and sets the BusyHandler which is called
+// whenever the file is locked by another write.
+func WithBusyHandler(h BusyHandler) Option {
+	return func(c *config) {
+		c.locking = true
+		c.busy = h
+	}
+}
+
+// pathLock is the lock of a single file.
+type pathLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// locks contains the locks of the files which are currently written or waited for by absolute name.
+var locks = struct {
+	sync.Mutex
+	m map[string]*pathLock
+}{m: make(map[string]*pathLock)}
+
+// lock locks the file with the resolved name if locking is enabled.
+// The returned function releases the lock.
+func (c *config) lock(name string) (func(), error) {
+	if !c.locking {
+		return func() {}, nil
+	}
+	key, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	locks.Lock()
+	l, ok := locks.m[key]
+	if !ok {
+		l = &pathLock{ch: make(chan struct{}, 1)}
+		locks.m[key] = l
+	}
+	l.refs++
+	locks.Unlock()
+
+	if err := c.acquire(name, l); err != nil {
+		releaseRef(key, l)
+		return nil, err
+	}
+	return func() {
+		<-l.ch
+		releaseRef(key, l)
+	}, nil
+}
+
+// acquire takes the lock and consults the BusyHandler while the lock is held by another write.
+func (c *config) acquire(name string, l *pathLock) error {
+	if c.busy == nil {
+		select {
+		case l.ch <- struct{}{}:
+			return nil
+		case <-c.ctx.Done():
+			return &os.PathError{Op: "lock", Path: name, Err: c.ctx.Err()}
+		}
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		select {
+		case l.ch <- struct{}{}:
+			return nil
+		default:
+		}
+		if err := c.ctx.Err(); err != nil {
+			return &os.PathError{Op: "lock", Path: name, Err: err}
+		}
+		if !c.busy(attempt, time.Since(start)) {
+			return &os.PathError{Op: "lock", Path: name, Err: ErrBusy}
+		}
+	}
+}
+
+// releaseRef removes the lock from the map when nobody uses it anymore.
+func releaseRef(key string, l *pathLock) {
+	locks.Lock()
+	defer locks.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(locks.m, key)
+	}
+}
@@ -0,0 +1,89 @@
+package safe
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithLock(t *testing.T) {
+	t.Run("should serialize concurrent writes to the same file", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := WriteFile("testfile", []byte("some data"), WithLock(), WithStrict()); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		checkContents(t, "testfile", "some data")
+	})
+
+	t.Run("should return ErrBusy if the busy handler gives up", func(t *testing.T) {
+		defer clean(t, "testfile")
+		f, err := Create("testfile", WithLock())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var attempts []int
+		err = WriteFile("testfile", []byte("some data"), WithBusyHandler(func(attempt int, elapsed time.Duration) bool {
+			attempts = append(attempts, attempt)
+			return attempt < 3
+		}))
+		if !errors.Is(err, ErrBusy) {
+			t.Errorf("expect ErrBusy but got %v", err)
+		}
+		if len(attempts) != 3 {
+			t.Errorf("expect the busy handler to be called 3 times but got %v", attempts)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile.1")
+		if err := WriteFile("testfile", []byte("some data"), WithBusyHandler(BusyTimeout(time.Second))); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestWithNoLock(t *testing.T) {
+	t.Run("should lock by default", func(t *testing.T) {
+		defer clean(t, "testfile")
+		f, err := Create("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Abort()
+
+		err = WriteFile("testfile", []byte("some data"), WithBusyHandler(func(attempt int, elapsed time.Duration) bool {
+			return false
+		}))
+		if !errors.Is(err, ErrBusy) {
+			t.Errorf("expect ErrBusy but got %v", err)
+		}
+	})
+
+	t.Run("should not lock the file", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		f, err := Create("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Abort()
+
+		if err := WriteFile("testfile", []byte("some data"), WithNoLock()); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "some data")
+	})
+}
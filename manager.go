@@ -0,0 +1,340 @@
+package safe
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Manager provides the methods of this package with a set of default options.
+// The options of a Manager are applied before the options passed to a single call,
+// so every call can override the defaults.
+type Manager struct {
+	opts []Option
+
+	mu         sync.RWMutex
+	extensions map[string][]Option
+}
+
+// New creates a Manager which applies the options to every call.
+func New(opts ...Option) *Manager {
+	return &Manager{
+		opts:       opts,
+		extensions: make(map[string][]Option),
+	}
+}
+
+// NewDir creates a Manager for the files in the base directory. It works like New with WithPrefix,
+// so every tree of files can have its own policies, e.g.
+//
+//	secrets := safe.NewDir("/etc/app/secrets", safe.WithPerm(0600), safe.WithDirSync(safe.DirSyncParent))
+//	cache := safe.NewDir("/var/cache/app", safe.WithFsync(false), safe.WithAltSuffix(".bak"))
+func NewDir(base string, opts ...Option) *Manager {
+	all := make([]Option, 0, len(opts)+1)
+	all = append(all, WithPrefix(base))
+	return New(append(all, opts...)...)
+}
+
+// Extension registers options which the Manager applies to every file with the extension (e.g. ".json").
+// They are applied after the default options of the Manager and before the options of a call.
+// Registering an extension again replaces its options.
+//
+//	m.Extension(".json", safe.WithValidator(safe.ValidJSON), safe.WithTransform(safe.IndentJSON("  ")))
+//	m.Extension(".key", safe.WithPerm(0600))
+//	m.Extension(".gz", safe.WithCompression(), safe.WithDecompression())
+func (m *Manager) Extension(ext string, opts ...Option) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.extensions[ext] = opts
+}
+
+// options returns the default options of the Manager, the options of the extension of the name
+// and the options of a call.
+func (m *Manager) options(name string, opts []Option) []Option {
+	m.mu.RLock()
+	ext := m.extensions[filepath.Ext(name)]
+	m.mu.RUnlock()
+
+	all := make([]Option, 0, len(m.opts)+len(ext)+len(opts))
+	all = append(all, m.opts...)
+	all = append(all, ext...)
+	return append(all, opts...)
+}
+
+// ReadFile works like the ReadFile function of this package but applies the default options of the Manager.
+func (m *Manager) ReadFile(name string, opts ...Option) ([]byte, error) {
+	return ReadFile(name, m.options(name, opts)...)
+}
+
+// WriteFile works like the WriteFile function of this package but applies the default options of the Manager.
+func (m *Manager) WriteFile(name string, data []byte, opts ...Option) error {
+	return WriteFile(name, data, m.options(name, opts)...)
+}
+
+// WriteFileContext works like the WriteFileContext function of this package but applies the default options of the Manager.
+func (m *Manager) WriteFileContext(ctx context.Context, name string, data []byte, opts ...Option) error {
+	return WriteFileContext(ctx, name, data, m.options(name, opts)...)
+}
+
+// ReadFileContext works like the ReadFileContext function of this package but applies the default options of the Manager.
+func (m *Manager) ReadFileContext(ctx context.Context, name string, opts ...Option) ([]byte, error) {
+	return ReadFileContext(ctx, name, m.options(name, opts)...)
+}
+
+// ReadFileExpanded works like the ReadFileExpanded function of this package but applies the default options of the Manager.
+func (m *Manager) ReadFileExpanded(name string, lookup func(string) (string, bool), opts ...Option) ([]byte, error) {
+	return ReadFileExpanded(name, lookup, m.options(name, opts)...)
+}
+
+// RemoveFile works like the RemoveFile function of this package but applies the default options of the Manager.
+func (m *Manager) RemoveFile(name string, opts ...Option) error {
+	return RemoveFile(name, m.options(name, opts)...)
+}
+
+// Create works like the Create function of this package but applies the default options of the Manager.
+func (m *Manager) Create(name string, opts ...Option) (*File, error) {
+	return Create(name, m.options(name, opts)...)
+}
+
+// Adopt works like the Adopt function of this package but applies the default options of the Manager.
+func (m *Manager) Adopt(name string, opts ...Option) error {
+	return Adopt(name, m.options(name, opts)...)
+}
+
+// Open works like the Open function of this package but applies the default options of the Manager.
+func (m *Manager) Open(name string, opts ...Option) (*os.File, error) {
+	return Open(name, m.options(name, opts)...)
+}
+
+// Stat works like the Stat function of this package but applies the default options of the Manager.
+func (m *Manager) Stat(name string, opts ...Option) (os.FileInfo, error) {
+	return Stat(name, m.options(name, opts)...)
+}
+
+// Exists works like the Exists function of this package but applies the default options of the Manager.
+func (m *Manager) Exists(name string, opts ...Option) (bool, error) {
+	return Exists(name, m.options(name, opts)...)
+}
+
+// ReadFileInfo works like the ReadFileInfo function of this package but applies the default options of the Manager.
+func (m *Manager) ReadFileInfo(name string, opts ...Option) ([]byte, ReadInfo, error) {
+	return ReadFileInfo(name, m.options(name, opts)...)
+}
+
+// Verify works like the Verify function of this package but applies the default options of the Manager.
+func (m *Manager) Verify(dir string, opts ...Option) ([]Issue, error) {
+	return Verify(dir, m.options(dir, opts)...)
+}
+
+// Repair works like the Repair function of this package but applies the default options of the Manager.
+func (m *Manager) Repair(name string, opts ...Option) (*RecoveryReport, error) {
+	return Repair(name, m.options(name, opts)...)
+}
+
+// Eject works like the Eject function of this package but applies the default options of the Manager.
+func (m *Manager) Eject(name string, opts ...Option) error {
+	return Eject(name, m.options(name, opts)...)
+}
+
+// CopyFile works like the CopyFile function of this package but applies the default options of the Manager.
+func (m *Manager) CopyFile(src string, dst string, perm os.FileMode, opts ...Option) error {
+	return CopyFile(src, dst, perm, m.options(dst, opts)...)
+}
+
+// MoveFile works like the MoveFile function of this package but applies the default options of the Manager.
+func (m *Manager) MoveFile(oldname string, newname string, opts ...Option) error {
+	return MoveFile(oldname, newname, m.options(oldname, opts)...)
+}
+
+// Exchange works like the Exchange function of this package but applies the default options of the Manager.
+func (m *Manager) Exchange(a string, b string, opts ...Option) error {
+	return Exchange(a, b, m.options(a, opts)...)
+}
+
+// Revert works like the Revert function of this package but applies the default options of the Manager.
+func (m *Manager) Revert(name string, opts ...Option) error {
+	return Revert(name, m.options(name, opts)...)
+}
+
+// WriteDir works like the WriteDir function of this package but applies the default options of the Manager.
+func (m *Manager) WriteDir(name string, populate func(dir string) error, opts ...Option) error {
+	return WriteDir(name, populate, m.options(name, opts)...)
+}
+
+// Prepare works like the Prepare function of this package but applies the default options of the Manager.
+func (m *Manager) Prepare(name string, data []byte, opts ...Option) (*Pending, error) {
+	return Prepare(name, data, m.options(name, opts)...)
+}
+
+// Update works like the Update function of this package but applies the default options of the Manager.
+func (m *Manager) Update(name string, fn func(old []byte) ([]byte, error), opts ...Option) error {
+	return Update(name, fn, m.options(name, opts)...)
+}
+
+// ReadVersioned works like the ReadVersioned function of this package but applies the default options of the Manager.
+func (m *Manager) ReadVersioned(name string, opts ...Option) ([]byte, Version, error) {
+	return ReadVersioned(name, m.options(name, opts)...)
+}
+
+// WriteFileVersioned works like the WriteFileVersioned function of this package but applies the default options of the Manager.
+func (m *Manager) WriteFileVersioned(name string, data []byte, opts ...Option) error {
+	return WriteFileVersioned(name, data, m.options(name, opts)...)
+}
+
+// ListVersions works like the ListVersions function of this package but applies the default options of the Manager.
+func (m *Manager) ListVersions(name string, opts ...Option) ([]VersionInfo, error) {
+	return ListVersions(name, m.options(name, opts)...)
+}
+
+// ReadVersion works like the ReadVersion function of this package but applies the default options of the Manager.
+func (m *Manager) ReadVersion(name string, id VersionID, opts ...Option) ([]byte, error) {
+	return ReadVersion(name, id, m.options(name, opts)...)
+}
+
+// ReadFileAsOf works like the ReadFileAsOf function of this package but applies the default options of the Manager.
+func (m *Manager) ReadFileAsOf(name string, t time.Time, opts ...Option) ([]byte, error) {
+	return ReadFileAsOf(name, t, m.options(name, opts)...)
+}
+
+// DiffVersions works like the DiffVersions function of this package but applies the default options of the Manager.
+func (m *Manager) DiffVersions(name string, a VersionID, b VersionID, opts ...Option) ([]byte, error) {
+	return DiffVersions(name, a, b, m.options(name, opts)...)
+}
+
+// RestoreVersion works like the RestoreVersion function of this package but applies the default options of the Manager.
+func (m *Manager) RestoreVersion(name string, id VersionID, opts ...Option) error {
+	return RestoreVersion(name, id, m.options(name, opts)...)
+}
+
+// ApplyRetention works like the ApplyRetention function of this package but applies the default options of the Manager.
+func (m *Manager) ApplyRetention(name string, p RetentionPolicy, opts ...Option) error {
+	return ApplyRetention(name, p, m.options(name, opts)...)
+}
+
+// ReadAudit works like the ReadAudit function of this package but applies the default options of the Manager.
+func (m *Manager) ReadAudit(name string, opts ...Option) ([]AuditEntry, error) {
+	return ReadAudit(name, m.options(name, opts)...)
+}
+
+// WriteFileIf works like the WriteFileIf function of this package but applies the default options of the Manager.
+func (m *Manager) WriteFileIf(name string, data []byte, version Version, opts ...Option) error {
+	return WriteFileIf(name, data, version, m.options(name, opts)...)
+}
+
+// NewWriter works like the NewWriter function of this package but applies the default options of the Manager.
+func (m *Manager) NewWriter(name string, opts ...Option) *Writer {
+	return NewWriter(name, m.options(name, opts)...)
+}
+
+// WriteFileIfChanged works like the WriteFileIfChanged function of this package but applies the default options of the Manager.
+func (m *Manager) WriteFileIfChanged(name string, data []byte, opts ...Option) (bool, error) {
+	return WriteFileIfChanged(name, data, m.options(name, opts)...)
+}
+
+// WriteFileFrom works like the WriteFileFrom function of this package but applies the default options of the Manager.
+func (m *Manager) WriteFileFrom(name string, r io.Reader, opts ...Option) (int64, error) {
+	return WriteFileFrom(name, r, m.options(name, opts)...)
+}
+
+// CommitFD works like the CommitFD function of this package but applies the default options of the Manager.
+// With WithPrefix, a broker can restrict the files which can be installed to a directory.
+func (m *Manager) CommitFD(fd uintptr, name string, opts ...Option) error {
+	return CommitFD(fd, name, m.options(name, opts)...)
+}
+
+// RemoveFileAfter works like the RemoveFileAfter function of this package but applies the default options of the Manager.
+func (m *Manager) RemoveFileAfter(name string, retention time.Duration, opts ...Option) error {
+	return RemoveFileAfter(name, retention, m.options(name, opts)...)
+}
+
+// Restore works like the Restore function of this package but applies the default options of the Manager.
+func (m *Manager) Restore(name string, opts ...Option) error {
+	return Restore(name, m.options(name, opts)...)
+}
+
+// Acquire works like the Acquire function of this package but applies the default options of the Manager.
+func (m *Manager) Acquire(name string, opts ...Option) *Handle {
+	return Acquire(name, m.options(name, opts)...)
+}
+
+// Watch works like the Watch function of this package but applies the default options of the Manager.
+func (m *Manager) Watch(ctx context.Context, name string, opts ...Option) <-chan Event {
+	return Watch(ctx, name, m.options(name, opts)...)
+}
+
+// WatchDir works like the WatchDir function of this package but applies the default options of the Manager.
+func (m *Manager) WatchDir(ctx context.Context, dir string, codec Codec, opts ...Option) <-chan DirEvent {
+	return WatchDir(ctx, dir, codec, m.options(dir, opts)...)
+}
+
+// Begin works like the Begin function of this package but applies the default options of the Manager.
+// The options for the extension of each file are applied as well.
+func (m *Manager) Begin(opts ...Option) *Tx {
+	tx := Begin(opts...)
+	tx.m = m
+	return tx
+}
+
+// Pipeline works like the Pipeline function of this package but applies the default options of the Manager.
+// The options for the extension of each file are applied as well.
+func (m *Manager) Pipeline(ctx context.Context, opts ...Option) *Group {
+	g := Pipeline(ctx, opts...)
+	g.m = m
+	return g
+}
+
+// DirHealth works like the DirHealth function of this package but applies the default options of the Manager.
+func (m *Manager) DirHealth(dir string, opts ...Option) (Health, error) {
+	return DirHealth(dir, m.options(dir, opts)...)
+}
+
+// RecoverDir works like the RecoverDir function of this package but applies the default options of the Manager.
+func (m *Manager) RecoverDir(dir string, opts ...Option) (*RecoveryReport, error) {
+	return RecoverDir(dir, m.options(dir, opts)...)
+}
+
+// ProbeDir works like the ProbeDir function of this package but applies the default options of the Manager.
+func (m *Manager) ProbeDir(dir string, opts ...Option) (Capabilities, error) {
+	return ProbeDir(dir, m.options(dir, opts)...)
+}
+
+// SelfTest works like the SelfTest function of this package but applies the default options of the Manager.
+func (m *Manager) SelfTest(dir string, opts ...Option) error {
+	return SelfTest(dir, m.options(dir, opts)...)
+}
+
+// Recover works like the Recover function of this package but applies the default options of the Manager.
+func (m *Manager) Recover(dir string, opts ...Option) (*RecoveryReport, error) {
+	return Recover(dir, m.options(dir, opts)...)
+}
+
+// ReadIndex works like the ReadIndex function of this package but applies the default options of the Manager.
+func (m *Manager) ReadIndex(dir string, opts ...Option) (*Index, error) {
+	return ReadIndex(dir, m.options(dir, opts)...)
+}
+
+// WithPrefix resolves all names relative to the prefix directory.
+// Names must be relative and must not point outside of the prefix (e.g. using ".."),
+// otherwise a *os.PathError wrapping ErrInvalidPath is returned.
+// Combined with a Manager, this gives every tenant of an application its own directory.
+func WithPrefix(path string) Option {
+	return func(c *config) {
+		c.prefix = path
+	}
+}
+
+// path resolves the name relative to the prefix.
+func (c *config) path(name string) (string, error) {
+	if c.prefix == "" {
+		return name, nil
+	}
+	cleaned := filepath.Clean(name)
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", &os.PathError{Op: "resolve", Path: name, Err: ErrInvalidPath}
+	}
+	return filepath.Join(c.prefix, cleaned), nil
+}
@@ -0,0 +1,122 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	t.Run("should resolve the names relative to the prefix", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		m := New(WithPrefix("testdir"))
+
+		if err := m.WriteFile("testfile", []byte("some important data")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some important data")
+		checkContents(t, "testdir/testfile.1", "some important data")
+
+		got, err := m.ReadFile("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "some important data" {
+			t.Errorf("ReadFile does not return the correct file contents. Want %q but got %q", "some important data", got)
+		}
+
+		if err := m.RemoveFile("testfile"); err != nil {
+			t.Error(err)
+		}
+		checkNotExist(t, "testdir/testfile")
+		checkNotExist(t, "testdir/testfile.1")
+	})
+
+	t.Run("should create the file relative to the prefix", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		m := New(WithPrefix("testdir"))
+
+		f, err := m.Create("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("some important data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some important data")
+	})
+
+	t.Run("should return ErrInvalidPath if a name points outside of the prefix", func(t *testing.T) {
+		m := New(WithPrefix("testdir"))
+
+		for _, name := range []string{"../testfile", "a/../../testfile", "/etc/passwd"} {
+			err := m.WriteFile(name, []byte("some data"))
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("expect ErrInvalidPath for %q but got %v", name, err)
+			}
+		}
+		checkNotExist(t, "testfile")
+	})
+
+	t.Run("should resolve includes relative to the prefix", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createFile(t, "testbase", `{}`)
+		defer clean(t, "testbase")
+		createFile(t, "testdir/testfile", `{ "extends": "../testbase" }`)
+		m := New(WithPrefix("testdir"), WithIncludes())
+
+		_, err := m.ReadFile("testfile")
+		if !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("expect ErrInvalidPath but got %v", err)
+		}
+	})
+	t.Run("should apply the options registered for the extension of the file", func(t *testing.T) {
+		m := New()
+		m.Extension(".json", WithValidator(ValidJSON))
+
+		if err := m.WriteFile("testfile.json", []byte("{")); err == nil {
+			t.Errorf("expect the validator to reject the data")
+		}
+		checkNotExist(t, "testfile.json")
+
+		if err := m.WriteFile("testfile.txt", []byte("{")); err != nil {
+			t.Error(err)
+		}
+		defer clean(t, "testfile.txt")
+		defer clean(t, "testfile.txt.1")
+	})
+}
+
+func TestNewDir(t *testing.T) {
+	t.Run("should apply the policies of each Manager to its own directory", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createDir(t, "testdir/a")
+		createDir(t, "testdir/b")
+		a := NewDir("testdir/a", WithAltSuffix(".bak"))
+		b := NewDir("testdir/b")
+
+		if err := a.WriteFile("testfile", []byte("data a")); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.WriteFile("testfile", []byte("data b")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a/testfile.bak", "data a")
+		checkNotExist(t, "testdir/a/testfile.1")
+		checkContents(t, "testdir/b/testfile.1", "data b")
+
+		h, err := a.DirHealth(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Consistent != 1 {
+			t.Errorf("expect 1 consistent file but got %+v", h)
+		}
+	})
+}
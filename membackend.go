@@ -0,0 +1,168 @@
+package safe
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// MemBackend is a Backend which keeps the files in memory, e.g. for unit tests which must not touch the disk.
+// Hard links share their contents like on a real filesystem. Directories are implicit. It is safe for concurrent use.
+// Like a filesystem, it tracks what was synced: Sync of a file persists its contents and Sync of a directory
+// persists the names of the files in it. Crash discards everything else.
+type MemBackend struct {
+	mu    sync.Mutex
+	files map[string]*memFile
+	// synced are the names which were persisted by the Sync of their directory.
+	synced map[string]*memFile
+}
+
+// memFile is the contents of a file of a MemBackend which may have several names.
+type memFile struct {
+	data []byte
+	perm os.FileMode
+	// synced is the contents which were persisted by the last Sync of the file.
+	synced []byte
+}
+
+// NewMemBackend returns an empty MemBackend.
+func NewMemBackend() *MemBackend {
+	return &MemBackend{files: make(map[string]*memFile), synced: make(map[string]*memFile)}
+}
+
+// Names returns the names of all files in sorted order.
+func (m *MemBackend) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Create creates an empty file. The data is visible as soon as it is written.
+func (m *MemBackend) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	name = filepath.Clean(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; ok {
+		return nil, &os.PathError{Op: "create", Path: name, Err: os.ErrExist}
+	}
+	f := &memFile{perm: perm}
+	m.files[name] = f
+	return &memWriter{m: m, f: f}, nil
+}
+
+// Link adds the name newname to the file oldname.
+func (m *MemBackend) Link(oldname string, newname string) error {
+	oldname, newname = filepath.Clean(oldname), filepath.Clean(newname)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[oldname]
+	if !ok {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+	if _, ok := m.files[newname]; ok {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	m.files[newname] = f
+	return nil
+}
+
+// Rename moves the file oldname to newname.
+func (m *MemBackend) Rename(oldname string, newname string) error {
+	oldname, newname = filepath.Clean(oldname), filepath.Clean(newname)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[oldname]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+	delete(m.files, oldname)
+	m.files[newname] = f
+	return nil
+}
+
+// Remove removes the name of a file.
+func (m *MemBackend) Remove(name string) error {
+	name = filepath.Clean(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// ReadFile returns a copy of the contents of the file.
+func (m *MemBackend) ReadFile(name string) ([]byte, error) {
+	name = filepath.Clean(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+// Sync persists the contents of the file with the name, or the names of the files in the directory with the name.
+func (m *MemBackend) Sync(name string) error {
+	name = filepath.Clean(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.files[name]; ok {
+		f.synced = append([]byte(nil), f.data...)
+		return nil
+	}
+	for n := range m.synced {
+		if filepath.Dir(n) == name {
+			delete(m.synced, n)
+		}
+	}
+	for n, f := range m.files {
+		if filepath.Dir(n) == name {
+			m.synced[n] = f
+		}
+	}
+	return nil
+}
+
+// Crash simulates a crash of the operating system: the names and the contents which were not synced are lost.
+func (m *MemBackend) Crash() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files = make(map[string]*memFile, len(m.synced))
+	for n, f := range m.synced {
+		f.data = append([]byte(nil), f.synced...)
+		m.files[n] = f
+	}
+}
+
+// memWriter writes to a file of a MemBackend.
+type memWriter struct {
+	m *MemBackend
+	f *memFile
+}
+
+// Write appends the data to the file.
+func (w *memWriter) Write(p []byte) (int, error) {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	var buf bytes.Buffer
+	buf.Write(w.f.data)
+	buf.Write(p)
+	w.f.data = buf.Bytes()
+	return len(p), nil
+}
+
+// Close does nothing.
+func (w *memWriter) Close() error {
+	return nil
+}
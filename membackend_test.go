@@ -0,0 +1,73 @@
+package safe
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMemBackend(t *testing.T) {
+	t.Run("should share the contents of hard links", func(t *testing.T) {
+		b := NewMemBackend()
+		w, err := b.Create("a", 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Link("a", "b"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := b.ReadFile("b"); string(data) != "some data" {
+			t.Errorf("expect the link to share the contents but got %q", data)
+		}
+	})
+
+	t.Run("should behave like a filesystem for existing and missing files", func(t *testing.T) {
+		b := NewMemBackend()
+		if _, err := b.Create("a", 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.Create("a", 0600); !os.IsExist(err) {
+			t.Errorf("expect an Exist error but got %v", err)
+		}
+		if err := b.Link("a", "a"); !os.IsExist(err) {
+			t.Errorf("expect an Exist error but got %v", err)
+		}
+		if err := b.Remove("b"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+		if err := b.Rename("a", "b"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.ReadFile("a"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+
+	t.Run("should lose what was not synced in a crash", func(t *testing.T) {
+		b := NewMemBackend()
+		for _, name := range []string{"dir/synced", "dir/unsynced"} {
+			w, err := b.Create(name, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte("some data"))
+			w.Close()
+		}
+		b.Sync("dir/synced")
+		b.Sync("dir")
+		b.Create("dir/unlisted", 0600)
+
+		b.Crash()
+		if names := b.Names(); len(names) != 2 || names[0] != "dir/synced" || names[1] != "dir/unsynced" {
+			t.Errorf("expect the names which were synced but got %v", names)
+		}
+		if data, _ := b.ReadFile("dir/synced"); string(data) != "some data" {
+			t.Errorf("expect the synced contents but got %q", data)
+		}
+		if data, _ := b.ReadFile("dir/unsynced"); len(data) != 0 {
+			t.Errorf("expect the contents which were not synced to be lost but got %q", data)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// Package collector exposes the metrics of the metrics package as a prometheus.Collector:
+//
+//	m := metrics.New()
+//	files := safe.New(m.Options()...)
+//	prometheus.MustRegister(collector.New(m))
+//
+// It is a separate module, so the safe package does not depend on the Prometheus client library.
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robojones/safe-write/metrics"
+)
+
+// Collector implements prometheus.Collector for a metrics.Metrics.
+type Collector struct {
+	m        *metrics.Metrics
+	counters []*prometheus.Desc
+	duration *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// New creates a Collector which collects the current values of the metrics whenever it is scraped.
+func New(m *metrics.Metrics) *Collector {
+	c := &Collector{
+		m:        m,
+		duration: prometheus.NewDesc(metrics.WriteDurationName, metrics.WriteDurationHelp, nil, nil),
+	}
+	for _, counter := range m.Snapshot().Counters() {
+		c.counters = append(c.counters, prometheus.NewDesc(counter.Name, counter.Help, nil, nil))
+	}
+	return c
+}
+
+// Describe sends the descriptions of the metrics. It implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.counters {
+		ch <- d
+	}
+	ch <- c.duration
+}
+
+// Collect sends the current values of the metrics. It implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.m.Snapshot()
+	for i, counter := range s.Counters() {
+		ch <- prometheus.MustNewConstMetric(c.counters[i], prometheus.CounterValue, float64(counter.Value))
+	}
+
+	h := s.WriteDuration
+	buckets := make(map[float64]uint64, len(h.Buckets))
+	var cumulative uint64
+	for i, le := range h.Buckets {
+		cumulative += h.Counts[i]
+		buckets[le] = cumulative
+	}
+	ch <- prometheus.MustNewConstHistogram(c.duration, h.Count, h.Sum, buckets)
+}
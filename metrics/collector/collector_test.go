@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	safe "github.com/robojones/safe-write"
+	"github.com/robojones/safe-write/metrics"
+)
+
+func TestCollector(t *testing.T) {
+	defer os.Remove("testfile")
+	defer os.Remove("testfile.1")
+
+	m := metrics.New()
+	files := safe.New(m.Options()...)
+	if err := files.WriteFile("testfile", []byte("some data")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should collect the metrics", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		if err := reg.Register(New(m)); err != nil {
+			t.Fatal(err)
+		}
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]float64)
+		for _, f := range families {
+			metric := f.GetMetric()[0]
+			if h := metric.GetHistogram(); h != nil {
+				got[f.GetName()] = float64(h.GetSampleCount())
+			} else {
+				got[f.GetName()] = metric.GetCounter().GetValue()
+			}
+		}
+		want := map[string]float64{
+			"safe_writes_total":           1,
+			"safe_written_bytes_total":    9,
+			"safe_write_duration_seconds": 1,
+			"safe_reads_total":            0,
+		}
+		for name, value := range want {
+			if got[name] != value {
+				t.Errorf("expect %s to be %g but got %g", name, value, got[name])
+			}
+		}
+		if len(families) != 8 {
+			t.Errorf("expect 8 metrics but got %d", len(families))
+		}
+	})
+}
@@ -0,0 +1,10 @@
+module github.com/robojones/safe-write/metrics/collector
+
+go 1.14
+
+require (
+	github.com/prometheus/client_golang v1.11.1
+	github.com/robojones/safe-write v0.0.0
+)
+
+replace github.com/robojones/safe-write => ../..
@@ -0,0 +1,212 @@
+/*
+Package metrics collects metrics about the reads and writes of the safe package from its observers and instruments.
+
+The metrics are published with expvar and served in the text format of Prometheus, so no client library is needed:
+
+	m := metrics.New()
+	files := safe.New(m.Options()...)
+	m.Publish("safe")
+	http.Handle("/metrics", m)
+
+Applications which use the Prometheus client library can register them as a prometheus.Collector
+with the separate module github.com/robojones/safe-write/metrics/collector instead, which keeps the client library
+out of the dependencies of this module.
+
+The following metrics are collected. The durations are measured in seconds.
+
+	safe_writes_total               calls of WriteFile
+	safe_write_errors_total         calls of WriteFile which failed
+	safe_write_duration_seconds     histogram of the duration of WriteFile
+	safe_written_bytes_total        bytes passed to WriteFile
+	safe_reads_total                calls of ReadFile
+	safe_read_retries_total         retries of ReadFile because neither the file nor its alt file existed
+	safe_alt_reads_total            reads which fell back to the alt file
+	safe_temp_cleanups_total        temporary files which were removed after a write
+*/
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	safe "github.com/robojones/safe-write"
+)
+
+// DefaultBuckets are the upper bounds of the buckets of the write duration histogram in seconds.
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+
+// Metrics collects the metrics of the calls which are made with its options. It is safe for concurrent use.
+type Metrics struct {
+	mu sync.Mutex
+	s  Snapshot
+}
+
+// Snapshot contains the values of the metrics at a point in time.
+type Snapshot struct {
+	Writes       uint64 `json:"writes"`
+	WriteErrors  uint64 `json:"write_errors"`
+	BytesWritten uint64 `json:"bytes_written"`
+	Reads        uint64 `json:"reads"`
+	ReadRetries  uint64 `json:"read_retries"`
+	AltReads     uint64 `json:"alt_reads"`
+	TempCleanups uint64 `json:"temp_cleanups"`
+	// WriteDuration is the histogram of the duration of the writes.
+	WriteDuration Histogram `json:"write_duration"`
+}
+
+// Histogram counts the observed durations in buckets.
+type Histogram struct {
+	// Buckets are the upper bounds of the buckets in seconds.
+	Buckets []float64 `json:"buckets"`
+	// Counts are the numbers of durations in each bucket (not cumulative).
+	// The last count contains the durations which exceed every bucket.
+	Counts []uint64 `json:"counts"`
+	// Sum is the sum of the durations in seconds.
+	Sum float64 `json:"sum"`
+	// Count is the number of durations.
+	Count uint64 `json:"count"`
+}
+
+// observe adds the duration to the histogram.
+func (h *Histogram) observe(d time.Duration) {
+	s := d.Seconds()
+	i := 0
+	for i < len(h.Buckets) && s > h.Buckets[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Sum += s
+	h.Count++
+}
+
+// New creates a Metrics with DefaultBuckets.
+func New() *Metrics {
+	return NewWithBuckets(DefaultBuckets)
+}
+
+// NewWithBuckets creates a Metrics whose write duration histogram has the buckets, which are upper bounds in seconds
+// in increasing order.
+func NewWithBuckets(buckets []float64) *Metrics {
+	m := &Metrics{}
+	m.s.WriteDuration.Buckets = append([]float64(nil), buckets...)
+	m.s.WriteDuration.Counts = make([]uint64, len(buckets)+1)
+	return m
+}
+
+// Options returns the options which feed the metrics. Pass them to safe.New or to single calls.
+func (m *Metrics) Options() []safe.Option {
+	return []safe.Option{safe.WithObserver(m), safe.WithInstrument(m.measure)}
+}
+
+// Observe counts the steps of the reads and writes. It implements safe.Observer.
+func (m *Metrics) Observe(o safe.Observation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch o.Kind {
+	case safe.EventRetry:
+		m.s.ReadRetries++
+	case safe.EventAltRead:
+		m.s.AltReads++
+	case safe.EventCleanup:
+		if o.Err == nil {
+			m.s.TempCleanups++
+		}
+	}
+}
+
+// measure counts the calls of ReadFile and WriteFile.
+func (m *Metrics) measure(ms safe.Measurement) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ms.Op == "read" {
+		m.s.Reads++
+		return
+	}
+	m.s.Writes++
+	if ms.Err != nil {
+		m.s.WriteErrors++
+		return
+	}
+	m.s.BytesWritten += uint64(ms.Size)
+	m.s.WriteDuration.observe(ms.Duration)
+}
+
+// Snapshot returns the current values of the metrics.
+func (m *Metrics) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.s
+	s.WriteDuration.Buckets = append([]float64(nil), m.s.WriteDuration.Buckets...)
+	s.WriteDuration.Counts = append([]uint64(nil), m.s.WriteDuration.Counts...)
+	return s
+}
+
+// Publish publishes the Snapshot as an expvar variable with the name, so it is served at /debug/vars.
+// Like expvar.Publish, it panics if the name is already in use.
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.Snapshot()
+	}))
+}
+
+// Counter is a counter of a Snapshot with its name and help text in Prometheus.
+type Counter struct {
+	Name  string
+	Help  string
+	Value uint64
+}
+
+// Counters returns the counters of the snapshot, so they can be exposed by a Prometheus collector as well
+// (see the collector package).
+func (s Snapshot) Counters() []Counter {
+	return []Counter{
+		{"safe_writes_total", "Calls of WriteFile.", s.Writes},
+		{"safe_write_errors_total", "Calls of WriteFile which failed.", s.WriteErrors},
+		{"safe_written_bytes_total", "Bytes passed to WriteFile.", s.BytesWritten},
+		{"safe_reads_total", "Calls of ReadFile.", s.Reads},
+		{"safe_read_retries_total", "Retries of ReadFile because neither the file nor its alt file existed.", s.ReadRetries},
+		{"safe_alt_reads_total", "Reads which fell back to the alt file.", s.AltReads},
+		{"safe_temp_cleanups_total", "Temporary files which were removed after a write.", s.TempCleanups},
+	}
+}
+
+// WriteDurationName and WriteDurationHelp describe the histogram of the write duration in Prometheus.
+const (
+	WriteDurationName = "safe_write_duration_seconds"
+	WriteDurationHelp = "Duration of WriteFile."
+)
+
+// WritePrometheus writes the metrics in the text format of Prometheus.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	s := m.Snapshot()
+	for _, c := range s.Counters() {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.Name, c.Help, c.Name, c.Name, c.Value); err != nil {
+			return err
+		}
+	}
+
+	h := s.WriteDuration
+	const name = WriteDurationName
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, WriteDurationHelp, name); err != nil {
+		return err
+	}
+	var cumulative uint64
+	for i, le := range h.Buckets {
+		cumulative += h.Counts[i]
+		if _, err := fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, le, cumulative); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %g\n%s_count %d\n", name, h.Count, name, h.Sum, name, h.Count)
+	return err
+}
+
+// ServeHTTP serves the metrics in the text format of Prometheus, so the Metrics can be mounted at /metrics.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WritePrometheus(w)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	safe "github.com/robojones/safe-write"
+)
+
+func TestMetrics(t *testing.T) {
+	defer os.Remove("testfile")
+	defer os.Remove("testfile.1")
+
+	m := New()
+	files := safe.New(m.Options()...)
+	if err := files.WriteFile("testfile", []byte("some data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove("testfile"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files.ReadFile("testfile"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files.ReadFile("missing", safe.WithRetries(2)); !os.IsNotExist(err) {
+		t.Fatalf("expect a NotExist error but got %v", err)
+	}
+
+	t.Run("should count the reads and writes", func(t *testing.T) {
+		s := m.Snapshot()
+		if s.Writes != 1 || s.WriteErrors != 0 || s.BytesWritten != 9 {
+			t.Errorf("unexpected write metrics %+v", s)
+		}
+		if s.Reads != 2 || s.ReadRetries != 1 || s.AltReads != 1 {
+			t.Errorf("unexpected read metrics %+v", s)
+		}
+		if s.TempCleanups != 1 {
+			t.Errorf("expect 1 cleanup but got %d", s.TempCleanups)
+		}
+		if s.WriteDuration.Count != 1 {
+			t.Errorf("expect 1 write duration but got %+v", s.WriteDuration)
+		}
+	})
+
+	t.Run("should publish the metrics with expvar", func(t *testing.T) {
+		m.Publish("safe_test")
+		var s Snapshot
+		if err := json.Unmarshal([]byte(expvar.Get("safe_test").String()), &s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Writes != 1 {
+			t.Errorf("expect 1 write but got %+v", s)
+		}
+	})
+
+	t.Run("should serve the metrics in the format of Prometheus", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		body := w.Body.String()
+		for _, want := range []string{
+			"safe_writes_total 1\n",
+			"safe_alt_reads_total 1\n",
+			"# TYPE safe_write_duration_seconds histogram\n",
+			"safe_write_duration_seconds_bucket{le=\"+Inf\"} 1\n",
+			"safe_write_duration_seconds_count 1\n",
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("expect %q in\n%s", want, body)
+			}
+		}
+	})
+}
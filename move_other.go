@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package safe
+
+import "os"
+
+// defaultStrategy is the strategy which is used if neither WithStrategy nor WithRenameFallback is set.
+const defaultStrategy = StrategyHardlink
+
+// replaceFile replaces the file with the name dst with the file with the name src.
+func replaceFile(src string, dst string) error {
+	return os.Rename(src, dst)
+}
+
+// sharingViolation reports whether the error means that the file is open in another process.
+// Open files never prevent a rename on this platform.
+func sharingViolation(err error) bool {
+	return false
+}
@@ -0,0 +1,44 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// defaultStrategy is StrategyRename on Windows, since a hard link can not replace a file which is open
+// in another process and some volumes do not support hard links at all.
+const defaultStrategy = StrategyRename
+
+const (
+	movefileReplaceExisting = 0x1
+	movefileWriteThrough    = 0x8
+
+	errorSharingViolation syscall.Errno = 32
+)
+
+var procMoveFileExW = syscall.NewLazyDLL("kernel32.dll").NewProc("MoveFileExW")
+
+// replaceFile replaces the file with the name dst with the file with the name src using
+// MoveFileEx(MOVEFILE_REPLACE_EXISTING|MOVEFILE_WRITE_THROUGH), so the move is flushed to the disk before it returns.
+func replaceFile(src string, dst string) error {
+	from, err := syscall.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	to, err := syscall.UTF16PtrFromString(dst)
+	if err != nil {
+		return err
+	}
+	r, _, err := procMoveFileExW.Call(uintptr(unsafe.Pointer(from)), uintptr(unsafe.Pointer(to)), movefileReplaceExisting|movefileWriteThrough)
+	if r == 0 {
+		return &os.LinkError{Op: "move", Old: src, New: dst, Err: err}
+	}
+	return nil
+}
+
+// sharingViolation reports whether the error means that the file is open in another process.
+func sharingViolation(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+}
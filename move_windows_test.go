@@ -0,0 +1,51 @@
+package safe
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReplaceFile(t *testing.T) {
+	t.Run("should retry the commit while the file is open in another process", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			time.Sleep(2 * SleepTime)
+			f.Close()
+		}()
+
+		if err := WriteFile("testdir/testfile", []byte("new data"), WithRetries(100)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "new data")
+		checkContents(t, "testdir/testfile.1", "new data")
+	})
+
+	t.Run("should return the sharing violation if the file stays open", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		if err := WriteFile("testdir/testfile", []byte("new data"), WithRetries(2)); !sharingViolation(err) {
+			t.Errorf("expect a sharing violation but got %v", err)
+		}
+		checkContents(t, "testdir/testfile", "old data")
+	})
+}
@@ -0,0 +1,143 @@
+package safe
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithAllowReservedNames allows WriteFile to write files whose names end with the alt suffix or look like
+// the name of a temporary file or a tombstone. By default, such names are rejected with ErrReservedName because writing
+// e.g. config.json.1 instead of config.json breaks the protocol for config.json.
+func WithAllowReservedNames() Option {
+	return func(c *config) {
+		c.allowReserved = true
+	}
+}
+
+// checkReserved returns an error if the name is reserved for the bookkeeping of another file.
+func (c *config) checkReserved(name string) error {
+	if c.allowReserved {
+		return nil
+	}
+	base := filepath.Base(name)
+	if _, ok := c.isAlt(base); ok {
+		return &os.PathError{Op: "write", Path: name, Err: ErrReservedName}
+	}
+	if _, _, ok := c.isTemp(base); ok {
+		return &os.PathError{Op: "write", Path: name, Err: ErrReservedName}
+	}
+	if _, _, ok := isTombstone(base); ok {
+		return &os.PathError{Op: "write", Path: name, Err: ErrReservedName}
+	}
+	return nil
+}
+
+// Namer decides the names of the alt files and the temporary files, so deployments with strict filename
+// policies (e.g. no dots or fixed extensions) can adapt them. All names are base names without a directory.
+// The names must be distinguishable from the names of the files themselves. All calls for the same files
+// must use the same Namer.
+type Namer interface {
+	// TempName returns the name of the temporary file for a write of the file with the name at the time t.
+	TempName(name string, t time.Time) string
+	// AltName returns the name of the alt file of the file with the name.
+	AltName(name string) string
+	// IsTemp reports whether the name is the name of a temporary file and returns the name of the file
+	// it belongs to and the time when it was created.
+	IsTemp(name string) (string, time.Time, bool)
+	// IsAlt reports whether the name is the name of an alt file and returns the name of the file it belongs to.
+	IsAlt(name string) (string, bool)
+}
+
+// SuffixNamer is the default Namer. It appends the AltSuffix to get the alt name
+// and a timestamp in the TimestampFormat followed by a unique suffix to get the name of a temporary file.
+type SuffixNamer struct {
+	AltSuffix string
+}
+
+// TempName appends the time in the TimestampFormat and a unique suffix with the process ID and random digits
+// to the name (e.g. config.json.2020-01-02T15-04-05.000000-4242-1a2b3c4d),
+// so concurrent writes of the same file in the same microsecond get different temporary files.
+func (n SuffixNamer) TempName(name string, t time.Time) string {
+	return name + t.Format(TimestampFormat) + uniqueSuffix()
+}
+
+// AltName appends the AltSuffix to the name.
+func (n SuffixNamer) AltName(name string) string {
+	return name + n.AltSuffix
+}
+
+// IsTemp reports whether the name ends with a timestamp in the TimestampFormat, optionally followed
+// by the unique suffix of TempName.
+func (n SuffixNamer) IsTemp(name string) (string, time.Time, bool) {
+	name = trimUniqueSuffix(name)
+	l := len(TimestampFormat)
+	if len(name) <= l {
+		return "", time.Time{}, false
+	}
+	t, err := time.ParseInLocation(TimestampFormat, name[len(name)-l:], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return name[:len(name)-l], t, true
+}
+
+// IsAlt reports whether the name ends with the AltSuffix.
+func (n SuffixNamer) IsAlt(name string) (string, bool) {
+	if !strings.HasSuffix(name, n.AltSuffix) || len(name) == len(n.AltSuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(name, n.AltSuffix), true
+}
+
+// uniqueSuffix returns -$(pid)-$(random hex digits).
+func uniqueSuffix() string {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		binary.BigEndian.PutUint32(b[:], uint32(time.Now().UnixNano()))
+	}
+	return "-" + strconv.Itoa(os.Getpid()) + "-" + hex.EncodeToString(b[:])
+}
+
+// trimUniqueSuffix removes the suffix returned by uniqueSuffix from the name if it has one.
+func trimUniqueSuffix(name string) string {
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 || len(name)-i-1 != 8 {
+		return name
+	}
+	if _, err := hex.DecodeString(name[i+1:]); err != nil {
+		return name
+	}
+	j := strings.LastIndexByte(name[:i], '-')
+	if j < 0 || j+1 == i {
+		return name
+	}
+	if _, err := strconv.ParseUint(name[j+1:i], 10, 64); err != nil {
+		return name
+	}
+	return name[:j]
+}
+
+// WithNamer sets the Namer which decides the names of the alt files and the temporary files.
+// The default is a SuffixNamer with the AltNamePostfix.
+func WithNamer(n Namer) Option {
+	return func(c *config) {
+		c.namer = n
+	}
+}
+
+// isAlt reports whether the base name is the alt name of a file and returns the name of that file.
+func (c *config) isAlt(name string) (string, bool) {
+	return c.namer.IsAlt(name)
+}
+
+// isTemp reports whether the base name is the name of a temporary file and returns the name of the file it belongs to
+// and the time when it was created.
+func (c *config) isTemp(name string) (string, time.Time, bool) {
+	return c.namer.IsTemp(name)
+}
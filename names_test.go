@@ -0,0 +1,110 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prefixNamer uses prefixes instead of suffixes, so the names keep their extensions.
+type prefixNamer struct{}
+
+func (prefixNamer) TempName(name string, t time.Time) string {
+	return "tmp-" + strconv.FormatInt(t.UnixNano(), 10) + "-" + name
+}
+
+func (prefixNamer) AltName(name string) string {
+	return "alt-" + name
+}
+
+func (prefixNamer) IsTemp(name string) (string, time.Time, bool) {
+	parts := strings.SplitN(name, "-", 3)
+	if len(parts) != 3 || parts[0] != "tmp" {
+		return "", time.Time{}, false
+	}
+	ns, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return parts[2], time.Unix(0, ns), true
+}
+
+func (prefixNamer) IsAlt(name string) (string, bool) {
+	if !strings.HasPrefix(name, "alt-") {
+		return "", false
+	}
+	return strings.TrimPrefix(name, "alt-"), true
+}
+
+func TestWithNamer(t *testing.T) {
+	t.Run("should use the names of the Namer", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		m := NewDir("testdir", WithNamer(prefixNamer{}))
+
+		if err := m.WriteFile("config.json", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/config.json", "data")
+		checkContents(t, "testdir/alt-config.json", "data")
+		if infos, _ := ioutil.ReadDir("testdir"); len(infos) != 2 {
+			t.Errorf("expect only the file and its alt file but got %d files", len(infos))
+		}
+
+		h, err := m.DirHealth(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Consistent != 1 {
+			t.Errorf("expect 1 consistent file but got %+v", h)
+		}
+	})
+
+	t.Run("should reserve the names of the Namer", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		err := WriteFile("testdir/alt-config.json", []byte("data"), WithNamer(prefixNamer{}))
+		if !errors.Is(err, ErrReservedName) {
+			t.Errorf("expect ErrReservedName but got %v", err)
+		}
+	})
+}
+
+func TestSuffixNamer(t *testing.T) {
+	n := SuffixNamer{AltSuffix: AltNamePostfix}
+
+	t.Run("should return unique temporary names for the same time", func(t *testing.T) {
+		now := time.Now()
+		a, b := n.TempName("testfile", now), n.TempName("testfile", now)
+		if a == b {
+			t.Errorf("expect different names but got %s twice", a)
+		}
+	})
+
+	t.Run("should recognize its temporary names", func(t *testing.T) {
+		now := time.Now()
+		name, created, ok := n.IsTemp(n.TempName("testfile", now))
+		if !ok || name != "testfile" || !created.Equal(now.Truncate(time.Microsecond)) {
+			t.Errorf("expect testfile created at %v but got %q, %v, %v", now, name, created, ok)
+		}
+	})
+
+	t.Run("should recognize temporary names without the unique suffix", func(t *testing.T) {
+		name, _, ok := n.IsTemp("testfile" + time.Now().Format(TimestampFormat))
+		if !ok || name != "testfile" {
+			t.Errorf("expect testfile but got %q, %v", name, ok)
+		}
+	})
+
+	t.Run("should not recognize other names", func(t *testing.T) {
+		for _, name := range []string{"testfile", "testfile-1-abcdef01", "testfile.1"} {
+			if _, _, ok := n.IsTemp(name); ok {
+				t.Errorf("expect %s not to be a temporary name", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package safe
+
+import (
+	"os"
+	"time"
+)
+
+// EventKind describes a step of a write or read which is reported to the observers.
+type EventKind string
+
+const (
+	// EventTempCreated means that the temporary file was written (and synced).
+	EventTempCreated EventKind = "temp_created"
+	// EventSynced means that the temporary file was synced to the disk.
+	EventSynced EventKind = "synced"
+	// EventAltLinked means that the temporary file was linked to the alt name. It is reported by StrategyHardlink.
+	EventAltLinked EventKind = "alt_linked"
+	// EventLinkSkipped means that a hard link was skipped because its source did not exist or its target
+	// was created concurrently. Err is the ignored error. It is reported by StrategyHardlink,
+	// e.g. on the first write of a file, which has no alt file to complete an interrupted write with.
+	EventLinkSkipped EventKind = "link_skipped"
+	// EventCommitted means that the temporary file was committed to the name, including the sync of the directory.
+	EventCommitted EventKind = "committed"
+	// EventCleanup means that the temporary file was removed after the write.
+	EventCleanup EventKind = "cleanup"
+	// EventAltRead means that ReadFile read the alt file because the name did not exist,
+	// e.g. because a write was interrupted or is in progress.
+	EventAltRead EventKind = "alt_read"
+	// EventRetry means that a read (or a commit on Windows) is retried after Duration.
+	EventRetry EventKind = "retry"
+	// EventError means that a call of WriteFile or ReadFile failed after Duration.
+	EventError EventKind = "error"
+)
+
+// Observation is a step of a write or read which is reported to the observers.
+type Observation struct {
+	Kind EventKind
+	// Name is the resolved name of the file which is written or read, or the name passed to the call for EventError.
+	Name string
+	// Path is the name of the file the step was applied to, e.g. the temporary file or the alt file.
+	Path string
+	// Attempt is the number of the attempt which failed for EventRetry.
+	Attempt int
+	// Duration of the step.
+	Duration time.Duration
+	// Err is the error of the step if it failed.
+	Err error
+}
+
+// Observer is notified about the steps of writes and reads, e.g. to emit metrics or logs.
+// It is called synchronously, so it must return quickly.
+type Observer interface {
+	Observe(o Observation)
+}
+
+// ObserverFunc is a function which implements Observer.
+type ObserverFunc func(o Observation)
+
+// Observe calls the function.
+func (f ObserverFunc) Observe(o Observation) {
+	f(o)
+}
+
+// WithObserver makes WriteFile and ReadFile report each step to the Observer. It can be passed several times
+// to add more observers. Pass it to New, so the observer does not need to be passed to every call.
+// Unlike WithInstrument, which measures whole calls, the observers see the individual steps.
+func WithObserver(o Observer) Option {
+	return func(c *config) {
+		c.observers = append(c.observers, o)
+	}
+}
+
+// observe reports the step which started at the start time to the observers.
+func (c *config) observe(kind EventKind, name string, path string, start time.Time, err error) {
+	if len(c.observers) == 0 {
+		return
+	}
+	c.notify(Observation{Kind: kind, Name: name, Path: path, Duration: time.Since(start), Err: err})
+}
+
+// notify reports the event to the observers.
+func (c *config) notify(o Observation) {
+	for _, observer := range c.observers {
+		observer.Observe(o)
+	}
+}
+
+// removeTemp removes the temporary file of the resolved name after a write and reports it to the observers.
+func (c *config) removeTemp(name string, tmp string) {
+	start := time.Now()
+	err := c.storage().Remove(tmp)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	c.observe(EventCleanup, name, tmp, start, err)
+}
+
+// observedSync returns a function which syncs the temporary file tmp of the resolved name like syncFile
+// and reports it to the observers.
+func (c *config) observedSync(name string, tmp string) func(*os.File) error {
+	if len(c.observers) == 0 || c.noSync {
+		return c.syncFile
+	}
+	return func(f *os.File) error {
+		start := time.Now()
+		err := c.syncFile(f)
+		c.observe(EventSynced, name, tmp, start, err)
+		return err
+	}
+}
@@ -0,0 +1,91 @@
+package safe
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// recorder returns an option which records the kinds of the observations.
+func recorder(kinds *[]EventKind) Option {
+	return WithObserver(ObserverFunc(func(o Observation) {
+		*kinds = append(*kinds, o.Kind)
+	}))
+}
+
+func TestWithObserver(t *testing.T) {
+	t.Run("should report the steps of a write", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		var got []EventKind
+		if err := WriteFile("testfile", []byte("data"), recorder(&got), WithStrategy(StrategyHardlink)); err != nil {
+			t.Fatal(err)
+		}
+		// The alt file does not exist yet, so it can not be linked to the name.
+		want := []EventKind{EventSynced, EventTempCreated, EventLinkSkipped, EventAltLinked, EventCommitted, EventCleanup}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expect %v but got %v", want, got)
+		}
+	})
+
+	t.Run("should report the steps of a write to a Backend", func(t *testing.T) {
+		var got []EventKind
+		if err := WriteFile("testfile", []byte("data"), recorder(&got), WithBackend(NewMemBackend())); err != nil {
+			t.Fatal(err)
+		}
+		want := []EventKind{EventSynced, EventTempCreated, EventLinkSkipped, EventAltLinked, EventCommitted, EventCleanup}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expect %v but got %v", want, got)
+		}
+	})
+
+	t.Run("should not report the sync without fsync", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		var got []EventKind
+		if err := WriteFile("testfile", []byte("data"), recorder(&got), WithFsync(false)); err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range got {
+			if k == EventSynced {
+				t.Errorf("expect no sync but got %v", got)
+			}
+		}
+	})
+
+	t.Run("should report the retries and the error of a read", func(t *testing.T) {
+		var got []Observation
+		observer := WithObserver(ObserverFunc(func(o Observation) {
+			got = append(got, o)
+		}))
+		if _, err := ReadFile("testfile", observer, WithRetries(3)); !os.IsNotExist(err) {
+			t.Fatalf("expect a NotExist error but got %v", err)
+		}
+		if len(got) != 3 {
+			t.Fatalf("expect 3 observations but got %+v", got)
+		}
+		for i, o := range got[:2] {
+			if o.Kind != EventRetry || o.Attempt != i+1 || o.Duration != SleepTime {
+				t.Errorf("unexpected observation %+v", o)
+			}
+		}
+		if got[2].Kind != EventError || got[2].Name != "testfile" || !os.IsNotExist(got[2].Err) {
+			t.Errorf("unexpected observation %+v", got[2])
+		}
+	})
+
+	t.Run("should report a read of the alt file", func(t *testing.T) {
+		defer clean(t, "testfile.1")
+		createFile(t, "testfile.1", "data")
+
+		var got []EventKind
+		if _, err := ReadFile("testfile", recorder(&got)); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0] != EventAltRead {
+			t.Errorf("expect %v but got %v", EventAltRead, got)
+		}
+	})
+}
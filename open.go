@@ -0,0 +1,38 @@
+package safe
+
+import "os"
+
+// OpenPrimary opens the file with the name for reading without falling back to $(name).1
+// It is meant for tools which inspect the physical copies of a managed file.
+func OpenPrimary(name string, opts ...Option) (*os.File, error) {
+	name, err := newConfig(opts).path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(name)
+}
+
+// OpenAlt opens the file $(name).1 for reading.
+// It is meant for tools which inspect the physical copies of a managed file.
+func OpenAlt(name string, opts ...Option) (*os.File, error) {
+	c := newConfig(opts)
+	name, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(c.altName(name))
+}
+
+// Open opens the file with the name or $(name).1 for reading with the same fallback and retries as ReadFile,
+// so large files can be streamed or seeked (e.g. with http.ServeContent) without reading them into memory.
+// The returned *os.File implements io.ReadSeekCloser and fs.File. Because a write never modifies a file in place,
+// the open file keeps the version it was opened with until it is closed.
+// Transforms, decompression, includes and resolvers are not applied.
+func Open(name string, opts ...Option) (*os.File, error) {
+	c := newConfig(opts)
+	name, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.openSource(name)
+}
@@ -0,0 +1,88 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestOpenPrimaryAndAlt(t *testing.T) {
+	t.Run("should open the physical copies of the file", func(t *testing.T) {
+		createFile(t, "testfile", "primary")
+		defer clean(t, "testfile")
+		createFile(t, "testfile.1", "alt")
+		defer clean(t, "testfile.1")
+
+		primary, err := OpenPrimary("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer primary.Close()
+		alt, err := OpenAlt("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer alt.Close()
+
+		if got, _ := ioutil.ReadAll(primary); string(got) != "primary" {
+			t.Errorf("OpenPrimary does not open testfile. Got %q", got)
+		}
+		if got, _ := ioutil.ReadAll(alt); string(got) != "alt" {
+			t.Errorf("OpenAlt does not open testfile.1. Got %q", got)
+		}
+	})
+
+	t.Run("should not fall back to the alt file", func(t *testing.T) {
+		createFile(t, "testfile.1", "alt")
+		defer clean(t, "testfile.1")
+
+		_, err := OpenPrimary("testfile")
+		if !os.IsNotExist(err) {
+			t.Errorf("expect NotExist error but got %v", err)
+		}
+	})
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("should open the file or fall back to the alt file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/a", "primary")
+		createFile(t, "testdir/b.1", "alt")
+
+		for name, want := range map[string]string{"testdir/a": "primary", "testdir/b": "alt"} {
+			f, err := Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ioutil.ReadAll(f)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("expect %q for %s but got %q", want, name, got)
+			}
+		}
+	})
+
+	t.Run("should keep the version while the file is replaced", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		f, err := Open("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := WriteFile("testdir/testfile", []byte("new data")); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, _ := ioutil.ReadAll(f); string(got) != "old data" {
+			t.Errorf("expect the old data but got %q", got)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package safe
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Option configures the behaviour of a single call to one of the methods of this package.
+type Option func(*config)
+
+// config holds the settings which are collected from the options of a call.
+type config struct {
+	ctx             context.Context
+	perm            os.FileMode
+	namer           Namer
+	renameFallback  bool
+	reprobe         time.Duration
+	onStrategy      func(dir string, from Strategy, to Strategy)
+	fixedStrategy   bool
+	commitStrategy  Strategy
+	committer       CommitStrategy
+	autoStrategy    bool
+	tempDir         string
+	noSync          bool
+	dataSync        bool
+	retries         int
+	backoff         BackoffFunc
+	mkdirAll        bool
+	dirPerm         os.FileMode
+	dirSync         DirSync
+	prefix          string
+	strict          bool
+	readOnly        bool
+	allowReserved   bool
+	reproducible    bool
+	shadow          bool
+	fenced          bool
+	fencingToken    uint64
+	assertCommitted bool
+	hash            string
+	includes        bool
+	coalesce        bool
+	index           bool
+	watchdog        *Watchdog
+	locking         bool
+	busy            BusyHandler
+	flocking        bool
+	staleAge        time.Duration
+	writeStats      bool
+	reflink         bool
+	backend         Backend
+
+	transforms []func([]byte) ([]byte, error)
+	validators []func([]byte) error
+	compress   bool
+
+	decompress          bool
+	maxDecompressedSize int64
+
+	syncInterval time.Duration
+	pollInterval time.Duration
+	recoveryLog  string
+
+	resolvers map[string]func(string) (string, error)
+
+	instruments []func(Measurement)
+	sampleRate  float64
+	observers   []Observer
+	retention   RetentionPolicy
+	backup      string
+	tracer      Tracer
+	auditLog    string
+	auditUser   string
+	checksum    bool
+
+	// used is the strategy which committed the file and retried the number of retries of the call.
+	used    Strategy
+	retried int
+}
+
+// newConfig applies the options to a config with the default settings.
+func newConfig(opts []Option) *config {
+	c := &config{
+		ctx:                 context.Background(),
+		perm:                DefaultPerm,
+		namer:               SuffixNamer{AltSuffix: AltNamePostfix},
+		retries:             DefaultRetries,
+		hash:                DefaultHash,
+		maxDecompressedSize: DefaultMaxDecompressedSize,
+		pollInterval:        DefaultPollInterval,
+		sampleRate:          1,
+		dirSync:             DirSyncParent,
+		locking:             true,
+		staleAge:            StaleTempAge,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithStrict makes WriteFile verify the result of every link step.
+// By default, errors which look like a concurrent write (e.g. the link already exists or the alt file vanished)
+// are ignored. In strict mode, WriteFile checks after each step that the link exists,
+// is a regular file and points to the expected inode and returns a *os.LinkError describing the problem otherwise.
+func WithStrict() Option {
+	return func(c *config) {
+		c.strict = true
+	}
+}
+
+// WithReadOnly makes every call which would modify a file fail with a *os.PathError wrapping ErrReadOnly,
+// while reads keep working. Passed to a Manager, it ensures that a component can only observe its files.
+func WithReadOnly() Option {
+	return func(c *config) {
+		c.readOnly = true
+	}
+}
+
+// writable returns an error if the call with the op may not modify the file with the name.
+func (c *config) writable(op string, name string) error {
+	if c.readOnly {
+		return &os.PathError{Op: op, Path: name, Err: ErrReadOnly}
+	}
+	return nil
+}
+
+// WithAssertCommitted makes WriteFile check the result of the write before it returns.
+// Both the name and the alt name have to exist, point to the inode of the temporary file which was written
+// and have the size of the data. Otherwise, a *os.PathError wrapping ErrCommitIncomplete is returned.
+func WithAssertCommitted() Option {
+	return func(c *config) {
+		c.assertCommitted = true
+	}
+}
+
+// WithPerm sets the permissions of the files created by WriteFile. The default is DefaultPerm.
+func WithPerm(perm os.FileMode) Option {
+	return func(c *config) {
+		c.perm = perm
+	}
+}
+
+// WithAltSuffix sets the suffix of the alt names. The default is AltNamePostfix.
+// It is a shorthand for WithNamer with a SuffixNamer.
+// All calls for the same files must use the same suffix. An empty suffix is ignored.
+func WithAltSuffix(suffix string) Option {
+	return func(c *config) {
+		if suffix != "" {
+			c.namer = SuffixNamer{AltSuffix: suffix}
+		}
+	}
+}
+
+// WithTempDir makes WriteFile and Create write the temporary files to the directory instead of
+// the directory of the file. The temporary files are hard linked to the file,
+// so the directory must be on the same filesystem.
+func WithTempDir(dir string) Option {
+	return func(c *config) {
+		c.tempDir = dir
+	}
+}
+
+// WithFsync controls whether WriteFile and Create sync the temporary file before it is committed. The default is true.
+// Disabling it makes writes faster, but after a power loss the file may be empty or incomplete.
+// See WithSyncPolicy to control the syncs of the directories as well.
+func WithFsync(enabled bool) Option {
+	return func(c *config) {
+		c.noSync = !enabled
+		c.dataSync = false
+	}
+}
@@ -0,0 +1,105 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestWithAltSuffix(t *testing.T) {
+	t.Run("should use the suffix for the alt name", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.bak")
+
+		if err := WriteFile("testfile", []byte("data"), WithAltSuffix(".bak")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile.bak", "data")
+		checkNotExist(t, "testfile.1")
+
+		clean(t, "testfile")
+		data, err := ReadFile("testfile", WithAltSuffix(".bak"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "data" {
+			t.Errorf("expect data but got %s", data)
+		}
+	})
+}
+
+func TestWithTempDir(t *testing.T) {
+	t.Run("should write the temporary file to the directory", func(t *testing.T) {
+		defer clean(t, "testdir")
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		createDir(t, "testdir")
+
+		f, err := Create("testfile", WithTempDir("testdir"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 1 {
+			t.Errorf("expect the temporary file in testdir but got %d files", len(infos))
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "data")
+		if infos, _ := ioutil.ReadDir("testdir"); len(infos) != 0 {
+			t.Errorf("expect the temporary file to be removed but got %d files", len(infos))
+		}
+	})
+}
+
+func TestWithFsync(t *testing.T) {
+	t.Run("should write the file without fsync", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		if err := WriteFile("testfile", []byte("data"), WithFsync(false)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "data")
+	})
+}
+
+func TestWithReadOnly(t *testing.T) {
+	t.Run("should reject modifications but allow reads", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		m := NewDir("testdir", WithReadOnly())
+
+		if err := m.WriteFile("testfile", []byte("new data")); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("expect ErrReadOnly from WriteFile but got %v", err)
+		}
+		if _, err := m.Create("testfile"); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("expect ErrReadOnly from Create but got %v", err)
+		}
+		if err := m.RemoveFile("testfile"); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("expect ErrReadOnly from RemoveFile but got %v", err)
+		}
+		if _, err := m.RecoverDir("."); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("expect ErrReadOnly from RecoverDir but got %v", err)
+		}
+		checkContents(t, "testdir/testfile", "data")
+
+		data, err := m.ReadFile("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "data" {
+			t.Errorf("expect data but got %s", data)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package safe
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Pending is a write which was prepared by Prepare. The data is written and synced to a temporary file,
+// and only the commit step is left, so it can be coordinated with other resources
+// (e.g. a row in a database). The file stays locked until Commit or Rollback is called.
+type Pending struct {
+	name   string
+	tmp    string
+	c      *config
+	data   []byte
+	t      time.Time
+	unlock func()
+
+	mu   sync.Mutex
+	done bool
+}
+
+// Prepare writes and syncs the data to a temporary file for the file with the name and returns the Pending write.
+// The file is not changed until Commit is called.
+//
+//	p, err := safe.Prepare("state.json", data)
+//	if err != nil {
+//		return err
+//	}
+//	if err := db.Commit(); err != nil {
+//		p.Rollback()
+//		return err
+//	}
+//	return p.Commit()
+func Prepare(name string, data []byte, opts ...Option) (*Pending, error) {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err = c.prepare(name, data)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	t := time.Now()
+	tmp := c.tempName(name, t)
+	if err := c.writeTemp(name, tmp, data); err != nil {
+		os.Remove(tmp)
+		unlock()
+		return nil, err
+	}
+	return &Pending{name: name, tmp: tmp, c: c, data: data, t: t, unlock: unlock}, nil
+}
+
+// Name returns the resolved name of the file.
+func (p *Pending) Name() string {
+	return p.name
+}
+
+// Commit replaces the contents of the file with the prepared data. It only performs the link or rename step.
+// After Commit or Rollback, Commit returns ErrTxDone.
+func (p *Pending) Commit() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return &os.PathError{Op: "commit", Path: p.name, Err: ErrTxDone}
+	}
+	p.done = true
+	defer p.unlock()
+	defer os.Remove(p.tmp)
+	return p.c.commit(p.tmp, p.name, int64(len(p.data)), p.t, p.data)
+}
+
+// Rollback removes the temporary file. The file is not changed.
+// Calling Rollback after Commit has no effect, so it can be deferred.
+func (p *Pending) Rollback() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return nil
+	}
+	p.done = true
+	defer p.unlock()
+	return remove(p.tmp)
+}
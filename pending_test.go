@@ -0,0 +1,58 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestPrepare(t *testing.T) {
+	t.Run("should only replace the file on commit", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile", "old data")
+
+		p, err := Prepare("testdir/testfile", []byte("new data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "old data")
+		if err := p.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "new data")
+		checkContents(t, "testdir/testfile.1", "new data")
+
+		if err := p.Commit(); !errors.Is(err, ErrTxDone) {
+			t.Errorf("expect ErrTxDone but got %v", err)
+		}
+		if err := p.Rollback(); err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+	})
+
+	t.Run("should remove the temporary file on rollback", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		p, err := Prepare("testdir/testfile", []byte("new data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 0 {
+			t.Errorf("expect no files but got %d", len(infos))
+		}
+
+		// The lock was released.
+		if err := WriteFile("testdir/testfile", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package safe
+
+import (
+	"context"
+	"sync"
+)
+
+// pin is the contents of a pinned file and the number of Pin calls which keep it.
+type pin struct {
+	data []byte
+	refs int
+}
+
+// pins contains the pinned files by name.
+var pins = struct {
+	sync.RWMutex
+	m map[string]*pin
+}{m: make(map[string]*pin)}
+
+// Pin reads the files with the names and keeps their contents in memory until the context is done.
+// The pinned contents are refreshed with Watch whenever a file changes, so Pinned can return them
+// without any disk I/O. If a file can not be read initially, nothing is pinned and the error is returned.
+// If a pinned file can not be read later, the last contents are kept. A file can be pinned by several calls
+// at once; it stays pinned until the contexts of all of them are done.
+func Pin(ctx context.Context, names ...string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	watches := make([]<-chan Event, len(names))
+	for i, name := range names {
+		watches[i] = Watch(ctx, name)
+	}
+
+	initial := make([][]byte, len(names))
+	for i, events := range watches {
+		e, ok := <-events
+		if !ok {
+			cancel()
+			return ctx.Err()
+		}
+		if e.Err != nil {
+			cancel()
+			return e.Err
+		}
+		initial[i] = e.Data
+	}
+
+	pins.Lock()
+	for i, name := range names {
+		p, ok := pins.m[name]
+		if !ok {
+			p = &pin{}
+			pins.m[name] = p
+		}
+		p.data = initial[i]
+		p.refs++
+	}
+	pins.Unlock()
+
+	var wg sync.WaitGroup
+	for i, events := range watches {
+		wg.Add(1)
+		go func(name string, events <-chan Event) {
+			defer wg.Done()
+			refreshPin(name, events)
+		}(names[i], events)
+	}
+	go func() {
+		defer cancel()
+		<-ctx.Done()
+		// The watches close their channels when the context is done.
+		wg.Wait()
+		pins.Lock()
+		for _, name := range names {
+			if p := pins.m[name]; p != nil {
+				if p.refs--; p.refs == 0 {
+					delete(pins.m, name)
+				}
+			}
+		}
+		pins.Unlock()
+	}()
+	return nil
+}
+
+// refreshPin updates the pinned contents of the file with the events of its watch.
+func refreshPin(name string, events <-chan Event) {
+	for e := range events {
+		if e.Err != nil {
+			continue
+		}
+		pins.Lock()
+		if p := pins.m[name]; p != nil {
+			p.data = e.Data
+		}
+		pins.Unlock()
+	}
+}
+
+// Pinned returns the contents of a file which was pinned with Pin or nil if the file is not pinned.
+// The returned slice is shared and must not be modified.
+func Pinned(name string) []byte {
+	pins.RLock()
+	defer pins.RUnlock()
+	if p := pins.m[name]; p != nil {
+		return p.data
+	}
+	return nil
+}
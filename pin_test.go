@@ -0,0 +1,71 @@
+package safe
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPin(t *testing.T) {
+	t.Run("should keep the contents of the files in memory until the context is done", func(t *testing.T) {
+		createFile(t, "testfile", "some important data")
+		defer clean(t, "testfile")
+		ctx, cancel := context.WithCancel(context.Background())
+
+		if err := Pin(ctx, "testfile"); err != nil {
+			t.Fatal(err)
+		}
+		if got := Pinned("testfile"); string(got) != "some important data" {
+			t.Errorf("Pinned does not return the file contents. Want %q but got %q", "some important data", got)
+		}
+
+		cancel()
+		deadline := time.Now().Add(time.Second)
+		for Pinned("testfile") != nil && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if got := Pinned("testfile"); got != nil {
+			t.Errorf("expect the file to be unpinned but got %q", got)
+		}
+	})
+
+	t.Run("should return the error if a file can not be read", func(t *testing.T) {
+		err := Pin(context.Background(), "testfile")
+		if !os.IsNotExist(err) {
+			t.Errorf("expect NotExist error but got %v", err)
+		}
+		if got := Pinned("testfile"); got != nil {
+			t.Errorf("expect the file not to be pinned but got %q", got)
+		}
+	})
+
+	t.Run("should keep the file pinned until all pins are done", func(t *testing.T) {
+		createFile(t, "testfile", "some important data")
+		defer clean(t, "testfile")
+		first, cancelFirst := context.WithCancel(context.Background())
+		second, cancelSecond := context.WithCancel(context.Background())
+		defer cancelSecond()
+
+		if err := Pin(first, "testfile"); err != nil {
+			t.Fatal(err)
+		}
+		if err := Pin(second, "testfile"); err != nil {
+			t.Fatal(err)
+		}
+		cancelFirst()
+		time.Sleep(50 * time.Millisecond)
+		if got := Pinned("testfile"); string(got) != "some important data" {
+			t.Errorf("expect the file to stay pinned but got %q", got)
+		}
+
+		cancelSecond()
+		deadline := time.Now().Add(time.Second)
+		for Pinned("testfile") != nil && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if got := Pinned("testfile"); got != nil {
+			t.Errorf("expect the file to be unpinned but got %q", got)
+		}
+	})
+}
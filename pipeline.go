@@ -0,0 +1,78 @@
+package safe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+)
+
+// WithTransform makes WriteFile pass the data through the transform function before it is written.
+// Transforms are applied in the order of the options, before the data is validated.
+func WithTransform(transform func(data []byte) ([]byte, error)) Option {
+	return func(c *config) {
+		c.transforms = append(c.transforms, transform)
+	}
+}
+
+// WithValidator makes WriteFile check the data with the validate function before it is written.
+// If the validate function returns an error, nothing is written and a *os.PathError wrapping the error is returned.
+func WithValidator(validate func(data []byte) error) Option {
+	return func(c *config) {
+		c.validators = append(c.validators, validate)
+	}
+}
+
+// WithCompression makes WriteFile gzip the data after it was transformed and validated.
+// Use WithDecompression to read the file.
+func WithCompression() Option {
+	return func(c *config) {
+		c.compress = true
+	}
+}
+
+// ValidJSON is a validator for WithValidator which accepts valid JSON.
+func ValidJSON(data []byte) error {
+	var v interface{}
+	return json.Unmarshal(data, &v)
+}
+
+// IndentJSON returns a transform for WithTransform which pretty-prints JSON using the indent.
+func IndentJSON(indent string) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", indent); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	}
+}
+
+// prepare applies the transforms, validators and compression to the data which is written to the file with the name.
+func (c *config) prepare(name string, data []byte) ([]byte, error) {
+	for _, transform := range c.transforms {
+		var err error
+		if data, err = transform(data); err != nil {
+			return nil, &os.PathError{Op: "transform", Path: name, Err: err}
+		}
+	}
+	for _, validate := range c.validators {
+		if err := validate(data); err != nil {
+			return nil, &os.PathError{Op: "validate", Path: name, Err: err}
+		}
+	}
+	if !c.compress {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, &os.PathError{Op: "compress", Path: name, Err: err}
+	}
+	if err := w.Close(); err != nil {
+		return nil, &os.PathError{Op: "compress", Path: name, Err: err}
+	}
+	return buf.Bytes(), nil
+}
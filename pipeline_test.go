@@ -0,0 +1,64 @@
+package safe
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestWriteFilePipeline(t *testing.T) {
+	t.Run("should transform the data before it is written", func(t *testing.T) {
+		err := WriteFile("testfile", []byte(`{"a":1}`), WithTransform(IndentJSON("  ")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		checkContents(t, "testfile", "{\n  \"a\": 1\n}\n")
+	})
+
+	t.Run("should not write the file if the validator rejects the data", func(t *testing.T) {
+		err := WriteFile("testfile", []byte(`{"a":`), WithValidator(ValidJSON))
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("expect the error of the validator but got %v", err)
+		}
+		checkNotExist(t, "testfile")
+	})
+
+	t.Run("should compress the data so it can be read with decompression", func(t *testing.T) {
+		err := WriteFile("testfile", []byte("some important data"), WithCompression())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		got, err := ReadFile("testfile", WithDecompression())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "some important data" {
+			t.Errorf("ReadFile does not return the correct file contents. Want %q but got %q", "some important data", got)
+		}
+	})
+
+	t.Run("should create the file with the permissions", func(t *testing.T) {
+		err := WriteFile("testfile", []byte("secret"), WithPerm(0600))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		info, err := os.Stat("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("expect the permissions 0600 but got %v", info.Mode().Perm())
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Capabilities describes which features the filesystem of a directory supports.
+type Capabilities struct {
+	// HardLinks reports whether hard links can be created, which is required by StrategyHardlink.
+	HardLinks bool `json:"hard_links"`
+	// AtomicRename reports whether a file can be renamed over an existing file, which is required by
+	// StrategyRename and StrategyReplace.
+	AtomicRename bool `json:"atomic_rename"`
+	// Tmpfile reports whether unnamed temporary files can be created with O_TMPFILE.
+	Tmpfile bool `json:"tmpfile"`
+	// Exchange reports whether two files can be swapped atomically, which is required by StrategyExchange.
+	Exchange bool `json:"exchange"`
+	// DirSync reports whether directories can be synced, which makes new names durable.
+	DirSync bool `json:"dir_sync"`
+}
+
+// Strategy returns the best commit strategy for the capabilities.
+// If safe writes are not possible, it returns a *CapabilityError.
+func (caps Capabilities) Strategy(dir string) (Strategy, error) {
+	if caps.HardLinks && defaultStrategy == StrategyHardlink {
+		return StrategyHardlink, nil
+	}
+	if caps.AtomicRename {
+		return StrategyRename, nil
+	}
+	return StrategyHardlink, &CapabilityError{Dir: dir, Capabilities: caps}
+}
+
+// CapabilityError is returned if the filesystem of a directory supports neither hard links nor atomic renames,
+// so files can not be replaced safely.
+type CapabilityError struct {
+	Dir          string
+	Capabilities Capabilities
+}
+
+// Is reports whether the target is ErrUnsupportedFS.
+func (e *CapabilityError) Is(target error) bool {
+	return target == ErrUnsupportedFS
+}
+
+// Error describes the missing capabilities.
+func (e *CapabilityError) Error() string {
+	return "safe: " + e.Dir + " supports neither hard links nor replacing files with a rename"
+}
+
+// ProbeDir detects the capabilities of the filesystem of the directory by creating and removing probe files.
+func ProbeDir(dir string, opts ...Option) (Capabilities, error) {
+	c := newConfig(opts)
+	if err := c.writable("probe", dir); err != nil {
+		return Capabilities{}, err
+	}
+	dir, err := c.path(dir)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return c.probeDir(dir)
+}
+
+// probeDir detects the capabilities of the filesystem of the resolved directory.
+func (c *config) probeDir(dir string) (Capabilities, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	if !info.IsDir() {
+		return Capabilities{}, &os.PathError{Op: "probe", Path: dir, Err: ErrNotDir}
+	}
+
+	base := filepath.Join(dir, ".probe")
+	caps := Capabilities{
+		HardLinks: c.probeLink(base),
+	}
+
+	a := c.tempName(base+"-a", time.Now())
+	b := c.tempName(base+"-b", time.Now())
+	defer os.Remove(a)
+	defer os.Remove(b)
+	if err := write(a, []byte("a"), c.perm, nil); err != nil {
+		return Capabilities{}, err
+	}
+	if err := write(b, []byte("b"), c.perm, nil); err != nil {
+		return Capabilities{}, err
+	}
+	caps.Exchange = renameExchange(a, b) == nil
+	if caps.Exchange {
+		a, b = b, a
+	}
+	if err := replaceFile(a, b); err == nil {
+		data, err := ioutil.ReadFile(b)
+		caps.AtomicRename = err == nil && string(data) == "a"
+	}
+
+	if f, err := openAnonymous(dir, c.perm); err == nil {
+		caps.Tmpfile = true
+		f.Close()
+	}
+	caps.DirSync = runtime.GOOS != "windows" && syncDir(dir) == nil
+	return caps, nil
+}
+
+// WithAutoStrategy makes WriteFile and Create probe the directory of a file with ProbeDir before the first write
+// and commit the files with the best strategy the filesystem supports.
+// The result is cached for the lifetime of the process. If the filesystem supports neither hard links
+// nor atomic renames, the write fails with a *CapabilityError. WithStrategy takes precedence.
+func WithAutoStrategy() Option {
+	return func(c *config) {
+		c.autoStrategy = true
+	}
+}
+
+// probed contains the strategies of the probed directories by absolute name.
+var probed = struct {
+	sync.Mutex
+	m map[string]Strategy
+}{m: make(map[string]Strategy)}
+
+// probedStrategy returns the best strategy for the directory of the resolved name and probes it on first use.
+func (c *config) probedStrategy(name string) (Strategy, error) {
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return StrategyHardlink, err
+	}
+	probed.Lock()
+	s, ok := probed.m[dir]
+	probed.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	caps, err := c.probeDir(dir)
+	if err != nil {
+		return StrategyHardlink, err
+	}
+	s, err = caps.Strategy(dir)
+	if err != nil {
+		return s, err
+	}
+	probed.Lock()
+	probed.m[dir] = s
+	probed.Unlock()
+	return s, nil
+}
@@ -0,0 +1,101 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestProbeDir(t *testing.T) {
+	t.Run("should detect the capabilities and leave no files behind", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		caps, err := ProbeDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !caps.HardLinks || !caps.AtomicRename {
+			t.Errorf("expect hard links and atomic renames but got %+v", caps)
+		}
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 0 {
+			t.Errorf("expect no files but got %d", len(infos))
+		}
+	})
+
+	t.Run("should detect missing hard links", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		restore := withoutLinks()
+		defer restore()
+		caps, err := ProbeDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if caps.HardLinks {
+			t.Error("expect no hard links")
+		}
+	})
+
+	t.Run("should fail if the name is not a directory", func(t *testing.T) {
+		defer clean(t, "testfile")
+		createFile(t, "testfile", "some data")
+
+		if _, err := ProbeDir("testfile"); !errors.Is(err, ErrNotDir) {
+			t.Errorf("expect ErrNotDir but got %v", err)
+		}
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Run("should pick the best strategy", func(t *testing.T) {
+		if s, err := (Capabilities{HardLinks: true, AtomicRename: true}).Strategy("testdir"); err != nil || s != defaultStrategy {
+			t.Errorf("expect %v but got %v, %v", defaultStrategy, s, err)
+		}
+		if s, err := (Capabilities{AtomicRename: true}).Strategy("testdir"); err != nil || s != StrategyRename {
+			t.Errorf("expect StrategyRename but got %v, %v", s, err)
+		}
+	})
+
+	t.Run("should return a CapabilityError if safe writes are not possible", func(t *testing.T) {
+		_, err := Capabilities{}.Strategy("testdir")
+		var capErr *CapabilityError
+		if !errors.As(err, &capErr) || capErr.Dir != "testdir" {
+			t.Errorf("expect a CapabilityError but got %v", err)
+		}
+	})
+}
+
+func TestWithAutoStrategy(t *testing.T) {
+	t.Run("should commit with renames if hard links are not supported", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		abs, _ := filepath.Abs("testdir")
+		defer func() {
+			probed.Lock()
+			delete(probed.m, abs)
+			probed.Unlock()
+		}()
+
+		restore := withoutLinks()
+		defer restore()
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithAutoStrategy()); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkContents(t, "testdir/testfile.1", "some data")
+
+		probed.Lock()
+		s := probed.m[abs]
+		probed.Unlock()
+		if s != StrategyRename {
+			t.Errorf("expect the cached StrategyRename but got %v", s)
+		}
+	})
+}
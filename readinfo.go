@@ -0,0 +1,69 @@
+package safe
+
+import (
+	"io/ioutil"
+	"time"
+)
+
+// ReadInfo describes the file which was read by ReadFileInfo.
+type ReadInfo struct {
+	// Name is the name of the file which was read.
+	Name string `json:"name"`
+	// Alt reports whether the alt file was read because the name did not exist,
+	// e.g. because a write was interrupted. Monitoring can alert on it.
+	Alt     bool      `json:"alt"`
+	ModTime time.Time `json:"mod_time"`
+	// Size is the size of the file on disk, before it was decompressed.
+	Size int64 `json:"size"`
+	// Inode is the inode number of the file. It is 0 on platforms without inodes.
+	Inode uint64 `json:"inode"`
+}
+
+// ReadFileInfo reads the file with the name like ReadFile and reports which file was read.
+// Decompression and resolvers are applied, but includes are not, because they merge several files.
+func ReadFileInfo(name string, opts ...Option) ([]byte, ReadInfo, error) {
+	c := newConfig(opts)
+	name, err := c.path(name)
+	if err != nil {
+		return nil, ReadInfo{}, err
+	}
+	unlock, err := c.flock(name, false)
+	if err != nil {
+		return nil, ReadInfo{}, err
+	}
+	defer unlock()
+
+	alt := c.altName(name)
+	f, err := c.open(name, alt)
+	if err != nil {
+		return nil, ReadInfo{}, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, ReadInfo{}, err
+	}
+	ino, _ := inode(stat)
+	info := ReadInfo{
+		Name:    f.Name(),
+		Alt:     f.Name() == alt,
+		ModTime: stat.ModTime(),
+		Size:    stat.Size(),
+		Inode:   ino,
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, info, err
+	}
+	if c.decompress {
+		if data, err = decompress(name, data, c.maxDecompressedSize); err != nil {
+			return nil, info, err
+		}
+	}
+	if len(c.resolvers) > 0 {
+		if data, err = c.resolveRefs(name, data); err != nil {
+			return nil, info, err
+		}
+	}
+	return data, info, nil
+}
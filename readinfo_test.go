@@ -0,0 +1,47 @@
+package safe
+
+import (
+	"os"
+	"testing"
+)
+
+func TestReadFileInfo(t *testing.T) {
+	t.Run("should report that the name was read", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+
+		data, info, err := ReadFileInfo("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "some data" {
+			t.Errorf("expect %q but got %q", "some data", data)
+		}
+		if info.Name != "testdir/testfile" || info.Alt || info.Size != 9 {
+			t.Errorf("expect the info of the name but got %+v", info)
+		}
+	})
+
+	t.Run("should report that the alt file was read", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile.1", "some data")
+
+		_, info, err := ReadFileInfo("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name != "testdir/testfile.1" || !info.Alt {
+			t.Errorf("expect the info of the alt file but got %+v", info)
+		}
+	})
+
+	t.Run("should fail if neither file exists", func(t *testing.T) {
+		if _, _, err := ReadFileInfo("testfile", WithRetries(1)); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+}
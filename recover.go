@@ -0,0 +1,190 @@
+package safe
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RecoveryAction describes what RecoverDir did to a file.
+type RecoveryAction string
+
+const (
+	// ActionRelink means that the name was linked to its alt file because a write was interrupted.
+	ActionRelink RecoveryAction = "relink"
+	// ActionRelinkAlt means that the alt file was replaced by a link or a copy of the name (see Repair).
+	ActionRelinkAlt RecoveryAction = "relink_alt"
+	// ActionRemoveTemp means that a temporary file older than StaleTempAge was removed.
+	ActionRemoveTemp RecoveryAction = "remove_temp"
+	// ActionRollForward means that a file of an interrupted transaction (see Tx) was committed.
+	ActionRollForward RecoveryAction = "roll_forward"
+	// ActionRollBack means that the staged file of a transaction which was interrupted before it was decided was removed.
+	ActionRollBack RecoveryAction = "roll_back"
+	// ActionReadJournal means that the journal of a transaction could not be read, so the transaction was
+	// neither rolled forward nor rolled back. It is only reported together with an error.
+	ActionReadJournal RecoveryAction = "read_journal"
+)
+
+// RecoveryReport is the machine-readable result of RecoverDir.
+type RecoveryReport struct {
+	Dir      string         `json:"dir"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Files    []FileRecovery `json:"files"`
+}
+
+// FileRecovery describes an action which was taken for a single file.
+type FileRecovery struct {
+	Name   string         `json:"name"`
+	Action RecoveryAction `json:"action"`
+	// Error is the message of the error if the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Failed reports whether any action of the report failed.
+func (r *RecoveryReport) Failed() bool {
+	for _, f := range r.Files {
+		if f.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRecoveryLog makes RecoverDir and Repair append their report as JSON to the Log with the name
+// (see AppendRecord), so the recoveries of many hosts can be collected. The log is separate from the audit
+// journal of WithAudit, which records every action of the report as an AuditEntry.
+func WithRecoveryLog(name string) Option {
+	return func(c *config) {
+		c.recoveryLog = name
+	}
+}
+
+// RecoverDir completes the writes and the transactions in the directory which were interrupted, e.g. by a crash,
+// and removes the temporary files which are older than StaleTempAge.
+// It is meant to be called when an application starts. Every action is listed in the returned report.
+// If a single action fails, RecoverDir continues and records the error in the report.
+// Like with Repair, an alt file which is not expected to match the name with the strategy (e.g. the previous
+// version of StrategyExchange and StrategySymlink or the copy of StrategyRename) only restores a missing name.
+func RecoverDir(dir string, opts ...Option) (*RecoveryReport, error) {
+	c := newConfig(opts)
+	if err := c.writable("recover", dir); err != nil {
+		return nil, err
+	}
+	resolved, err := c.path(dir)
+	if err != nil {
+		return nil, err
+	}
+	report := &RecoveryReport{Dir: resolved, Started: time.Now()}
+
+	infos, err := ioutil.ReadDir(resolved)
+	if err != nil {
+		return nil, err
+	}
+	// The staged files of a transaction look like stale temporary files, so transactions are completed first.
+	journals := 0
+	for _, info := range infos {
+		if !c.isJournal(info.Name()) {
+			continue
+		}
+		journals++
+		if action, err := c.recoverTx(filepath.Join(resolved, info.Name()), report); err != nil {
+			report.add(filepath.Join(resolved, info.Name()), action, err)
+		}
+	}
+	if journals > 0 {
+		if infos, err = ioutil.ReadDir(resolved); err != nil {
+			return nil, err
+		}
+	}
+	alts, err := c.readAltDir(resolved, infos)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		name := filepath.Join(resolved, info.Name())
+		if _, created, ok := c.isTemp(info.Name()); ok && report.Started.Sub(created) > c.staleAge {
+			if info.IsDir() {
+				// The staging directory of WriteDir.
+				report.add(name, ActionRemoveTemp, os.RemoveAll(name))
+				continue
+			}
+			report.add(name, ActionRemoveTemp, remove(name))
+		}
+	}
+	for _, info := range alts {
+		if _, _, ok := c.isTemp(info.Name()); ok {
+			continue
+		}
+		primary, ok := c.isAlt(info.Name())
+		if !ok {
+			continue
+		}
+		name := filepath.Join(c.altDir(resolved), info.Name())
+		primary = filepath.Join(resolved, primary)
+		if !interrupted(name, primary) {
+			continue
+		}
+		info, err := os.Lstat(primary)
+		if err == nil {
+			s, err := c.strategy(primary)
+			if err != nil {
+				report.add(primary, ActionRelink, err)
+				continue
+			}
+			// Only StrategyHardlink links the alt file to the name. With the other strategies, the alt file
+			// is a copy or the previous version, so it must not replace the name.
+			if s != StrategyHardlink || info.Mode()&os.ModeSymlink != 0 {
+				continue
+			}
+			err = link(name, primary)
+		} else {
+			err = c.restoreFrom(name, primary)
+		}
+		if err == nil {
+			recordRecovery(primary)
+		}
+		report.add(primary, ActionRelink, err)
+	}
+	report.Finished = time.Now()
+	return report, c.logRecovery(report, opts)
+}
+
+// Recover works like RecoverDir, but it removes all temporary files regardless of their age,
+// so the staged files of transactions which were not decided are rolled back as well.
+// It must only be called when no other process writes to the directory, e.g. when a daemon starts.
+func Recover(dir string, opts ...Option) (*RecoveryReport, error) {
+	all := make([]Option, 0, len(opts)+1)
+	all = append(all, opts...)
+	all = append(all, func(c *config) {
+		c.staleAge = 0
+	})
+	return RecoverDir(dir, all...)
+}
+
+// logRecovery records the actions of the report in the audit journal of WithAudit
+// and appends the report to the log of WithRecoveryLog.
+func (c *config) logRecovery(report *RecoveryReport, opts []Option) error {
+	if err := c.auditRecovery(report); err != nil {
+		return err
+	}
+	if c.recoveryLog == "" {
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return AppendRecord(c.recoveryLog, data, opts...)
+}
+
+// add records an action in the report.
+func (r *RecoveryReport) add(name string, action RecoveryAction, err error) {
+	f := FileRecovery{Name: name, Action: action}
+	if err != nil {
+		f.Error = err.Error()
+	}
+	r.Files = append(r.Files, f)
+}
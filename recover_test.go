@@ -0,0 +1,138 @@
+package safe
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRecoverDir(t *testing.T) {
+	t.Run("should relink interrupted writes and remove stale temps", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createFile(t, "testdir/degraded.1", "new data")
+		createFile(t, "testdir/pending", "old data")
+		createFile(t, "testdir/pending.1", "new data")
+		if err := WriteFile("testdir/consistent", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		stale := "testdir/stale" + time.Now().Add(-time.Hour).Format(TimestampFormat)
+		createFile(t, stale, "partial")
+
+		report, err := RecoverDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Files) != 3 || report.Failed() {
+			t.Errorf("expect 3 successful actions but got %+v", report.Files)
+		}
+		checkContents(t, "testdir/degraded", "new data")
+		checkContents(t, "testdir/pending", "new data")
+		checkNotExist(t, stale)
+	})
+
+	t.Run("should not roll back a file written with StrategyExchange", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		opt := WithStrategy(StrategyExchange)
+		for _, data := range []string{"old", "new"} {
+			if err := WriteFile("testdir/testfile", []byte(data), opt); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		report, err := RecoverDir("testdir", opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Files) != 0 {
+			t.Errorf("expect no actions but got %+v", report.Files)
+		}
+		checkContents(t, "testdir/testfile", "new")
+	})
+
+	t.Run("should not roll back a file written with StrategySymlink", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		for _, data := range []string{"old", "new"} {
+			if err := WriteFile("testdir/testfile", []byte(data), WithStrategy(StrategySymlink)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// The name is recognized as a symbolic link even without the strategy.
+		report, err := RecoverDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Files) != 0 {
+			t.Errorf("expect no actions but got %+v", report.Files)
+		}
+		checkContents(t, "testdir/testfile", "new")
+	})
+
+	t.Run("should leave the copies of StrategyRename alone without hard links", func(t *testing.T) {
+		defer withoutLinks()()
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		opt := WithStrategy(StrategyRename)
+		if err := WriteFile("testdir/testfile", []byte("data"), opt); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/degraded.1", "data")
+
+		report, err := RecoverDir("testdir", opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Files) != 1 || report.Failed() {
+			t.Errorf("expect only the missing name to be restored but got %+v", report.Files)
+		}
+		checkContents(t, "testdir/testfile", "data")
+		checkContents(t, "testdir/degraded", "data")
+	})
+
+	t.Run("should append the report to the recovery log", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		defer clean(t, "testlog")
+		createFile(t, "testdir/degraded.1", "data")
+
+		if _, err := RecoverDir("testdir", WithRecoveryLog("testlog")); err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := ReadRecords("testlog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 {
+			t.Fatalf("expect 1 report in the log but got %d", len(records))
+		}
+		var report RecoveryReport
+		if err := json.Unmarshal(records[0], &report); err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Files) != 1 || report.Files[0].Action != ActionRelink {
+			t.Errorf("expect the relink in the logged report but got %+v", report)
+		}
+	})
+
+	t.Run("should record the actions in the audit journal", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		defer clean(t, "audit.log")
+		createFile(t, "testdir/degraded.1", "data")
+
+		if _, err := RecoverDir("testdir", WithAudit("audit.log")); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := ReadAudit("testdir/degraded", WithAudit("audit.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Recovery != ActionRelink || entries[0].Error != "" {
+			t.Errorf("expect the relink in the audit journal but got %+v", entries)
+		}
+	})
+}
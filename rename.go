@@ -0,0 +1,293 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MoveFile renames the file with the oldname to the newname together with its $(oldname).1.
+// The file is committed to the newname like WriteFile, so the newname and its alt name point to
+// the contents and an existing file with the newname is replaced. Then the oldname and its alt name are removed,
+// so ReadFile can't bring back the old file from an orphaned $(oldname).1. The fencing token of WithFencingToken
+// and the checksum sidecar (see WithChecksum) are moved with the file, and the backup of WithBackup of the oldname
+// is removed. If the commit fails,
+// the oldname is not changed. If neither the oldname nor its alt name exists, a NotExist error is returned.
+func MoveFile(oldname string, newname string, opts ...Option) error {
+	c := newConfig(opts)
+	po, err := c.path(oldname)
+	if err != nil {
+		return err
+	}
+	pn, err := c.path(newname)
+	if err != nil {
+		return err
+	}
+	if po == pn {
+		return nil
+	}
+	// Lock the files in a fixed order, so two moves between the same files can't deadlock.
+	first, second := oldname, newname
+	if pn < po {
+		first, second = second, first
+	}
+	_, unlockFirst, err := c.begin(first)
+	if err != nil {
+		return err
+	}
+	defer unlockFirst()
+	_, unlockSecond, err := c.begin(second)
+	if err != nil {
+		return err
+	}
+	defer unlockSecond()
+
+	src := po
+	if _, err := os.Lstat(src); os.IsNotExist(err) {
+		src = c.altName(po)
+	}
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	t := time.Now()
+	tmp := c.tempName(pn, t)
+	defer os.Remove(tmp)
+	if err := osLink(src, tmp); err != nil {
+		if !c.linkUnsupported(pn, err) {
+			return err
+		}
+		if err := copyFile(src, tmp, !c.noSync); err != nil {
+			return err
+		}
+	}
+	if err := c.commit(tmp, pn, int64(len(data)), t, data); err != nil {
+		return err
+	}
+	// The fencing token stays with the file, so delayed writes with lower tokens are still rejected.
+	if err := moveSidecars(po, pn, FencePostfix, FencePostfix+AltNamePostfix); err != nil {
+		return err
+	}
+	if err := c.moveChecksums(po, pn, t); err != nil {
+		return err
+	}
+	if c.backup != "" {
+		if err := remove(po + c.backup); err != nil {
+			return err
+		}
+	}
+
+	// The alt name is removed first, so the oldname never falls back to it.
+	if err := remove(c.altName(po)); err != nil {
+		return err
+	}
+	if err := remove(po); err != nil {
+		return err
+	}
+	if c.dirSync >= DirSyncParent {
+		if err := c.syncDirs(po, c.altName(po)); err != nil {
+			return err
+		}
+	}
+	if c.index {
+		if err := c.removeFromIndex(po); err != nil {
+			return err
+		}
+	}
+	return c.auditRemove(po)
+}
+
+// RenamePrefix renames all managed files in the directory whose names start with the oldPrefix
+// so they start with the newPrefix instead (e.g. app-*.json to service-*.json).
+// Each file is renamed together with its $(name).1 and the entries of the manifest (see WithIndex) are moved.
+// If a file can not be renamed, the files which were already renamed are renamed back.
+// If a new name already exists, nothing is renamed and a *os.LinkError wrapping os.ErrExist is returned.
+// The old and the new names are locked like by WriteFile while they are renamed, so concurrent writes wait.
+func RenamePrefix(dir string, oldPrefix string, newPrefix string, opts ...Option) error {
+	c := newConfig(opts)
+	if err := c.writable("rename", dir); err != nil {
+		return err
+	}
+	dir, err := c.path(dir)
+	if err != nil {
+		return err
+	}
+
+	var renames [][2]string
+	for {
+		if renames, err = c.prefixRenames(dir, oldPrefix, newPrefix); err != nil {
+			return err
+		}
+		paths := c.renamedPaths(dir, renames)
+		unlock, err := c.lockPaths(paths)
+		if err != nil {
+			return err
+		}
+		// A file with the prefix can be created before the names are locked, so the renames are collected again.
+		locked, err := c.prefixRenames(dir, oldPrefix, newPrefix)
+		if err != nil {
+			unlock()
+			return err
+		}
+		if reflect.DeepEqual(c.renamedPaths(dir, locked), paths) {
+			renames = locked
+			defer unlock()
+			break
+		}
+		unlock()
+	}
+
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	moved := make(map[string]string)
+	for _, r := range renames {
+		if filepath.Dir(r[0]) != dir {
+			continue
+		}
+		base := filepath.Base(r[0])
+		if _, ok := c.isAlt(base); !ok {
+			moved[base] = filepath.Base(r[1])
+		}
+	}
+
+	for i, r := range renames {
+		if err := os.Rename(r[0], r[1]); err != nil {
+			rollbackRenames(renames[:i])
+			return err
+		}
+	}
+
+	return c.updateIndexLocked(dir, func(index *Index) bool {
+		changed := false
+		for old, target := range moved {
+			if entry, ok := index.Files[old]; ok {
+				delete(index.Files, old)
+				index.Files[target] = entry
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// moveSidecars renames the sidecars of the resolved oldname with the postfixes to the sidecars of the newname.
+// Sidecars which do not exist are skipped.
+func moveSidecars(oldname string, newname string, postfixes ...string) error {
+	for _, postfix := range postfixes {
+		if err := os.Rename(oldname+postfix, newname+postfix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// prefixRenames returns the renames of the files in the resolved directory and their alt files
+// whose names start with the oldPrefix.
+func (c *config) prefixRenames(dir string, oldPrefix string, newPrefix string) ([][2]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	renames, err := c.collectRenames(dir, infos, oldPrefix, newPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if c.shadow {
+		alts, err := c.readAltDir(dir, infos)
+		if err != nil {
+			return nil, err
+		}
+		altRenames, err := c.collectRenames(c.altDir(dir), alts, oldPrefix, newPrefix)
+		if err != nil {
+			return nil, err
+		}
+		renames = append(renames, altRenames...)
+	}
+	return renames, nil
+}
+
+// renamedPaths returns the sorted names of the files in the resolved directory which are affected by the renames.
+// An alt file is represented by the name of its file.
+func (c *config) renamedPaths(dir string, renames [][2]string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, r := range renames {
+		for _, name := range r {
+			base := filepath.Base(name)
+			if primary, ok := c.isAlt(base); ok {
+				base = primary
+			}
+			if path := filepath.Join(dir, base); !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// lockPaths locks the files with the resolved names in their order like begin.
+// The returned function releases the locks.
+func (c *config) lockPaths(paths []string) (func(), error) {
+	var unlocks []func()
+	unlock := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, path := range paths {
+		unlockPath, err := c.lock(path)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlockPath)
+		funlock, err := c.flock(path, true)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		unlocks = append(unlocks, funlock)
+	}
+	return unlock, nil
+}
+
+// rollbackRenames reverts the renames in the reverse order.
+func rollbackRenames(renames [][2]string) {
+	for i := len(renames) - 1; i >= 0; i-- {
+		os.Rename(renames[i][1], renames[i][0])
+	}
+}
+
+// collectRenames returns the renames of the files in the directory whose names start with the oldPrefix.
+// Temporary files and the manifest are skipped.
+func (c *config) collectRenames(dir string, infos []os.FileInfo, oldPrefix string, newPrefix string) ([][2]string, error) {
+	existing := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		existing[info.Name()] = true
+	}
+
+	var renames [][2]string
+	for _, info := range infos {
+		base := info.Name()
+		if info.IsDir() || !strings.HasPrefix(base, oldPrefix) || strings.HasPrefix(base, IndexName) {
+			continue
+		}
+		if _, _, ok := c.isTemp(base); ok {
+			continue
+		}
+		target := newPrefix + strings.TrimPrefix(base, oldPrefix)
+		if existing[target] {
+			return nil, &os.LinkError{Op: "rename", Old: filepath.Join(dir, base), New: filepath.Join(dir, target), Err: os.ErrExist}
+		}
+		renames = append(renames, [2]string{filepath.Join(dir, base), filepath.Join(dir, target)})
+	}
+	return renames, nil
+}
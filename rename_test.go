@@ -0,0 +1,169 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRenamePrefix(t *testing.T) {
+	t.Run("should rename the files with the prefix together with their alt files and manifest entries", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		for _, name := range []string{"testdir/app-a.json", "testdir/app-b.json"} {
+			if err := WriteFile(name, []byte(name), WithIndex()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		createFile(t, "testdir/other.json", "other")
+
+		if err := RenamePrefix("testdir", "app-", "service-"); err != nil {
+			t.Fatal(err)
+		}
+
+		checkContents(t, "testdir/service-a.json", "testdir/app-a.json")
+		checkContents(t, "testdir/service-a.json.1", "testdir/app-a.json")
+		checkContents(t, "testdir/service-b.json", "testdir/app-b.json")
+		checkContents(t, "testdir/other.json", "other")
+		checkNotExist(t, "testdir/app-a.json")
+		checkNotExist(t, "testdir/app-a.json.1")
+
+		index, err := ReadIndex("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := index.Files["service-a.json"]; !ok {
+			t.Errorf("expect the manifest entry to be moved but got %v", index.Files)
+		}
+		if _, ok := index.Files["app-a.json"]; ok {
+			t.Errorf("expect the old manifest entry to be removed but got %v", index.Files)
+		}
+	})
+
+	t.Run("should not rename anything if a new name already exists", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		if err := WriteFile("testdir/app-a.json", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/service-a.json", "existing")
+
+		err := RenamePrefix("testdir", "app-", "service-")
+		if !errors.Is(err, os.ErrExist) {
+			t.Errorf("expect ErrExist but got %v", err)
+		}
+		checkContents(t, "testdir/app-a.json", "a")
+		checkContents(t, "testdir/service-a.json", "existing")
+	})
+
+	t.Run("should wait for the writes of the old and the new names", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		if err := WriteFile("testdir/app-a.json", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		c := newConfig(nil)
+		unlock, err := c.lock("testdir/service-a.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		giveUp := WithBusyHandler(func(attempt int, elapsed time.Duration) bool {
+			return false
+		})
+
+		if err := RenamePrefix("testdir", "app-", "service-", giveUp); !errors.Is(err, ErrBusy) {
+			t.Errorf("expect ErrBusy but got %v", err)
+		}
+		checkContents(t, "testdir/app-a.json", "data")
+		unlock()
+
+		if err := RenamePrefix("testdir", "app-", "service-", giveUp); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/service-a.json", "data")
+	})
+}
+
+func TestMoveFile(t *testing.T) {
+	t.Run("should move the file together with its alt file", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		if err := WriteFile("testdir/old.json", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := MoveFile("testdir/old.json", "testdir/new.json"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/new.json", "some data")
+		checkContents(t, "testdir/new.json.1", "some data")
+		checkNotExist(t, "testdir/old.json")
+		checkNotExist(t, "testdir/old.json.1")
+		if _, err := ReadFile("testdir/old.json"); !os.IsNotExist(err) {
+			t.Errorf("expect the old file to be gone but got %v", err)
+		}
+	})
+
+	t.Run("should replace an existing file", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		if err := WriteFile("testdir/old.json", []byte("new data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/new.json", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := MoveFile("testdir/old.json", "testdir/new.json"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/new.json", "new data")
+		checkContents(t, "testdir/new.json.1", "new data")
+	})
+
+	t.Run("should move the checksum and remove the backup", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		for _, data := range []string{"old data", "some data"} {
+			if err := WriteFile("testdir/old.json", []byte(data), WithChecksum(), WithBackup(".bak")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := WriteFile("testdir/new.json", []byte("replaced data"), WithChecksum()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := MoveFile("testdir/old.json", "testdir/new.json", WithBackup(".bak")); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/old.json.sha256")
+		checkNotExist(t, "testdir/old.json.bak")
+		checkContents(t, "testdir/new.json.sha256", checksum([]byte("some data"))+"  new.json\n")
+		if _, err := ReadFile("testdir/new.json", WithChecksum()); err != nil {
+			t.Errorf("expect the moved file to match its checksum but got %v", err)
+		}
+	})
+
+	t.Run("should move the alt file of an interrupted write", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createFile(t, "testdir/old.json.1", "some data")
+
+		if err := MoveFile("testdir/old.json", "testdir/new.json"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/new.json", "some data")
+		checkNotExist(t, "testdir/old.json.1")
+	})
+
+	t.Run("should fail if the file does not exist", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := MoveFile("testdir/old.json", "testdir/new.json"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+		checkNotExist(t, "testdir/new.json")
+	})
+}
@@ -0,0 +1,120 @@
+/*
+Package renameio mirrors the API of github.com/google/renameio, backed by the hard link protocol of the safe package.
+Projects can migrate by changing the import path:
+
+	import "github.com/robojones/safe-write/renameio"
+
+	err := renameio.WriteFile("config.json", data, 0644)
+
+Unlike the original, the previous version of a file stays available as $(name).1 while it is replaced,
+so it has to be read with safe.ReadFile to profit from the stronger guarantees.
+The permissions are set exactly as given; the umask is not applied.
+*/
+package renameio
+
+import (
+	"errors"
+	"os"
+
+	safe "github.com/robojones/safe-write"
+)
+
+// Option configures a write like the options of github.com/google/renameio.
+type Option func(*config)
+
+// config holds the settings which are collected from the options of a call.
+type config struct {
+	perm    os.FileMode
+	tempDir string
+}
+
+// WithPermissions sets the permissions of the file.
+func WithPermissions(perm os.FileMode) Option {
+	return func(c *config) {
+		c.perm = perm
+	}
+}
+
+// WithTempDir sets the directory of the temporary file. It must be on the same filesystem as the file.
+func WithTempDir(dir string) Option {
+	return func(c *config) {
+		c.tempDir = dir
+	}
+}
+
+// WithStaticPermissions sets the permissions of the file like WithPermissions.
+func WithStaticPermissions(perm os.FileMode) Option {
+	return WithPermissions(perm)
+}
+
+// IgnoreUmask exists for compatibility. The umask is never applied by this package.
+func IgnoreUmask() Option {
+	return func(c *config) {}
+}
+
+// WithExistingPermissions keeps the permissions of the file if it already exists.
+func WithExistingPermissions() Option {
+	return func(c *config) {
+		c.perm = 0
+	}
+}
+
+// safeOptions returns the options of the safe package for a write of the file with the name.
+func safeOptions(path string, perm os.FileMode, opts []Option) []safe.Option {
+	c := &config{perm: perm}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.perm == 0 {
+		c.perm = perm
+		if info, err := os.Stat(path); err == nil {
+			c.perm = info.Mode().Perm()
+		}
+	}
+	safeOpts := []safe.Option{safe.WithPerm(c.perm)}
+	if c.tempDir != "" {
+		safeOpts = append(safeOpts, safe.WithTempDir(c.tempDir))
+	}
+	return safeOpts
+}
+
+// WriteFile writes the data to the file with the name like safe.WriteFile.
+func WriteFile(filename string, data []byte, perm os.FileMode, opts ...Option) error {
+	return safe.WriteFile(filename, data, safeOptions(filename, perm, opts)...)
+}
+
+// PendingFile is a file which is committed when CloseAtomicallyReplace is called.
+type PendingFile struct {
+	*safe.File
+}
+
+// NewPendingFile creates a PendingFile for the file with the path. The default permissions are 0600.
+func NewPendingFile(path string, opts ...Option) (*PendingFile, error) {
+	f, err := safe.Create(path, safeOptions(path, 0600, opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return &PendingFile{f}, nil
+}
+
+// TempFile creates a PendingFile for the file with the path. The dir is used for the temporary file
+// if it is not empty.
+func TempFile(dir string, path string) (*PendingFile, error) {
+	if dir == "" {
+		return NewPendingFile(path)
+	}
+	return NewPendingFile(path, WithTempDir(dir))
+}
+
+// CloseAtomicallyReplace commits the written data to the file.
+func (p *PendingFile) CloseAtomicallyReplace() error {
+	return p.File.Close()
+}
+
+// Cleanup discards the PendingFile unless it was already committed. It is meant to be deferred.
+func (p *PendingFile) Cleanup() error {
+	if err := p.File.Abort(); !errors.Is(err, safe.ErrClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package renameio
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	if err := os.Mkdir("testdir", 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("testdir")
+
+	t.Run("should write the file with the permissions", func(t *testing.T) {
+		if err := WriteFile("testdir/testfile", []byte("some data"), 0640); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0640 {
+			t.Errorf("expect the permissions 0640 but got %v", info.Mode().Perm())
+		}
+		data, err := ioutil.ReadFile("testdir/testfile.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "some data" {
+			t.Errorf("expect the alt file to contain some data but got %q", data)
+		}
+	})
+
+	t.Run("should keep the existing permissions", func(t *testing.T) {
+		if err := WriteFile("testdir/testfile", []byte("new data"), 0600, WithExistingPermissions()); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0640 {
+			t.Errorf("expect the permissions 0640 but got %v", info.Mode().Perm())
+		}
+	})
+}
+
+func TestPendingFile(t *testing.T) {
+	if err := os.Mkdir("testdir", 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("testdir")
+
+	t.Run("should commit the file on CloseAtomicallyReplace", func(t *testing.T) {
+		f, err := TempFile("", "testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Cleanup()
+		if _, err := f.Write([]byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.CloseAtomicallyReplace(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "some data" {
+			t.Errorf("expect some data but got %q", data)
+		}
+	})
+
+	t.Run("should discard the file on Cleanup", func(t *testing.T) {
+		f, err := NewPendingFile("testdir/other")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Cleanup(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat("testdir/other"); !os.IsNotExist(err) {
+			t.Errorf("expect the file not to exist but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package safe
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Repair reconciles the file with the name and its alt file if they diverged, e.g. after a crash between
+// the links of a write or after the name was edited by another program.
+// If one of them is missing, it is restored from the other. If they are different files with different contents,
+// the newer one by modification time wins and replaces the other. Different files with the same contents
+// are linked again. Nothing is changed if they are consistent.
+// With StrategyReplace, StrategyExchange and StrategySymlink, the alt file is not expected to match the name,
+// so only a missing name is restored. If neither file exists, a NotExist error is returned.
+// The action which was taken is listed in the returned report like with RecoverDir, also if it failed.
+// The report is appended to the log of WithRecoveryLog as well.
+func Repair(name string, opts ...Option) (*RecoveryReport, error) {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	report := &RecoveryReport{Dir: filepath.Dir(name), Started: time.Now()}
+	action, err := c.repair(name)
+	if action != "" {
+		report.add(name, action, err)
+	}
+	report.Finished = time.Now()
+	if err != nil {
+		return report, err
+	}
+	return report, c.logRecovery(report, opts)
+}
+
+// repair reconciles the resolved name and its alt file for Repair and returns the action which was taken,
+// or an empty action if nothing was changed.
+func (c *config) repair(name string) (RecoveryAction, error) {
+	alt := c.altName(name)
+
+	info, nameErr := os.Lstat(name)
+	if nameErr != nil && !os.IsNotExist(nameErr) {
+		return "", nameErr
+	}
+	altInfo, altErr := os.Lstat(alt)
+	if altErr != nil && !os.IsNotExist(altErr) {
+		return "", altErr
+	}
+	switch {
+	case nameErr != nil && altErr != nil:
+		return "", &os.PathError{Op: "repair", Path: name, Err: os.ErrNotExist}
+	case nameErr != nil:
+		return ActionRelink, c.repaired(name, c.restoreFrom(alt, name))
+	}
+
+	s, err := c.strategy(name)
+	if err != nil {
+		return "", err
+	}
+	if s == StrategyReplace || s == StrategyExchange || s == StrategySymlink {
+		return "", nil
+	}
+	if altErr != nil {
+		return ActionRelinkAlt, c.repaired(name, c.syncAlt(name, s))
+	}
+	if os.SameFile(info, altInfo) {
+		return "", nil
+	}
+	same, err := sameContents(name, alt)
+	if err != nil {
+		return "", err
+	}
+	if same && s == StrategyRename {
+		// The alt file is a copy with this strategy.
+		return "", nil
+	}
+	if same || !altInfo.ModTime().After(info.ModTime()) {
+		return ActionRelinkAlt, c.repaired(name, c.syncAlt(name, s))
+	}
+	return ActionRelink, c.repaired(name, c.restoreFrom(alt, name))
+}
+
+// repaired syncs the directories of the resolved name after a successful repair.
+func (c *config) repaired(name string, err error) error {
+	if err != nil || c.dirSync < DirSyncParent {
+		return err
+	}
+	return c.syncDirs(name, c.altName(name))
+}
+
+// syncAlt replaces the alt file of the resolved name with a link or a copy of it, depending on the strategy.
+func (c *config) syncAlt(name string, s Strategy) error {
+	if s == StrategyRename {
+		return c.copyAlt(name)
+	}
+	return c.linkAlt(name)
+}
+
+// restoreFrom atomically replaces the resolved name with a hard link to the resolved src, or a copy
+// if the filesystem does not support hard links.
+func (c *config) restoreFrom(src string, name string) error {
+	tmp := c.tempName(name, time.Now())
+	if err := osLink(src, tmp); err != nil {
+		if c.linkUnsupported(name, err) {
+			err = copyFile(src, tmp, !c.noSync)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := replaceFile(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// sameContents reports whether the files with the names a and b have the same contents.
+func sameContents(a string, b string) (bool, error) {
+	dataA, err := ioutil.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	dataB, err := ioutil.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(dataA, dataB), nil
+}
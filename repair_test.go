@@ -0,0 +1,135 @@
+package safe
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+// checkLinked validates that the file and its alt file are the same file.
+func checkLinked(t *testing.T, name string) {
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt, err := os.Stat(name + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(info, alt) {
+		t.Errorf("expect %s and its alt file to be the same file", name)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	t.Run("should keep the newer alt file of an interrupted write", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile", "old data")
+		createFile(t, "testdir/testfile.1", "new data")
+		past := time.Now().Add(-time.Hour)
+		if err := os.Chtimes("testdir/testfile", past, past); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Repair("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "new data")
+		checkLinked(t, "testdir/testfile")
+	})
+
+	t.Run("should keep the newer file after an external edit", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile.1", "old data")
+		createFile(t, "testdir/testfile", "edited data")
+		past := time.Now().Add(-time.Hour)
+		if err := os.Chtimes("testdir/testfile.1", past, past); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Repair("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile.1", "edited data")
+		checkLinked(t, "testdir/testfile")
+	})
+
+	t.Run("should restore a missing file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile.1", "some data")
+
+		if _, err := Repair("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkLinked(t, "testdir/testfile")
+	})
+
+	t.Run("should create a missing alt file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile", "some data")
+
+		report, err := Repair("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkLinked(t, "testdir/testfile")
+		if len(report.Files) != 1 || report.Files[0].Action != ActionRelinkAlt || report.Failed() {
+			t.Errorf("expect the alt file to be relinked but got %+v", report.Files)
+		}
+	})
+
+	t.Run("should report nothing if the files are consistent", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := Repair("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Files) != 0 {
+			t.Errorf("expect no actions but got %+v", report.Files)
+		}
+	})
+
+	t.Run("should append the report to the recovery log", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile.1", "some data")
+
+		if _, err := Repair("testdir/testfile", WithRecoveryLog("testdir/testlog")); err != nil {
+			t.Fatal(err)
+		}
+		records, err := ReadRecords("testdir/testlog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report RecoveryReport
+		if len(records) != 1 {
+			t.Fatalf("expect 1 report in the log but got %d", len(records))
+		}
+		if err := json.Unmarshal(records[0], &report); err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Files) != 1 || report.Files[0].Action != ActionRelink {
+			t.Errorf("expect the restored name in the logged report but got %+v", report)
+		}
+	})
+
+	t.Run("should fail if neither file exists", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if _, err := Repair("testdir/testfile"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package safe
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// WithReproducible makes the files written by WriteFile and Create independent of the time of the write,
+// so directory trees produced with this package can be reproduced byte for byte.
+// The modification time of the files and the write times in the manifest (see WithIndex) are set to
+// the time in the SOURCE_DATE_EPOCH environment variable or to the Unix epoch if it is not set.
+// Compressed files do not contain a timestamp in any case, and the timestamped temporary files
+// never outlive a write.
+func WithReproducible() Option {
+	return func(c *config) {
+		c.reproducible = true
+	}
+}
+
+// reproducibleTime returns the time which is used instead of the current time by WithReproducible.
+func reproducibleTime() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return time.Unix(0, 0).UTC()
+}
@@ -0,0 +1,52 @@
+package safe
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithReproducible(t *testing.T) {
+	t.Run("should set the modification time and the manifest time to SOURCE_DATE_EPOCH", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		os.Setenv("SOURCE_DATE_EPOCH", "1600000000")
+		defer os.Unsetenv("SOURCE_DATE_EPOCH")
+
+		if err := WriteFile("testdir/testfile", []byte("data"), WithReproducible(), WithIndex()); err != nil {
+			t.Fatal(err)
+		}
+
+		want := time.Unix(1600000000, 0)
+		info, err := os.Stat("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(want) {
+			t.Errorf("expect the modification time %v but got %v", want, info.ModTime())
+		}
+		index, err := ReadIndex("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := index.Files["testfile"].Written; !got.Equal(want) {
+			t.Errorf("expect the manifest time %v but got %v", want, got)
+		}
+	})
+
+	t.Run("should use the Unix epoch if SOURCE_DATE_EPOCH is not set", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("data"), WithReproducible()); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		info, err := os.Stat("testfile.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.ModTime().Unix() != 0 {
+			t.Errorf("expect the modification time to be the Unix epoch but got %v", info.ModTime())
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package safe
+
+import (
+	"bytes"
+	"os"
+)
+
+// ReferencePrefix starts a reference which is resolved by ReadFile with WithResolver.
+const ReferencePrefix = '!'
+
+// WithResolver makes ReadFile replace every reference !scheme:ref in the contents of the file
+// with the value returned by the resolve function for the ref (e.g. !vault:secret/db#password).
+// This keeps secrets out of the files on disk while they are still written with WriteFile.
+// A ref ends at the first whitespace, quote, comma or closing bracket. References with a scheme
+// which has no resolver are kept as they are. The value is inserted as it is, so the resolve function
+// has to escape it if the format of the file requires it.
+// If the resolve function fails, ReadFile returns a *os.PathError wrapping the error.
+func WithResolver(scheme string, resolve func(ref string) (string, error)) Option {
+	return func(c *config) {
+		if c.resolvers == nil {
+			c.resolvers = make(map[string]func(string) (string, error))
+		}
+		c.resolvers[scheme] = resolve
+	}
+}
+
+// resolveRefs replaces the references in the data of the file with the name.
+func (c *config) resolveRefs(name string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+
+	for {
+		i := bytes.IndexByte(data, ReferencePrefix)
+		if i < 0 {
+			buf.Write(data)
+			return buf.Bytes(), nil
+		}
+		buf.Write(data[:i])
+		data = data[i+1:]
+
+		scheme := 0
+		for scheme < len(data) && isSchemeByte(data[scheme]) {
+			scheme++
+		}
+		resolve, ok := c.resolvers[string(data[:scheme])]
+		if !ok || scheme == len(data) || data[scheme] != ':' {
+			buf.WriteByte(ReferencePrefix)
+			continue
+		}
+		end := scheme + 1
+		for end < len(data) && !isRefEnd(data[end]) {
+			end++
+		}
+		value, err := resolve(string(data[scheme+1 : end]))
+		if err != nil {
+			return nil, &os.PathError{Op: "resolve", Path: name, Err: err}
+		}
+		buf.WriteString(value)
+		data = data[end:]
+	}
+}
+
+// isSchemeByte reports whether the byte may be part of the scheme of a reference.
+func isSchemeByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-' || b == '_'
+}
+
+// isRefEnd reports whether the byte ends the ref of a reference.
+func isRefEnd(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\n', '"', '\'', ',', ')', ']', '}':
+		return true
+	}
+	return false
+}
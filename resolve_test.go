@@ -0,0 +1,38 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithResolver(t *testing.T) {
+	vault := func(ref string) (string, error) {
+		if ref == "secret/db#password" {
+			return "hunter2", nil
+		}
+		return "", errors.New("unknown secret")
+	}
+
+	t.Run("should replace the references with the resolved values", func(t *testing.T) {
+		defer clean(t, "testfile")
+		createFile(t, "testfile", `{"password":"!vault:secret/db#password","greeting":"hi!","other":"!env:HOME"}`)
+
+		data, err := ReadFile("testfile", WithResolver("vault", vault))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"password":"hunter2","greeting":"hi!","other":"!env:HOME"}`
+		if string(data) != want {
+			t.Errorf("expect %s but got %s", want, data)
+		}
+	})
+
+	t.Run("should return the error of the resolver", func(t *testing.T) {
+		defer clean(t, "testfile")
+		createFile(t, "testfile", "password: !vault:missing\n")
+
+		if _, err := ReadFile("testfile", WithResolver("vault", vault)); err == nil {
+			t.Error("expect an error")
+		}
+	})
+}
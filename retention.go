@@ -0,0 +1,78 @@
+package safe
+
+import "time"
+
+// RetentionPolicy decides which versions of a file are kept by WriteFileVersioned.
+// The oldest versions are removed as long as any of the limits is exceeded.
+// The newest version, which has the current contents, is always kept. The zero value keeps all versions.
+type RetentionPolicy struct {
+	// KeepLast is the maximum number of versions. 0 means no limit.
+	KeepLast int
+	// MaxAge is the maximum age of a version. 0 means no limit.
+	MaxAge time.Duration
+	// MaxBytes is the maximum total size of the versions. 0 means no limit.
+	MaxBytes int64
+}
+
+// WithRetention makes WriteFileVersioned and RestoreVersion apply the RetentionPolicy after each write.
+func WithRetention(p RetentionPolicy) Option {
+	return func(c *config) {
+		c.retention = p
+	}
+}
+
+// KeepLast makes WriteFileVersioned keep only the last n versions of the file.
+// It sets the KeepLast limit of the RetentionPolicy.
+func KeepLast(n int) Option {
+	return func(c *config) {
+		c.retention.KeepLast = n
+	}
+}
+
+// ApplyRetention removes the versions of the file with the name which exceed the limits of the RetentionPolicy,
+// e.g. to apply a new policy to the versions which were kept before.
+func ApplyRetention(name string, p RetentionPolicy, opts ...Option) error {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return c.applyRetention(name, p)
+}
+
+// applyRetention removes the oldest versions of the resolved name while they exceed the limits of the policy.
+func (c *config) applyRetention(name string, p RetentionPolicy) error {
+	if p == (RetentionPolicy{}) {
+		return nil
+	}
+	versions, err := listVersions(name)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, v := range versions {
+		total += v.Size
+	}
+	now := time.Now()
+	for len(versions) > 1 && p.exceeded(versions, total, now) {
+		if err := remove(name + VersionPostfix + string(versions[0].ID)); err != nil {
+			return err
+		}
+		total -= versions[0].Size
+		versions = versions[1:]
+	}
+	return nil
+}
+
+// exceeded reports whether the versions, which are sorted from the oldest to the newest and have the total size,
+// exceed a limit of the policy at the time now.
+func (p RetentionPolicy) exceeded(versions []VersionInfo, total int64, now time.Time) bool {
+	if p.KeepLast > 0 && len(versions) > p.KeepLast {
+		return true
+	}
+	if p.MaxAge > 0 && now.Sub(versions[0].Time) > p.MaxAge {
+		return true
+	}
+	return p.MaxBytes > 0 && total > p.MaxBytes
+}
@@ -0,0 +1,103 @@
+package safe
+
+import (
+	"testing"
+	"time"
+)
+
+// writeVersions writes a version of the file with the name for each of the contents.
+func writeVersions(t *testing.T, name string, contents ...string) {
+	for _, c := range contents {
+		if err := WriteFileVersioned(name, []byte(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// countKept returns the number of versions of the file with the name which are listed by ListVersions.
+func countKept(t *testing.T, name string) int {
+	versions, err := ListVersions(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(versions)
+}
+
+func TestApplyRetention(t *testing.T) {
+	t.Run("should keep the last versions", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "1", "2", "3", "4")
+
+		if err := ApplyRetention("testdir/testfile", RetentionPolicy{KeepLast: 2}); err != nil {
+			t.Fatal(err)
+		}
+		if n := countKept(t, "testdir/testfile"); n != 2 {
+			t.Errorf("expect 2 versions but got %d", n)
+		}
+	})
+
+	t.Run("should remove the versions which are too old but keep the newest one", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "1", "2")
+		old := versionName("testdir/testfile", time.Now().Add(-time.Hour))
+		createFile(t, old, "0")
+
+		if err := ApplyRetention("testdir/testfile", RetentionPolicy{MaxAge: time.Minute}); err != nil {
+			t.Fatal(err)
+		}
+		if n := countKept(t, "testdir/testfile"); n != 2 {
+			t.Errorf("expect 2 versions but got %d", n)
+		}
+		checkNotExist(t, old)
+
+		if err := ApplyRetention("testdir/testfile", RetentionPolicy{MaxAge: time.Nanosecond}); err != nil {
+			t.Fatal(err)
+		}
+		if n := countKept(t, "testdir/testfile"); n != 1 {
+			t.Errorf("expect the newest version but got %d versions", n)
+		}
+	})
+
+	t.Run("should cap the total size of the versions", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "aaaa", "bbbb", "cccc")
+
+		if err := ApplyRetention("testdir/testfile", RetentionPolicy{MaxBytes: 9}); err != nil {
+			t.Fatal(err)
+		}
+		if n := countKept(t, "testdir/testfile"); n != 2 {
+			t.Errorf("expect 2 versions but got %d", n)
+		}
+	})
+
+	t.Run("should apply the policy after each versioned write", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, c := range []string{"1", "2", "3"} {
+			err := WriteFileVersioned("testdir/testfile", []byte(c), WithRetention(RetentionPolicy{MaxBytes: 2}))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := countKept(t, "testdir/testfile"); n != 2 {
+			t.Errorf("expect 2 versions but got %d", n)
+		}
+	})
+
+	t.Run("should keep all versions with the zero policy", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		writeVersions(t, "testdir/testfile", "1", "2", "3")
+
+		if err := ApplyRetention("testdir/testfile", RetentionPolicy{}); err != nil {
+			t.Fatal(err)
+		}
+		if n := countKept(t, "testdir/testfile"); n != 3 {
+			t.Errorf("expect 3 versions but got %d", n)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package safe
+
+import (
+	"os"
+	"time"
+)
+
+// DefaultRetries is the number of times ReadFile tries to read the files if they don't exist.
+const DefaultRetries = 3
+
+// BackoffFunc returns how long ReadFile waits after the failed attempt with the number (starting at 1).
+type BackoffFunc func(attempt int) time.Duration
+
+// ConstantBackoff waits the same interval after every attempt.
+func ConstantBackoff(interval time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		return interval
+	}
+}
+
+// ExponentialBackoff doubles the interval after every attempt, starting with the base interval,
+// until it reaches the max interval.
+func ExponentialBackoff(base time.Duration, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// WithRetries sets how often ReadFile tries to read the files if neither the file nor its alt file exist,
+// e.g. because they are replaced concurrently. The default is DefaultRetries.
+// Slow network filesystems may need more retries, while a single attempt returns NotExist errors right away.
+func WithRetries(n int) Option {
+	return func(c *config) {
+		c.retries = n
+	}
+}
+
+// WithRetryInterval sets how long ReadFile waits between the attempts. The default is SleepTime.
+func WithRetryInterval(interval time.Duration) Option {
+	return WithBackoff(ConstantBackoff(interval))
+}
+
+// WithBackoff sets the BackoffFunc which decides how long ReadFile waits between the attempts.
+func WithBackoff(backoff BackoffFunc) Option {
+	return func(c *config) {
+		c.backoff = backoff
+	}
+}
+
+// wait waits after the failed attempt to read the file with the name.
+// It returns an error if the context of the call is done in the meantime.
+func (c *config) wait(name string, attempt int) error {
+	d := SleepTime
+	if c.backoff != nil {
+		d = c.backoff(attempt)
+	}
+	c.retried++
+	c.notify(Observation{Kind: EventRetry, Name: name, Path: name, Attempt: attempt, Duration: d})
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-c.ctx.Done():
+		return &os.PathError{Op: "read", Path: name, Err: c.ctx.Err()}
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package safe
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithRetries(t *testing.T) {
+	t.Run("should not wait if retries are disabled", func(t *testing.T) {
+		start := time.Now()
+		if _, err := ReadFile("testfile", WithRetries(1)); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+		if d := time.Since(start); d >= DefaultRetries*SleepTime {
+			t.Errorf("expect a single attempt but took %v", d)
+		}
+	})
+
+	t.Run("should wait according to the backoff", func(t *testing.T) {
+		var attempts []int
+		backoff := func(attempt int) time.Duration {
+			attempts = append(attempts, attempt)
+			return time.Millisecond
+		}
+		if _, err := ReadFile("testfile", WithRetries(4), WithBackoff(backoff)); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+		if len(attempts) != 3 || attempts[0] != 1 || attempts[2] != 3 {
+			t.Errorf("expect the backoff to be called for the attempts 1 to 3 but got %v", attempts)
+		}
+	})
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Run("should double the interval up to the max", func(t *testing.T) {
+		b := ExponentialBackoff(time.Millisecond, 5*time.Millisecond)
+		want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond}
+		for i, w := range want {
+			if got := b(i + 1); got != w {
+				t.Errorf("expect %v for attempt %d but got %v", w, i+1, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,156 @@
+/*
+Package safetest verifies that a write with the safe package survives a crash at every step.
+
+Check writes a file with safe.WriteFile, then writes new contents again and again through a safe.FaultBackend
+which simulates a crash (or a failure) after each operation of the write: creating the temporary file, syncing it,
+and every link, rename and remove of the commit. The writes take the same path as on the operating system,
+including the strategy, the backup and the checksum of the options. After a crash, a Backend which implements
+Crasher (like safe.MemBackend) discards everything which was not synced. After each interruption, safe.ReadFile
+must return either the old or the new contents, never a partial or missing file, and a following write must succeed.
+
+Use it to verify a Backend, e.g. one which stores the files remotely, together with the options of an application:
+
+	err := safetest.Check("config.json", func() safe.Backend {
+		return mybackend.New(...)
+	})
+
+CheckStrategy verifies a safe.CommitStrategy the same way. The strategy is created for the Backend, so its
+operations can be interrupted as well.
+*/
+package safetest
+
+import (
+	"bytes"
+	"fmt"
+
+	safe "github.com/robojones/safe-write"
+)
+
+// Failure describes an interruption after which the file was not consistent.
+type Failure struct {
+	// Name is the name of the file.
+	Name string
+	// Step is the number of operations after which the write was interrupted.
+	Step int
+	// Steps is the number of operations of an uninterrupted write.
+	Steps int
+	// Crash is true if the write was interrupted by a crash and false if the operation failed.
+	Crash bool
+	// Data is the contents which were read after the interruption.
+	Data []byte
+	// Err is the error of the read or of the following write.
+	Err error
+}
+
+func (f *Failure) Error() string {
+	kind := "failure"
+	if f.Crash {
+		kind = "crash"
+	}
+	if f.Err != nil {
+		return fmt.Sprintf("safetest: %s after step %d of %d of %s: %v", kind, f.Step, f.Steps, f.Name, f.Err)
+	}
+	return fmt.Sprintf("safetest: %s after step %d of %d of %s: read %q", kind, f.Step, f.Steps, f.Name, f.Data)
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// Crasher is implemented by a Backend which can simulate a crash of the operating system,
+// e.g. safe.MemBackend. Crash must discard the names and the contents which were not synced.
+type Crasher interface {
+	Crash()
+}
+
+// oldData and newData are the contents of the file before and after the interrupted write.
+var (
+	oldData = []byte("safetest old contents")
+	newData = []byte("safetest new contents, which are longer than the old ones")
+)
+
+// Check verifies that the file with the name is consistent after a crash or a failure at every step of WriteFile.
+// newBackend must return an empty Backend for every call. The options are applied to every write and read.
+// If the file is inconsistent after an interruption, a *Failure is returned.
+func Check(name string, newBackend func() safe.Backend, opts ...safe.Option) error {
+	return CheckStrategy(name, newBackend, nil, opts...)
+}
+
+// CheckStrategy works like Check, but commits the files with the CommitStrategy which newStrategy returns
+// for the Backend (see safe.WithCommitStrategy). If newStrategy is nil, the strategy of the options is used.
+func CheckStrategy(name string, newBackend func() safe.Backend, newStrategy func(safe.Backend) safe.CommitStrategy, opts ...safe.Option) error {
+	counter := &safe.FaultBackend{Backend: newBackend()}
+	if err := write(counter, name, oldData, newStrategy, opts); err != nil {
+		return err
+	}
+	start := counter.Ops()
+	if err := write(counter, name, newData, newStrategy, opts); err != nil {
+		return err
+	}
+	steps := counter.Ops() - start
+
+	for step := 1; step <= steps; step++ {
+		for _, crash := range []bool{true, false} {
+			if err := check(name, newBackend(), newStrategy, step, steps, crash, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check interrupts the write of the new contents after the step and verifies the file.
+func check(name string, b safe.Backend, newStrategy func(safe.Backend) safe.CommitStrategy, step int, steps int, crash bool, opts []safe.Option) error {
+	fail := func(data []byte, err error) error {
+		return &Failure{Name: name, Step: step, Steps: steps, Crash: crash, Data: data, Err: err}
+	}
+	if err := write(b, name, oldData, newStrategy, opts); err != nil {
+		return err
+	}
+	fb := &safe.FaultBackend{Backend: b}
+	if crash {
+		fb.CrashAfter = step
+	} else {
+		fb.FailAfter = step
+	}
+	// The error is expected if the operation failed.
+	write(fb, name, newData, newStrategy, opts)
+	if c, ok := b.(Crasher); ok && crash {
+		c.Crash()
+	}
+
+	data, err := read(b, name, opts)
+	if err != nil {
+		return fail(data, err)
+	}
+	if !bytes.Equal(data, oldData) && !bytes.Equal(data, newData) {
+		return fail(data, nil)
+	}
+
+	// The next write must complete the interrupted one.
+	if err := write(b, name, newData, newStrategy, opts); err != nil {
+		return fail(nil, err)
+	}
+	data, err = read(b, name, opts)
+	if err != nil {
+		return fail(data, err)
+	}
+	if !bytes.Equal(data, newData) {
+		return fail(data, nil)
+	}
+	return nil
+}
+
+// write writes the data to the file in the Backend with the CommitStrategy of newStrategy if it is not nil.
+func write(b safe.Backend, name string, data []byte, newStrategy func(safe.Backend) safe.CommitStrategy, opts []safe.Option) error {
+	opts = append(opts[:len(opts):len(opts)], safe.WithBackend(b))
+	if newStrategy != nil {
+		opts = append(opts, safe.WithCommitStrategy(newStrategy(b)))
+	}
+	return safe.WriteFile(name, data, opts...)
+}
+
+// read reads the file from the Backend. The file is complete or missing, so the read is not retried.
+func read(b safe.Backend, name string, opts []safe.Option) ([]byte, error) {
+	return safe.ReadFile(name, append(opts[:len(opts):len(opts)], safe.WithBackend(b), safe.WithRetries(1))...)
+}
@@ -0,0 +1,109 @@
+package safetest
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	safe "github.com/robojones/safe-write"
+)
+
+// noLinkBackend is a broken Backend which does not create hard links.
+type noLinkBackend struct {
+	*safe.MemBackend
+}
+
+func (noLinkBackend) Link(oldname string, newname string) error {
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("should pass for the MemBackend", func(t *testing.T) {
+		err := Check("testfile", func() safe.Backend {
+			return safe.NewMemBackend()
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("should pass for the OSBackend", func(t *testing.T) {
+		defer os.RemoveAll("testdir")
+		err := Check("testdir/testfile", func() safe.Backend {
+			os.RemoveAll("testdir")
+			if err := os.Mkdir("testdir", 0700); err != nil {
+				t.Fatal(err)
+			}
+			return safe.OSBackend{}
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("should return a Failure for a broken Backend", func(t *testing.T) {
+		err := Check("testfile", func() safe.Backend {
+			return noLinkBackend{safe.NewMemBackend()}
+		})
+		var f *Failure
+		if !errors.As(err, &f) {
+			t.Fatalf("expect a Failure but got %v", err)
+		}
+		if f.Step < 1 || f.Step > f.Steps {
+			t.Errorf("expect the step to be between 1 and %d but got %d", f.Steps, f.Step)
+		}
+	})
+
+	t.Run("should pass for the strategies", func(t *testing.T) {
+		for _, s := range []safe.Strategy{safe.StrategyRename, safe.StrategyReplace} {
+			err := Check("testfile", func() safe.Backend {
+				return safe.NewMemBackend()
+			}, safe.WithStrategy(s), safe.WithBackup(".bak"), safe.WithChecksum())
+			if err != nil {
+				t.Errorf("%s: %v", s, err)
+			}
+		}
+	})
+
+	t.Run("should return a Failure for contents which were not synced", func(t *testing.T) {
+		err := Check("testfile", func() safe.Backend {
+			return safe.NewMemBackend()
+		}, safe.WithFsync(false))
+		var f *Failure
+		if !errors.As(err, &f) || !f.Crash {
+			t.Errorf("expect a Failure after a crash but got %v", err)
+		}
+	})
+}
+
+func TestCheckStrategy(t *testing.T) {
+	t.Run("should pass for an atomic strategy", func(t *testing.T) {
+		err := CheckStrategy("testfile", func() safe.Backend {
+			return safe.NewMemBackend()
+		}, func(b safe.Backend) safe.CommitStrategy {
+			return safe.CommitFunc(func(tmp string, alt string, final string) error {
+				return b.Rename(tmp, final)
+			})
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("should return a Failure for a strategy which removes the file first", func(t *testing.T) {
+		err := CheckStrategy("testfile", func() safe.Backend {
+			return safe.NewMemBackend()
+		}, func(b safe.Backend) safe.CommitStrategy {
+			return safe.CommitFunc(func(tmp string, alt string, final string) error {
+				if err := b.Remove(final); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				return b.Rename(tmp, final)
+			})
+		})
+		var f *Failure
+		if !errors.As(err, &f) {
+			t.Errorf("expect a Failure but got %v", err)
+		}
+	})
+}
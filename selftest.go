@@ -0,0 +1,136 @@
+package safe
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// SelfTestPrefix is the prefix of the name of the scratch file which is written by SelfTest.
+const SelfTestPrefix = ".safe-self-test-"
+
+// SelfTest checks whether the directory supports the guarantees of this package with the options.
+// It is meant to be called when an application starts, so an unsuitable filesystem is reported
+// before the first real write. SelfTest probes the commit strategy, writes a scratch file,
+// simulates an interrupted write, reads the file from the alt name, completes the write again and removes the file.
+// The returned error is a *os.PathError whose Op names the step which failed.
+// If the filesystem does not support hard links and WithRenameFallback is not set, the error wraps ErrNoHardLinks.
+func SelfTest(dir string, opts ...Option) error {
+	c := newConfig(opts)
+	if err := c.writable("self-test", dir); err != nil {
+		return err
+	}
+	dir, err := c.path(dir)
+	if err != nil {
+		return err
+	}
+	// Only the commit procedure is tested, so the scratch file must not be transformed or indexed.
+	c.prefix = ""
+	c.fenced = false
+	c.index = false
+	c.writeStats = false
+
+	name := filepath.Join(dir, SelfTestPrefix+strconv.Itoa(os.Getpid())+"-"+strconv.FormatInt(time.Now().UnixNano(), 36))
+	alt := c.altName(name)
+	defer remove(name)
+	defer remove(alt)
+
+	if err := c.prepareShadow(name); err != nil {
+		return &os.PathError{Op: "self-test prepare", Path: dir, Err: err}
+	}
+
+	strategy, err := c.strategy(name)
+	if err != nil {
+		return &os.PathError{Op: "self-test probe", Path: dir, Err: err}
+	}
+	if strategy == StrategyHardlink && !c.probeLink(name) {
+		if !c.renameFallback {
+			return &os.PathError{Op: "self-test probe", Path: dir, Err: ErrNoHardLinks}
+		}
+		c.fallBack(name)
+		strategy = StrategyRename
+	}
+
+	data := []byte("safe self-test " + strategy.String())
+	if err := c.replace(name, data); err != nil {
+		return &os.PathError{Op: "self-test write", Path: name, Err: err}
+	}
+	if err := c.selfCheck(name, data); err != nil {
+		return &os.PathError{Op: "self-test read", Path: name, Err: err}
+	}
+	if strategy == StrategyReplace || strategy == StrategyExchange || strategy == StrategySymlink {
+		// The name is replaced in a single step, so there is no interrupted state to simulate.
+		data = append(data, " replaced"...)
+		if err := c.replace(name, data); err != nil {
+			return &os.PathError{Op: "self-test replace", Path: name, Err: err}
+		}
+		if err := c.selfCheck(name, data); err != nil {
+			return &os.PathError{Op: "self-test replace", Path: name, Err: err}
+		}
+		return c.selfClean(name)
+	}
+
+	// Simulate a process which was interrupted after the alt name was committed.
+	if err := os.Remove(name); err != nil {
+		return &os.PathError{Op: "self-test interrupt", Path: name, Err: err}
+	}
+	if err := c.selfCheck(name, data); err != nil {
+		return &os.PathError{Op: "self-test fallback", Path: alt, Err: err}
+	}
+
+	data = append(data, " recovered"...)
+	if err := c.replace(name, data); err != nil {
+		return &os.PathError{Op: "self-test recover", Path: name, Err: err}
+	}
+	if err := c.selfCheck(name, data); err != nil {
+		return &os.PathError{Op: "self-test recover", Path: name, Err: err}
+	}
+	return c.selfClean(name)
+}
+
+// selfCheck reads the scratch file with the resolved name and compares it with the data.
+func (c *config) selfCheck(name string, data []byte) error {
+	got, err := c.read(name, c.altName(name))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, data) {
+		return ErrCommitIncomplete
+	}
+	return nil
+}
+
+// selfClean removes the scratch file with the resolved name and its alt name and checks that nothing is left.
+func (c *config) selfClean(name string) error {
+	for _, n := range []string{name, c.altName(name)} {
+		if target, err := symlinkTarget(n); err == nil && target != "" {
+			remove(target)
+		}
+		if err := remove(n); err != nil {
+			return &os.PathError{Op: "self-test cleanup", Path: n, Err: err}
+		}
+	}
+	dir := filepath.Dir(c.tempName(name, time.Now()))
+	names, err := readDirNames(dir)
+	if err != nil {
+		return &os.PathError{Op: "self-test cleanup", Path: dir, Err: err}
+	}
+	for _, n := range names {
+		if primary, _, ok := c.isTemp(n); ok && primary == filepath.Base(name) {
+			return &os.PathError{Op: "self-test cleanup", Path: filepath.Join(dir, n), Err: os.ErrExist}
+		}
+	}
+	return nil
+}
+
+// readDirNames returns the names of the entries of the directory.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
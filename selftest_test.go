@@ -0,0 +1,56 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	t.Run("should pass and leave no files behind", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := SelfTest("testdir"); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 0 {
+			t.Errorf("expect no files but got %d", len(infos))
+		}
+	})
+
+	t.Run("should fail if the filesystem does not support hard links", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		restore := withoutLinks()
+		defer restore()
+		if err := SelfTest("testdir"); !errors.Is(err, ErrNoHardLinks) {
+			t.Errorf("expect ErrNoHardLinks but got %v", err)
+		}
+	})
+
+	t.Run("should pass without hard links with a rename strategy", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		restore := withoutLinks()
+		defer restore()
+		defer resetStrategies()
+		for _, opt := range []Option{WithStrategy(StrategyRename), WithStrategy(StrategyReplace), WithRenameFallback(0, nil)} {
+			if err := SelfTest("testdir", opt); err != nil {
+				t.Errorf("expect the self-test to pass but got %v", err)
+			}
+		}
+	})
+
+	t.Run("should fail if the directory does not exist", func(t *testing.T) {
+		if err := SelfTest("testdir"); err == nil {
+			t.Error("expect an error")
+		}
+	})
+}
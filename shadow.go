@@ -0,0 +1,82 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ShadowDirName is the name of the directory which contains the alt files with WithShadowDir.
+const ShadowDirName = ".safe"
+
+// WithShadowDir keeps the alt files in the subdirectory ShadowDirName of the directory of each file
+// instead of next to the file, so the directory only contains the files themselves (e.g. config.json
+// and .safe/config.json.1). The shadow directory is created when a file is written.
+// Because the alt files are hard links, the shadow directory must be on the same filesystem as the files,
+// otherwise a *os.PathError wrapping ErrCrossDevice is returned.
+// All calls for the same files must use this option consistently.
+func WithShadowDir() Option {
+	return func(c *config) {
+		c.shadow = true
+	}
+}
+
+// altName returns the alt name of the file with the resolved name.
+func (c *config) altName(name string) string {
+	if !c.shadow {
+		return filepath.Join(filepath.Dir(name), c.namer.AltName(filepath.Base(name)))
+	}
+	return filepath.Join(filepath.Dir(name), ShadowDirName, c.namer.AltName(filepath.Base(name)))
+}
+
+// altDir returns the directory which contains the alt files of the files in the resolved directory.
+func (c *config) altDir(dir string) string {
+	if !c.shadow {
+		return dir
+	}
+	return filepath.Join(dir, ShadowDirName)
+}
+
+// readAltDir returns the entries of the directory which contains the alt files.
+// The entries of the directory itself are passed in to avoid reading it twice without a shadow directory.
+func (c *config) readAltDir(dir string, infos []os.FileInfo) ([]os.FileInfo, error) {
+	if !c.shadow {
+		return infos, nil
+	}
+	alts, err := ioutil.ReadDir(c.altDir(dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return alts, err
+}
+
+// prepareShadow creates the shadow directory for the file with the resolved name
+// and checks that it is on the same filesystem.
+func (c *config) prepareShadow(name string) error {
+	if !c.shadow {
+		return nil
+	}
+	dir := filepath.Dir(name)
+	shadow := c.altDir(dir)
+	if err := os.Mkdir(shadow, DefaultPerm); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	shadowInfo, err := os.Stat(shadow)
+	if err != nil {
+		return err
+	}
+	if !shadowInfo.IsDir() {
+		return &os.PathError{Op: "shadow", Path: shadow, Err: ErrNotDir}
+	}
+	a, ok := device(dirInfo)
+	b, _ := device(shadowInfo)
+	if ok && a != b {
+		return &os.PathError{Op: "shadow", Path: shadow, Err: ErrCrossDevice}
+	}
+	return nil
+}
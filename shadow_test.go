@@ -0,0 +1,59 @@
+package safe
+
+import (
+	"testing"
+)
+
+func TestWithShadowDir(t *testing.T) {
+	t.Run("should keep the alt file in the shadow directory", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithShadowDir()); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkContents(t, "testdir/.safe/testfile.1", "some data")
+		checkNotExist(t, "testdir/testfile.1")
+	})
+
+	t.Run("should read the alt file from the shadow directory", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createDir(t, "testdir/.safe")
+		createFile(t, "testdir/.safe/testfile.1", "some data")
+
+		got, err := ReadFile("testdir/testfile", WithShadowDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "some data" {
+			t.Errorf("ReadFile does not return the correct file contents. Want %q but got %q", "some data", got)
+		}
+	})
+
+	t.Run("should recover and remove files with the alt file in the shadow directory", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createDir(t, "testdir/.safe")
+		createFile(t, "testdir/.safe/testfile.1", "some data")
+
+		h, err := DirHealth("testdir", WithShadowDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Degraded != 1 {
+			t.Errorf("expect 1 degraded file but got %+v", h)
+		}
+		if _, err := RecoverDir("testdir", WithShadowDir()); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+
+		if err := RemoveFile("testdir/testfile", WithShadowDir()); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/testfile")
+		checkNotExist(t, "testdir/.safe/testfile.1")
+	})
+}
@@ -0,0 +1,42 @@
+//go:build go1.21
+// +build go1.21
+
+package safe
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger makes WriteFile and ReadFile log each step at debug level with the logger, e.g. the temporary file,
+// the links which were skipped because of concurrent writes and the temporary files which could not be removed.
+// Pass it to New, so every call of the Manager is logged. The steps are logged by an Observer (see WithObserver).
+func WithLogger(l *slog.Logger) Option {
+	return WithObserver(slogObserver{l: l})
+}
+
+// slogObserver logs the observations with a slog.Logger.
+type slogObserver struct {
+	l *slog.Logger
+}
+
+// Observe logs the observation at debug level.
+func (o slogObserver) Observe(obs Observation) {
+	ctx := context.Background()
+	if !o.l.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("event", string(obs.Kind)),
+		slog.String("name", obs.Name),
+		slog.String("path", obs.Path),
+		slog.Duration("duration", obs.Duration),
+	}
+	if obs.Kind == EventRetry {
+		attrs = append(attrs, slog.Int("attempt", obs.Attempt))
+	}
+	if obs.Err != nil {
+		attrs = append(attrs, slog.String("error", obs.Err.Error()))
+	}
+	o.l.LogAttrs(ctx, slog.LevelDebug, "safe: "+string(obs.Kind), attrs...)
+}
@@ -0,0 +1,44 @@
+//go:build go1.21
+// +build go1.21
+
+package safe
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	t.Run("should log the steps of a write at debug level", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		var buf bytes.Buffer
+		l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		if err := WriteFile("testfile", []byte("data"), WithLogger(l), WithStrategy(StrategyHardlink)); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, want := range []string{"level=DEBUG", "event=temp_created", "event=link_skipped", "error=", "event=committed"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expect %q in\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("should not log above debug level", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		var buf bytes.Buffer
+		l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		if err := WriteFile("testfile", []byte("data"), WithLogger(l)); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expect no output but got\n%s", buf.String())
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package safe
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Handle refers to a managed file by name.
+type Handle struct {
+	c    *config
+	name string
+}
+
+// Snapshot is a version of a managed file which stays readable while the file is replaced.
+// Because WriteFile never modifies a file in place but links a new file to the name, the open file descriptor
+// of a Snapshot keeps referring to the version which was current when the Snapshot was taken.
+// A Snapshot must be closed to release the old version.
+type Snapshot struct {
+	f *os.File
+}
+
+// Acquire returns a Handle for the file with the name.
+func Acquire(name string, opts ...Option) *Handle {
+	return &Handle{c: newConfig(opts), name: name}
+}
+
+// Snapshot opens the current version of the file with the same fallback to the alt file as ReadFile.
+func (h *Handle) Snapshot() (*Snapshot, error) {
+	name, err := h.c.path(h.name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := h.c.open(name, h.c.altName(name))
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{f: f}, nil
+}
+
+// open opens the file with the name or the alt name for reading and retries if neither exists.
+func (c *config) open(name string, alt string) (*os.File, error) {
+	var (
+		f   *os.File
+		err error
+	)
+
+	for i := 0; i < c.retries; i++ {
+		f, err = os.Open(name)
+		if !os.IsNotExist(err) {
+			return f, err
+		}
+		f, err = os.Open(alt)
+		if !os.IsNotExist(err) {
+			return f, err
+		}
+
+		if i == c.retries-1 {
+			break
+		}
+		if err := c.wait(name, i+1); err != nil {
+			return nil, err
+		}
+	}
+
+	// Report the name instead of the alt name, which was opened last.
+	return f, &os.PathError{Op: "open", Path: name, Err: ErrNotExist}
+}
+
+// Read reads from the current offset of the Snapshot.
+func (s *Snapshot) Read(p []byte) (int, error) {
+	return s.f.Read(p)
+}
+
+// ReadAt reads from the offset of the Snapshot.
+func (s *Snapshot) ReadAt(p []byte, off int64) (int, error) {
+	return s.f.ReadAt(p, off)
+}
+
+// Seek sets the offset for the next Read.
+func (s *Snapshot) Seek(offset int64, whence int) (int64, error) {
+	return s.f.Seek(offset, whence)
+}
+
+// Stat returns the FileInfo of the version of the Snapshot.
+func (s *Snapshot) Stat() (os.FileInfo, error) {
+	return s.f.Stat()
+}
+
+// Bytes returns the whole contents of the Snapshot regardless of the current offset.
+func (s *Snapshot) Bytes() ([]byte, error) {
+	info, err := s.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(io.NewSectionReader(s.f, 0, info.Size()))
+}
+
+// Close releases the version of the Snapshot.
+func (s *Snapshot) Close() error {
+	return s.f.Close()
+}
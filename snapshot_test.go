@@ -0,0 +1,54 @@
+package safe
+
+import (
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Run("should keep reading the acquired version while the file is replaced", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		s, err := Acquire("testfile").Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		for _, data := range []string{"new data", "newer data"} {
+			if err := WriteFile("testfile", []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := s.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "old data" {
+			t.Errorf("Snapshot does not return the acquired version. Want %q but got %q", "old data", got)
+		}
+		checkContents(t, "testfile", "newer data")
+	})
+
+	t.Run("should fall back to the alt file", func(t *testing.T) {
+		createFile(t, "testfile.1", "some data")
+		defer clean(t, "testfile.1")
+
+		s, err := Acquire("testfile").Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		got, err := s.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "some data" {
+			t.Errorf("Snapshot does not return the contents of the alt file. Got %q", got)
+		}
+	})
+}
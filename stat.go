@@ -0,0 +1,42 @@
+package safe
+
+import "os"
+
+// Stat returns the FileInfo of the file with the name or $(name).1 with the same fallback and retries as ReadFile.
+// The FileInfo has the name of the file which was found.
+func Stat(name string, opts ...Option) (os.FileInfo, error) {
+	c := newConfig(opts)
+	name, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	alt := c.altName(name)
+	for i := 0; i < c.retries; i++ {
+		info, err := os.Stat(name)
+		if !os.IsNotExist(err) {
+			return info, err
+		}
+		info, err = os.Stat(alt)
+		if !os.IsNotExist(err) {
+			return info, err
+		}
+
+		if i == c.retries-1 {
+			break
+		}
+		if err := c.wait(name, i+1); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: ErrNotExist}
+}
+
+// Exists reports whether the file with the name or $(name).1 exists, like ReadFile would find it.
+// Other errors than NotExist errors are returned.
+func Exists(name string, opts ...Option) (bool, error) {
+	_, err := Stat(name, opts...)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
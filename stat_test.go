@@ -0,0 +1,47 @@
+package safe
+
+import (
+	"os"
+	"testing"
+)
+
+func TestStat(t *testing.T) {
+	t.Run("should fall back to the alt file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile.1", "some data")
+
+		info, err := Stat("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name() != "testfile.1" || info.Size() != 9 {
+			t.Errorf("expect the info of the alt file but got %s with %d bytes", info.Name(), info.Size())
+		}
+	})
+
+	t.Run("should fail if neither file exists", func(t *testing.T) {
+		if _, err := Stat("testfile", WithRetries(1)); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+}
+
+func TestExists(t *testing.T) {
+	t.Run("should report whether the file or the alt file exists", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/a", "")
+		createFile(t, "testdir/b.1", "")
+
+		for name, want := range map[string]bool{"testdir/a": true, "testdir/b": true, "testdir/c": false} {
+			got, err := Exists(name, WithRetries(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("expect %v for %s but got %v", want, name, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,206 @@
+/*
+Package statestore persists the state of an application as a snapshot and a log of updates.
+
+Updates are appended to the log, which is much cheaper than rewriting the whole state.
+After a number of updates, the log is compacted: the current state is written as a new snapshot with safe.WriteFile
+and the log is truncated. Open restores the state from the snapshot and replays the updates of the log.
+
+Every update has a sequence number which is also stored in the snapshot, so updates which are already contained
+in the snapshot are skipped if the process is interrupted between writing the snapshot and truncating the log.
+*/
+package statestore
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync"
+
+	safe "github.com/robojones/safe-write"
+)
+
+// LogPostfix is the extension appended to the name of the snapshot to get the name of the log.
+const LogPostfix = ".log"
+
+// DefaultCompactEvery is the number of updates after which the log is compacted if no other value is set.
+const DefaultCompactEvery = 1000
+
+// seqSize is the size of the sequence number which precedes snapshots and updates.
+const seqSize = 8
+
+// ErrInvalidRecord is returned by Open if the snapshot or an update in the log has no sequence number.
+var ErrInvalidRecord = errors.New("statestore: invalid record")
+
+// State is the state of an application which is persisted by a Store.
+type State interface {
+	// Snapshot returns the encoded state.
+	Snapshot() ([]byte, error)
+	// Restore replaces the state with an encoded state which was returned by Snapshot.
+	Restore(snapshot []byte) error
+	// Apply applies an update to the state.
+	Apply(update []byte) error
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// CompactEvery sets the number of updates after which the log is compacted.
+func CompactEvery(n int) Option {
+	return func(s *Store) {
+		s.compactEvery = n
+	}
+}
+
+// WithSafeOptions sets the options which are used to write and read the snapshot and the log.
+func WithSafeOptions(opts ...safe.Option) Option {
+	return func(s *Store) {
+		s.opts = opts
+	}
+}
+
+// Store persists a State in a snapshot with the name and a log with the name $(name).log
+// A Store is safe for concurrent use.
+type Store struct {
+	name         string
+	state        State
+	opts         []safe.Option
+	compactEvery int
+
+	mu      sync.Mutex
+	log     *safe.Log
+	seq     uint64
+	pending int
+}
+
+// Open restores the state from the snapshot with the name and the log and returns a Store to update it.
+// If neither the snapshot nor the log exist, the state is left as it is.
+func Open(name string, state State, opts ...Option) (*Store, error) {
+	s := &Store{
+		name:         name,
+		state:        state,
+		compactEvery: DefaultCompactEvery,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	log, err := safe.OpenLog(s.name+LogPostfix, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.log = log
+	return s, nil
+}
+
+// load restores the snapshot and replays the updates of the log which are newer than the snapshot.
+func (s *Store) load() error {
+	snapshot, err := safe.ReadFile(s.name, s.opts...)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		seq, data, err := split(snapshot)
+		if err != nil {
+			return err
+		}
+		if err := s.state.Restore(data); err != nil {
+			return err
+		}
+		s.seq = seq
+	}
+
+	updates, err := safe.ReadRecords(s.name+LogPostfix, s.opts...)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, rec := range updates {
+		seq, update, err := split(rec)
+		if err != nil {
+			return err
+		}
+		if seq <= s.seq {
+			// The update is already contained in the snapshot.
+			continue
+		}
+		if err := s.state.Apply(update); err != nil {
+			return err
+		}
+		s.seq = seq
+		s.pending++
+	}
+	return nil
+}
+
+// Update appends the update to the log and applies it to the state, so the state never contains an update
+// which is not persisted. If the update can not be appended, the state is not changed.
+// If the update can not be applied, the state is written as a new snapshot which skips the update,
+// so it is not replayed by Open, and the error of Apply is returned.
+// The log is compacted after every CompactEvery updates.
+func (s *Store) Update(update []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.log.Append(join(s.seq+1, update)); err != nil {
+		return err
+	}
+	s.seq++
+	s.pending++
+	if err := s.state.Apply(update); err != nil {
+		if cerr := s.compact(); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+	if s.pending >= s.compactEvery {
+		return s.compact()
+	}
+	return nil
+}
+
+// Compact writes the current state as a new snapshot and truncates the log.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
+
+// compact writes the snapshot and truncates the log.
+func (s *Store) compact() error {
+	snapshot, err := s.state.Snapshot()
+	if err != nil {
+		return err
+	}
+	if err := safe.WriteFile(s.name, join(s.seq, snapshot), s.opts...); err != nil {
+		return err
+	}
+	if err := s.log.Truncate(); err != nil {
+		return err
+	}
+	s.pending = 0
+	return nil
+}
+
+// Close closes the log. The state is not compacted.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
+
+// join prefixes the data with the sequence number.
+func join(seq uint64, data []byte) []byte {
+	buf := make([]byte, seqSize+len(data))
+	binary.BigEndian.PutUint64(buf, seq)
+	copy(buf[seqSize:], data)
+	return buf
+}
+
+// split separates the sequence number from the data.
+func split(rec []byte) (uint64, []byte, error) {
+	if len(rec) < seqSize {
+		return 0, nil, ErrInvalidRecord
+	}
+	return binary.BigEndian.Uint64(rec), rec[seqSize:], nil
+}
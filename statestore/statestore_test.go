@@ -0,0 +1,178 @@
+package statestore
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	safe "github.com/robojones/safe-write"
+)
+
+// counter is a State which adds up the updates.
+type counter struct {
+	sum int
+}
+
+func (c *counter) Snapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(c.sum)), nil
+}
+
+func (c *counter) Restore(snapshot []byte) error {
+	sum, err := strconv.Atoi(string(snapshot))
+	c.sum = sum
+	return err
+}
+
+func (c *counter) Apply(update []byte) error {
+	n, err := strconv.Atoi(string(update))
+	c.sum += n
+	return err
+}
+
+// clean removes the files of the store.
+func clean(t *testing.T) {
+	for _, name := range []string{"teststate", "teststate.1", "teststate.log"} {
+		if err := os.RemoveAll(name); err != nil {
+			t.Errorf("Error during cleanup: %v", err)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Run("should restore the state from the log", func(t *testing.T) {
+		defer clean(t)
+		s, err := Open("teststate", &counter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, update := range []string{"1", "2", "3"} {
+			if err := s.Update([]byte(update)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		got := &counter{}
+		s, err = Open("teststate", got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if got.sum != 6 {
+			t.Errorf("expect the restored sum 6 but got %d", got.sum)
+		}
+	})
+
+	t.Run("should restore the state from the snapshot and the log after a compaction", func(t *testing.T) {
+		defer clean(t)
+		s, err := Open("teststate", &counter{}, CompactEvery(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, update := range []string{"1", "2", "3"} {
+			if err := s.Update([]byte(update)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := safe.ReadRecords("teststate.log")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 {
+			t.Errorf("expect 1 update in the log after the compaction but got %d", len(records))
+		}
+
+		got := &counter{}
+		s, err = Open("teststate", got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if got.sum != 6 {
+			t.Errorf("expect the restored sum 6 but got %d", got.sum)
+		}
+	})
+
+	t.Run("should skip updates which are contained in the snapshot", func(t *testing.T) {
+		defer clean(t)
+		s, err := Open("teststate", &counter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, update := range []string{"1", "2"} {
+			if err := s.Update([]byte(update)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Simulate an interruption between writing the snapshot and truncating the log.
+		if err := safe.WriteFile("teststate", join(2, []byte("3"))); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		got := &counter{}
+		s, err = Open("teststate", got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if got.sum != 3 {
+			t.Errorf("expect the restored sum 3 but got %d", got.sum)
+		}
+	})
+
+	t.Run("should not replay an update which can not be applied", func(t *testing.T) {
+		defer clean(t)
+		s, err := Open("teststate", &counter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, update := range []string{"1", "invalid", "2"} {
+			err := s.Update([]byte(update))
+			if update == "invalid" && err == nil {
+				t.Error("expect the error of Apply")
+			} else if update != "invalid" && err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		got := &counter{}
+		s, err = Open("teststate", got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if got.sum != 3 {
+			t.Errorf("expect the restored sum 3 but got %d", got.sum)
+		}
+	})
+
+	t.Run("should not apply an update which can not be appended", func(t *testing.T) {
+		defer clean(t)
+		state := &counter{}
+		s, err := Open("teststate", state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.log.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Update([]byte("1")); err == nil {
+			t.Error("expect the error of the append")
+		}
+		if state.sum != 0 {
+			t.Errorf("expect the state not to change but got %d", state.sum)
+		}
+	})
+}
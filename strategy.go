@@ -0,0 +1,282 @@
+package safe
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Strategy is the protocol which is used to commit the files of a directory.
+type Strategy int
+
+const (
+	// StrategyHardlink commits a file by hard linking the temporary file to the alt name and the name.
+	// It is the default and guarantees that the name or the alt name always points to a complete version.
+	// On Windows, StrategyRename is the default instead.
+	StrategyHardlink Strategy = iota
+	// StrategyRename commits a file by renaming a copy of the temporary file to the alt name and the temporary
+	// file itself to the name. It is used with WithRenameFallback on filesystems without hard links.
+	// On Windows, the files are moved with MoveFileEx(MOVEFILE_REPLACE_EXISTING|MOVEFILE_WRITE_THROUGH),
+	// and a move which fails because another process has the file open is retried (see WithRetries).
+	// Each name still points to a complete version, but the alt file is a copy, so the write costs twice the I/O.
+	StrategyRename
+	// StrategyReplace commits a file by renaming the temporary file to the name. No alt file is kept,
+	// so there is no $(name).1 next to the file. Since a rename replaces the name atomically,
+	// the name still always points to a complete version. It works on filesystems without hard links
+	// (e.g. FAT32 and some network filesystems). An alt file left over from a previous strategy is removed.
+	StrategyReplace
+	// StrategyExchange commits a file by atomically swapping the temporary file with the name
+	// (renameat2 with RENAME_EXCHANGE on Linux) and moving the previous version to the alt name.
+	// The name is replaced in a single step, so it never diverges from a complete version.
+	// Unlike the other strategies, the alt file contains the previous version, which can be restored.
+	// If the platform or the filesystem does not support exchanges, StrategyHardlink is used.
+	StrategyExchange
+	// StrategySymlink commits a file by renaming the temporary file to an immutable version $(name).v$(version)
+	// and atomically repointing the name, which is a symbolic link, to it (see SymlinkCommit).
+	// The alt name points to the previous version, so a rollout can be undone with Revert.
+	// On Windows, creating symbolic links requires the corresponding privilege.
+	StrategySymlink
+)
+
+// String returns the name of the Strategy.
+func (s Strategy) String() string {
+	switch s {
+	case StrategyRename:
+		return "rename"
+	case StrategyReplace:
+		return "replace"
+	case StrategyExchange:
+		return "exchange"
+	case StrategySymlink:
+		return "symlink"
+	}
+	return "hardlink"
+}
+
+// WithStrategy makes WriteFile and Create always commit the files with the strategy instead of probing
+// the directory. It takes precedence over WithRenameFallback. Combined with a Manager, a whole tree of files
+// can use another strategy, e.g.
+//
+//	m := safe.New(safe.WithStrategy(safe.StrategyReplace))
+func WithStrategy(s Strategy) Option {
+	return func(c *config) {
+		c.fixedStrategy = true
+		c.commitStrategy = s
+	}
+}
+
+// DefaultReprobeInterval is the interval after which a directory which uses StrategyRename is probed
+// for hard link support again.
+const DefaultReprobeInterval = time.Minute
+
+// WithRenameFallback makes WriteFile and Create fall back to StrategyRename if the filesystem
+// does not support hard links. The directory is probed again every reprobe interval (DefaultReprobeInterval if 0),
+// so the stronger StrategyHardlink is used again as soon as the filesystem supports it (e.g. after a remount).
+// The onChange callback, which may be nil, is called whenever the strategy of a directory changes.
+// A link which is denied although the filesystem supports hard links (e.g. by protected_hardlinks) is an error.
+func WithRenameFallback(reprobe time.Duration, onChange func(dir string, from Strategy, to Strategy)) Option {
+	return func(c *config) {
+		if reprobe <= 0 {
+			reprobe = DefaultReprobeInterval
+		}
+		c.renameFallback = true
+		c.reprobe = reprobe
+		c.onStrategy = onChange
+	}
+}
+
+// dirStrategy is the strategy of a directory and the time when it was last probed.
+type dirStrategy struct {
+	strategy Strategy
+	probed   time.Time
+}
+
+// strategies contains the strategies of the directories which fell back to StrategyRename by absolute name.
+var strategies = struct {
+	sync.Mutex
+	m map[string]dirStrategy
+}{m: make(map[string]dirStrategy)}
+
+// osLink creates hard links. It is a variable so tests can simulate filesystems without hard links.
+var osLink = os.Link
+
+// strategy returns the strategy for the directory of the resolved name.
+// It fails if WithAutoStrategy is set and the directory does not support safe writes.
+func (c *config) strategy(name string) (Strategy, error) {
+	if c.fixedStrategy {
+		return c.commitStrategy, nil
+	}
+	if c.backend != nil {
+		// Probing the directory relies on the operating system.
+		return StrategyHardlink, nil
+	}
+	if c.autoStrategy {
+		return c.probedStrategy(name)
+	}
+	return c.fallbackStrategy(name), nil
+}
+
+// fallbackStrategy returns the strategy for the directory of the resolved name and probes it again if it is due.
+func (c *config) fallbackStrategy(name string) Strategy {
+	if !c.renameFallback || defaultStrategy != StrategyHardlink {
+		return defaultStrategy
+	}
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return StrategyHardlink
+	}
+	strategies.Lock()
+	s, ok := strategies.m[dir]
+	strategies.Unlock()
+	if !ok {
+		return StrategyHardlink
+	}
+	if time.Since(s.probed) < c.reprobe {
+		return s.strategy
+	}
+	if c.probeLink(name) {
+		c.setStrategy(dir, StrategyHardlink)
+		return StrategyHardlink
+	}
+	c.setStrategy(dir, StrategyRename)
+	return StrategyRename
+}
+
+// fallBack switches the directory of the resolved name to StrategyRename.
+func (c *config) fallBack(name string) {
+	if dir, err := filepath.Abs(filepath.Dir(name)); err == nil {
+		c.setStrategy(dir, StrategyRename)
+	}
+}
+
+// setStrategy records the strategy of the absolute directory and reports a change to the callback.
+func (c *config) setStrategy(dir string, s Strategy) {
+	strategies.Lock()
+	from, ok := strategies.m[dir]
+	if s == StrategyHardlink {
+		delete(strategies.m, dir)
+	} else {
+		strategies.m[dir] = dirStrategy{strategy: s, probed: time.Now()}
+	}
+	strategies.Unlock()
+
+	if !ok {
+		from.strategy = StrategyHardlink
+	}
+	if from.strategy != s && c.onStrategy != nil {
+		c.onStrategy(dir, from.strategy, s)
+	}
+}
+
+// probeLink reports whether hard links can be created in the directory of the resolved name.
+func (c *config) probeLink(name string) bool {
+	probe := c.tempName(filepath.Join(filepath.Dir(name), ".probe"), time.Now())
+	if err := write(probe, nil, c.perm, nil); err != nil {
+		return false
+	}
+	defer os.Remove(probe)
+	target := probe + ".link"
+	if err := osLink(probe, target); err != nil {
+		return false
+	}
+	os.Remove(target)
+	return true
+}
+
+// linkUnsupported reports whether the error of a link in the directory of the resolved name means that the
+// filesystem does not support hard links. A denied link only counts if the probe can not link either,
+// so other permission errors (e.g. of protected_hardlinks) are returned to the caller.
+func (c *config) linkUnsupported(name string, err error) bool {
+	for _, e := range linkUnsupportedErrs {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return errors.Is(err, errLinkDenied) && !c.probeLink(name)
+}
+
+// commitWith commits the tmpname to the resolved name with the strategy and returns the strategy which was used.
+// If the file is open in another process (on Windows), the commit is retried.
+func (c *config) commitWith(s Strategy, tmpname string, altname string, name string) (Strategy, error) {
+	for i := 1; ; i++ {
+		used, err := c.commitOnce(s, tmpname, altname, name)
+		if !sharingViolation(err) || i >= c.retries {
+			return used, err
+		}
+		if err := c.wait(name, i); err != nil {
+			return used, err
+		}
+	}
+}
+
+// commitOnce commits the tmpname to the resolved name with the strategy and returns the strategy which was used.
+// If hard links are not supported and WithRenameFallback is set, it falls back to StrategyRename.
+func (c *config) commitOnce(s Strategy, tmpname string, altname string, name string) (Strategy, error) {
+	switch s {
+	case StrategyRename:
+		return s, CopyRenameCommit{Sync: !c.noSync, backend: c.backend, perm: c.perm}.Commit(tmpname, altname, name)
+	case StrategyReplace:
+		return s, RenameCommit{backend: c.backend}.Commit(tmpname, altname, name)
+	case StrategySymlink:
+		if c.backend != nil {
+			return s, &os.LinkError{Op: "symlink", Old: tmpname, New: name, Err: ErrUnsupportedFS}
+		}
+		return s, SymlinkCommit{}.Commit(tmpname, altname, name)
+	case StrategyExchange:
+		// A Backend can not exchange files.
+		if c.backend == nil {
+			err := ExchangeCommit{}.Commit(tmpname, altname, name)
+			if !exchangeUnsupported(err) {
+				return s, err
+			}
+		}
+		s = StrategyHardlink
+	}
+	h := HardlinkCommit{Strict: c.strict, backend: c.backend}
+	if len(c.observers) > 0 {
+		h.report = func(kind EventKind, path string, start time.Time, err error) {
+			c.observe(kind, name, path, start, err)
+		}
+	}
+	err := h.Commit(tmpname, altname, name)
+	if err != nil && c.renameFallback && c.linkUnsupported(name, err) {
+		c.fallBack(name)
+		return c.commitOnce(StrategyRename, tmpname, altname, name)
+	}
+	return s, err
+}
+
+// copyFile copies the contents, the permissions and the modification time of the file with the name src
+// to a new file with the name dst.
+func copyFile(src string, dst string, sync bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if sync {
+		if err := out.Sync(); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
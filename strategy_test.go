@@ -0,0 +1,177 @@
+package safe
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withoutLinks simulates a filesystem without hard links until the returned function is called.
+func withoutLinks() func() {
+	osLink = func(oldname string, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errLinkDenied}
+	}
+	return func() {
+		osLink = os.Link
+	}
+}
+
+// resetStrategies forgets the strategies of all directories.
+func resetStrategies() {
+	strategies.Lock()
+	strategies.m = make(map[string]dirStrategy)
+	strategies.Unlock()
+}
+
+func TestWithRenameFallback(t *testing.T) {
+	t.Run("should fall back to renames and upgrade to hard links again", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		type change struct{ from, to Strategy }
+		var changes []change
+		opt := WithRenameFallback(SleepTime, func(dir string, from Strategy, to Strategy) {
+			changes = append(changes, change{from, to})
+		})
+
+		restore := withoutLinks()
+		if err := WriteFile("testdir/testfile", []byte("old data"), opt); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/testfile", []byte("new data"), opt, WithAssertCommitted()); err != nil {
+			t.Fatal(err)
+		}
+		restore()
+		checkContents(t, "testdir/testfile", "new data")
+		checkContents(t, "testdir/testfile.1", "new data")
+
+		time.Sleep(2 * SleepTime)
+		if err := WriteFile("testdir/testfile", []byte("newer data"), opt, WithAssertCommitted()); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "newer data")
+
+		want := []change{{StrategyHardlink, StrategyRename}, {StrategyRename, StrategyHardlink}}
+		if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+			t.Errorf("expect the changes %v but got %v", want, changes)
+		}
+	})
+
+	t.Run("should fail without the fallback", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		restore := withoutLinks()
+		defer restore()
+		if err := WriteFile("testdir/testfile", []byte("data")); !os.IsPermission(err) {
+			t.Errorf("expect a permission error but got %v", err)
+		}
+	})
+
+	t.Run("should return a denied link if the filesystem supports hard links", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		defer resetStrategies()
+
+		// Only the links of the file are denied, like by protected_hardlinks, so the probe succeeds.
+		osLink = func(oldname string, newname string) error {
+			if strings.Contains(newname, ".probe") {
+				return os.Link(oldname, newname)
+			}
+			return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errLinkDenied}
+		}
+		defer func() {
+			osLink = os.Link
+		}()
+		changed := false
+		opt := WithRenameFallback(time.Minute, func(dir string, from Strategy, to Strategy) {
+			changed = true
+		})
+		if err := WriteFile("testdir/testfile", []byte("data"), opt); !os.IsPermission(err) {
+			t.Errorf("expect a permission error but got %v", err)
+		}
+		if changed {
+			t.Error("expect the strategy of the directory to stay unchanged")
+		}
+	})
+}
+
+func TestWithStrategy(t *testing.T) {
+	t.Run("should replace the file without an alt file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/testfile", []byte("new data"), WithStrategy(StrategyReplace)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "new data")
+		checkNotExist(t, "testdir/testfile.1")
+
+		data, err := ReadFile("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "new data" {
+			t.Errorf("expect new data but got %q", data)
+		}
+	})
+
+	t.Run("should commit with renames on filesystems without hard links", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		restore := withoutLinks()
+		defer restore()
+		if err := WriteFile("testdir/testfile", []byte("some data"), WithStrategy(StrategyRename)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkContents(t, "testdir/testfile.1", "some data")
+	})
+
+	t.Run("should apply the strategy of a Manager", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		m := New(WithStrategy(StrategyReplace))
+		if err := m.WriteFile("testdir/testfile", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+		checkNotExist(t, "testdir/testfile.1")
+	})
+}
+
+func TestStrategyExchange(t *testing.T) {
+	t.Run("should keep the previous version as the alt file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		opt := WithStrategy(StrategyExchange)
+		if err := WriteFile("testdir/testfile", []byte("old data"), opt); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/testfile", []byte("new data"), opt); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "new data")
+		if err := renameExchange("testdir/testfile", "testdir/testfile"); exchangeUnsupported(err) {
+			checkContents(t, "testdir/testfile.1", "new data")
+		} else {
+			checkContents(t, "testdir/testfile.1", "old data")
+		}
+	})
+
+	t.Run("should pass the self-test", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := SelfTest("testdir", WithStrategy(StrategyExchange)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
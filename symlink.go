@@ -0,0 +1,164 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionPostfix is inserted between a name and the version to get the name of an immutable version
+// which is pointed to by a symbolic link (e.g. config.json.v1a2b3c4d5e6f). Versions are created by WriteDir and
+// by WriteFile with StrategySymlink.
+const VersionPostfix = ".v"
+
+// versionName returns the name of the version of the resolved name which was created at the time.
+func versionName(name string, t time.Time) string {
+	return name + VersionPostfix + strconv.FormatInt(t.UnixNano(), 36)
+}
+
+// isVersion reports whether the base name is the name of a version of the file with the base name primary.
+func isVersion(name string, primary string) bool {
+	suffix := strings.TrimPrefix(name, primary+VersionPostfix)
+	if suffix == name || len(suffix) < 12 {
+		return false
+	}
+	_, err := strconv.ParseInt(suffix, 36, 64)
+	return err == nil
+}
+
+// SymlinkCommit renames the temporary file to a new immutable version $(final).v$(version)
+// and atomically repoints the final name, which is a symbolic link, to it.
+// The alt name is a symbolic link to the previous version, so Revert can switch back to it.
+// Older versions are removed. If the final name is a regular file, it is kept as the previous version.
+// It is used by StrategySymlink.
+type SymlinkCommit struct{}
+
+// Commit renames the tmp file to a new version and points the final name to it and the alt name to the previous version.
+func (SymlinkCommit) Commit(tmp string, alt string, final string) error {
+	previous, err := symlinkTarget(final)
+	if err != nil {
+		return err
+	}
+	version := versionName(final, time.Now())
+	if previous == "" {
+		// The final name is a regular file (or does not exist), which is kept as the previous version.
+		previous = versionName(final, time.Now().Add(-time.Nanosecond))
+		if err := osLink(final, previous); os.IsNotExist(err) {
+			previous = ""
+		} else if err != nil {
+			if err := copyFile(final, previous, true); err != nil {
+				os.Remove(previous)
+				return err
+			}
+		}
+	}
+	if err := os.Rename(tmp, version); err != nil {
+		return err
+	}
+	if previous != "" {
+		if err := symlinkOver(copyName(tmp, alt, final), alt, previous); err != nil {
+			return err
+		}
+	}
+	// The tmp name is free again after the rename.
+	if err := symlinkOver(tmp, final, version); err != nil {
+		return err
+	}
+	return pruneVersions(final, version, previous)
+}
+
+// Revert switches the name back to the previous version, which is pointed to by its alt name,
+// and points the alt name to the version which was current. Calling Revert twice restores the current version.
+// It works for files which are written with StrategySymlink and for directories written by WriteDir.
+// If the name or the alt name is not a symbolic link, a *os.PathError wrapping ErrNotSymlink is returned.
+func Revert(name string, opts ...Option) error {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	alt := c.altName(name)
+
+	current, err := symlinkTarget(name)
+	if err != nil {
+		return err
+	}
+	previous, err := symlinkTarget(alt)
+	if err != nil {
+		return err
+	}
+	if current == "" || previous == "" {
+		return &os.PathError{Op: "revert", Path: name, Err: ErrNotSymlink}
+	}
+	if err := c.relink(alt, current); err != nil {
+		return err
+	}
+	if err := c.relink(name, previous); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(name))
+}
+
+// symlinkTarget returns the resolved name the symbolic link with the resolved name points to.
+// If the name does not exist or is not a symbolic link, it returns "".
+func symlinkTarget(name string) (string, error) {
+	info, err := os.Lstat(name)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", nil
+	}
+	target, err := os.Readlink(name)
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(target) {
+		return target, nil
+	}
+	return filepath.Join(filepath.Dir(name), target), nil
+}
+
+// relink atomically replaces the resolved name with a symbolic link to the resolved target.
+func (c *config) relink(name string, target string) error {
+	return symlinkOver(c.tempName(name, time.Now()), name, target)
+}
+
+// symlinkOver creates a symbolic link to the resolved target with the name tmp and renames it over the resolved name.
+// The link is relative, so the directory can be moved.
+func symlinkOver(tmp string, name string, target string) error {
+	if err := os.Symlink(filepath.Base(target), tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// pruneVersions removes the versions of the resolved name except the resolved current and previous version.
+func pruneVersions(name string, current string, previous string) error {
+	dir := filepath.Dir(name)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		n := filepath.Join(dir, info.Name())
+		if n == current || n == previous || !isVersion(info.Name(), filepath.Base(name)) {
+			continue
+		}
+		if err := os.RemoveAll(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
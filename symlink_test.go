@@ -0,0 +1,93 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestStrategySymlink(t *testing.T) {
+	t.Run("should repoint the name to a new version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, data := range []string{"first", "second", "third"} {
+			if err := WriteFile("testdir/testfile", []byte(data), WithStrategy(StrategySymlink)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkContents(t, "testdir/testfile", "third")
+		checkContents(t, "testdir/testfile.1", "second")
+		info, err := os.Lstat("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("expect a symbolic link but got %v", info.Mode())
+		}
+		if n := countVersions(t, "testdir/testfile"); n != 2 {
+			t.Errorf("expect 2 versions but got %d", n)
+		}
+	})
+
+	t.Run("should keep a regular file as the previous version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/testfile", "old data")
+
+		if err := WriteFile("testdir/testfile", []byte("new data"), WithStrategy(StrategySymlink)); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "new data")
+		checkContents(t, "testdir/testfile.1", "old data")
+	})
+}
+
+func TestRevert(t *testing.T) {
+	t.Run("should switch between the current and the previous version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, data := range []string{"blue", "green"} {
+			if err := WriteFile("testdir/testfile", []byte(data), WithStrategy(StrategySymlink)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := Revert("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "blue")
+		checkContents(t, "testdir/testfile.1", "green")
+		if err := Revert("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "green")
+	})
+
+	t.Run("should revert a directory", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, contents := range []string{"blue", "green"} {
+			if err := WriteDir("testdir/conf", populateWith("file", contents)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := Revert("testdir/conf"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/conf/file", "blue")
+	})
+
+	t.Run("should fail for a regular file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteFile("testdir/testfile", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := Revert("testdir/testfile"); !errors.Is(err, ErrNotSymlink) {
+			t.Errorf("expect ErrNotSymlink but got %v", err)
+		}
+	})
+}
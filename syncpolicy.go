@@ -0,0 +1,71 @@
+package safe
+
+import "os"
+
+// SyncPolicy controls how much WriteFile and Create sync to the disk before they return.
+type SyncPolicy int
+
+const (
+	// SyncNone does not sync anything. It is meant for caches which can be rebuilt,
+	// since after a power loss the file may be empty, incomplete or missing.
+	SyncNone SyncPolicy = iota
+	// SyncData syncs the contents of the temporary file with fdatasync, which skips metadata
+	// like the modification time. On platforms without fdatasync, it works like SyncFull.
+	SyncData
+	// SyncFull syncs the contents and the metadata of the temporary file with fsync.
+	SyncFull
+	// SyncDir syncs the temporary file with fsync and the parent directory after the commit,
+	// so the new name is durable as well. It is the default.
+	SyncDir
+)
+
+// String returns the name of the SyncPolicy.
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncNone:
+		return "none"
+	case SyncData:
+		return "data"
+	case SyncFull:
+		return "full"
+	}
+	return "dir"
+}
+
+// WithSyncPolicy sets how much WriteFile and Create sync to the disk.
+// It replaces the settings of WithFsync and WithDirSync, except that SyncDir keeps DirSyncCreated.
+func WithSyncPolicy(p SyncPolicy) Option {
+	return func(c *config) {
+		c.noSync = p == SyncNone
+		c.dataSync = p == SyncData
+		if p != SyncDir {
+			c.dirSync = DirSyncNone
+		} else if c.dirSync < DirSyncParent {
+			c.dirSync = DirSyncParent
+		}
+	}
+}
+
+// syncPolicy returns the SyncPolicy which corresponds to the settings.
+func (c *config) syncPolicy() SyncPolicy {
+	switch {
+	case c.noSync:
+		return SyncNone
+	case c.dataSync:
+		return SyncData
+	case c.dirSync < DirSyncParent:
+		return SyncFull
+	}
+	return SyncDir
+}
+
+// syncFile syncs the written file according to the policy.
+func (c *config) syncFile(f *os.File) error {
+	if c.noSync {
+		return nil
+	}
+	if c.dataSync {
+		return fdatasync(f)
+	}
+	return f.Sync()
+}
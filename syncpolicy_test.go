@@ -0,0 +1,61 @@
+package safe
+
+import "testing"
+
+func TestWithSyncPolicy(t *testing.T) {
+	t.Run("should configure the syncs of the policy", func(t *testing.T) {
+		cases := []struct {
+			policy   SyncPolicy
+			noSync   bool
+			dataSync bool
+			dirSync  DirSync
+		}{
+			{SyncNone, true, false, DirSyncNone},
+			{SyncData, false, true, DirSyncNone},
+			{SyncFull, false, false, DirSyncNone},
+			{SyncDir, false, false, DirSyncParent},
+		}
+		for _, tc := range cases {
+			c := newConfig([]Option{WithSyncPolicy(tc.policy)})
+			if c.noSync != tc.noSync || c.dataSync != tc.dataSync || c.dirSync != tc.dirSync {
+				t.Errorf("unexpected config for %v: noSync %v, dataSync %v, dirSync %v", tc.policy, c.noSync, c.dataSync, c.dirSync)
+			}
+		}
+	})
+
+	t.Run("should keep DirSyncCreated with SyncDir", func(t *testing.T) {
+		c := newConfig([]Option{WithDirSync(DirSyncCreated), WithSyncPolicy(SyncDir)})
+		if c.dirSync != DirSyncCreated {
+			t.Errorf("expect DirSyncCreated but got %v", c.dirSync)
+		}
+	})
+
+	t.Run("should write the file with every policy", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, p := range []SyncPolicy{SyncNone, SyncData, SyncFull, SyncDir} {
+			if err := WriteFile("testdir/testfile", []byte(p.String()), WithSyncPolicy(p)); err != nil {
+				t.Fatal(err)
+			}
+			checkContents(t, "testdir/testfile", p.String())
+		}
+	})
+
+	t.Run("should apply the policy to streamed files", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		f, err := Create("testdir/testfile", WithSyncPolicy(SyncData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+	})
+}
@@ -0,0 +1,61 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package safe
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// oTmpfile is the O_TMPFILE flag of open, which includes O_DIRECTORY.
+	oTmpfile = 0x400000 | syscall.O_DIRECTORY
+	// atEmptyPath makes linkat link the file descriptor itself.
+	atEmptyPath = 0x1000
+	// atSymlinkFollow makes linkat follow the magic link in /proc.
+	atSymlinkFollow = 0x400
+)
+
+// openAnonymous creates a file without a name in the directory, which disappears if it is closed before
+// it was linked with linkAnonymous.
+func openAnonymous(dir string, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(dir, os.O_RDWR|oTmpfile, perm)
+}
+
+// linkAnonymous gives the file created by openAnonymous the name.
+// Linking the file descriptor itself requires CAP_DAC_READ_SEARCH, so /proc/self/fd is used otherwise.
+func linkAnonymous(f *os.File, name string) error {
+	fd := int(f.Fd())
+	err := linkat(fd, "", atFDCWD, name, atEmptyPath)
+	if err == syscall.ENOENT || err == syscall.EPERM {
+		err = linkat(atFDCWD, "/proc/self/fd/"+strconv.Itoa(fd), atFDCWD, name, atSymlinkFollow)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "linkat", Old: f.Name(), New: name, Err: err}
+	}
+	return nil
+}
+
+// linkat calls the linkat system call.
+func linkat(olddirfd int, oldname string, newdirfd int, newname string, flags int) error {
+	oldp, err := syscall.BytePtrFromString(oldname)
+	if err != nil {
+		return err
+	}
+	newp, err := syscall.BytePtrFromString(newname)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_LINKAT,
+		uintptr(olddirfd), uintptr(unsafe.Pointer(oldp)),
+		uintptr(newdirfd), uintptr(unsafe.Pointer(newp)),
+		uintptr(flags), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
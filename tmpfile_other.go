@@ -0,0 +1,16 @@
+//go:build !linux || (!amd64 && !arm64)
+// +build !linux !amd64,!arm64
+
+package safe
+
+import "os"
+
+// openAnonymous is not supported on this platform.
+func openAnonymous(dir string, perm os.FileMode) (*os.File, error) {
+	return nil, errNotSupported
+}
+
+// linkAnonymous is not supported on this platform.
+func linkAnonymous(f *os.File, name string) error {
+	return errNotSupported
+}
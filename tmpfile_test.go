@@ -0,0 +1,45 @@
+package safe
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestOpenAnonymous(t *testing.T) {
+	t.Run("should only create the file when it is linked", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		f, err := openAnonymous("testdir", DefaultPerm)
+		if err != nil {
+			t.Skipf("anonymous files are not supported: %v", err)
+		}
+		defer f.Close()
+		if _, err := f.Write([]byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 0 {
+			t.Errorf("expect no files before the link but got %d", len(infos))
+		}
+		if err := linkAnonymous(f, "testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+	})
+}
+
+func TestWriteTemp(t *testing.T) {
+	t.Run("should write the temporary file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := newConfig(nil).writeTemp("testdir/testfile", "testdir/testfile", []byte("some data")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "some data")
+	})
+}
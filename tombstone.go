@@ -0,0 +1,201 @@
+package safe
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TombstonePostfix is appended to the name of a file which was removed with RemoveFileAfter,
+// followed by the Unix time when its retention ends.
+const TombstonePostfix = ".removed-"
+
+// RemoveFileAfter removes the file with the name like RemoveFile but keeps its contents in a tombstone
+// $(name).removed-<unix time> until the retention has passed, so it can be brought back with Restore.
+// Reads of the name return a NotExist error right away. Expired tombstones are deleted by PurgeRemoved
+// or a Janitor. If the file does not exist, nothing happens.
+func RemoveFileAfter(name string, retention time.Duration, opts ...Option) error {
+	c := newConfig(opts)
+	if err := c.writable("remove", name); err != nil {
+		return err
+	}
+	name, err := c.path(name)
+	if err != nil {
+		return err
+	}
+	alt := c.altName(name)
+
+	src := name
+	if _, err := os.Lstat(src); os.IsNotExist(err) {
+		src = alt
+	}
+	tomb := c.tombstoneName(name, time.Now().Add(retention))
+	if err := link(src, tomb); err != nil {
+		return err
+	}
+	if err := remove(name); err != nil {
+		return err
+	}
+	if err := remove(alt); err != nil {
+		return err
+	}
+	if c.index {
+		if err := c.removeFromIndex(name); err != nil {
+			return err
+		}
+	}
+	return c.auditRemove(name)
+}
+
+// Restore brings back the file with the name from its latest tombstone which was created by RemoveFileAfter.
+// If there is no tombstone, a NotExist error is returned. If the file was written again in the meantime,
+// a *os.PathError wrapping os.ErrExist is returned and the tombstone is kept.
+func Restore(name string, opts ...Option) error {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	alt := c.altName(name)
+
+	tombs, err := c.tombstones(name)
+	if err != nil {
+		return err
+	}
+	if len(tombs) == 0 {
+		return &os.PathError{Op: "restore", Path: name, Err: os.ErrNotExist}
+	}
+	for _, n := range []string{name, alt} {
+		if _, err := os.Lstat(n); err == nil {
+			return &os.PathError{Op: "restore", Path: name, Err: os.ErrExist}
+		}
+	}
+
+	// The latest tombstone has the highest expiry.
+	tomb := tombs[len(tombs)-1].name
+	if err := os.Link(tomb, alt); err != nil {
+		return err
+	}
+	if err := link(alt, name); err != nil {
+		return err
+	}
+	if c.index {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if err := c.addToIndex(name, data, info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return os.Remove(tomb)
+}
+
+// PurgeRemoved deletes the tombstones in the directory whose retention has passed
+// and returns how many were deleted.
+func PurgeRemoved(dir string, opts ...Option) (int, error) {
+	c := newConfig(opts)
+	if err := c.writable("purge", dir); err != nil {
+		return 0, err
+	}
+	dir, err := c.path(dir)
+	if err != nil {
+		return 0, err
+	}
+	infos, err := ioutil.ReadDir(c.altDir(dir))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	n := 0
+	for _, info := range infos {
+		_, expiry, ok := isTombstone(info.Name())
+		if !ok || expiry.After(now) {
+			continue
+		}
+		if err := remove(filepath.Join(c.altDir(dir), info.Name())); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Janitor calls PurgeRemoved for the directory every interval until the context is done.
+// Errors are passed to the onError callback, which may be nil.
+func Janitor(ctx context.Context, dir string, interval time.Duration, onError func(error), opts ...Option) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := PurgeRemoved(dir, opts...); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// tombstone is a tombstone of a file.
+type tombstone struct {
+	name   string
+	expiry time.Time
+}
+
+// tombstoneName returns the name of the tombstone of the resolved name which expires at the expiry.
+// Tombstones are kept next to the alt files.
+func (c *config) tombstoneName(name string, expiry time.Time) string {
+	base := filepath.Base(name) + TombstonePostfix + strconv.FormatInt(expiry.Unix(), 10)
+	return filepath.Join(c.altDir(filepath.Dir(name)), base)
+}
+
+// tombstones returns the tombstones of the resolved name sorted by their expiry.
+func (c *config) tombstones(name string) ([]tombstone, error) {
+	dir := c.altDir(filepath.Dir(name))
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tombs []tombstone
+	for _, info := range infos {
+		owner, expiry, ok := isTombstone(info.Name())
+		if ok && owner == filepath.Base(name) {
+			tombs = append(tombs, tombstone{filepath.Join(dir, info.Name()), expiry})
+		}
+	}
+	sort.Slice(tombs, func(i, j int) bool { return tombs[i].expiry.Before(tombs[j].expiry) })
+	return tombs, nil
+}
+
+// isTombstone reports whether the name is the name of a tombstone and returns the name of the removed file
+// and the end of its retention.
+func isTombstone(name string) (string, time.Time, bool) {
+	i := strings.LastIndex(name, TombstonePostfix)
+	if i <= 0 {
+		return "", time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(name[i+len(TombstonePostfix):], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return name[:i], time.Unix(sec, 0), true
+}
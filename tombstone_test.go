@@ -0,0 +1,120 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRemoveFileAfter(t *testing.T) {
+	t.Run("should remove the file and restore it from the tombstone", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := RemoveFileAfter("testdir/testfile", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadFile("testdir/testfile", WithRetries(1)); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+
+		if err := Restore("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "data")
+		checkContents(t, "testdir/testfile.1", "data")
+		tombs, err := newConfig(nil).tombstones("testdir/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tombs) != 0 {
+			t.Errorf("expect the tombstone to be removed but got %v", tombs)
+		}
+	})
+
+	t.Run("should not restore over a new file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFileAfter("testdir/testfile", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFile("testdir/testfile", []byte("new data")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Restore("testdir/testfile"); !errors.Is(err, os.ErrExist) {
+			t.Errorf("expect os.ErrExist but got %v", err)
+		}
+		checkContents(t, "testdir/testfile", "new data")
+	})
+
+	t.Run("should return a NotExist error if there is no tombstone", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := Restore("testdir/testfile"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+}
+
+func TestPurgeRemoved(t *testing.T) {
+	t.Run("should delete the expired tombstones", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		for _, name := range []string{"testdir/a", "testdir/b"} {
+			if err := WriteFile(name, []byte("data")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := RemoveFileAfter("testdir/a", -time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFileAfter("testdir/b", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := PurgeRemoved("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("expect 1 purged tombstone but got %d", n)
+		}
+		if err := Restore("testdir/a"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+		if err := Restore("testdir/b"); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestJanitor(t *testing.T) {
+	t.Run("should purge the expired tombstones periodically", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/a", []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveFileAfter("testdir/a", -time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*SleepTime)
+		defer cancel()
+		Janitor(ctx, "testdir", SleepTime, func(err error) { t.Error(err) })
+
+		if err := Restore("testdir/a"); !os.IsNotExist(err) {
+			t.Errorf("expect a NotExist error but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package safe
+
+import "context"
+
+// Tracer starts the spans of WriteFile and ReadFile, e.g. to show the latency of the persistence of a configuration
+// in distributed traces. It is implemented with OpenTelemetry by a small adapter:
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, name string) (context.Context, safe.Span) {
+//		ctx, span := o.t.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttributes(attrs ...safe.Attribute) {
+//		for _, a := range attrs {
+//			switch v := a.Value.(type) {
+//			case string:
+//				s.Span.SetAttributes(attribute.String(a.Key, v))
+//			case int:
+//				s.Span.SetAttributes(attribute.Int(a.Key, v))
+//			}
+//		}
+//	}
+//
+//	func (s otelSpan) RecordError(err error) { s.Span.RecordError(err) }
+//
+//	func (s otelSpan) End() { s.Span.End() }
+type Tracer interface {
+	// Start starts a span with the name as a child of the span in the context.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span which was started by a Tracer.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is an attribute of a Span. The Value is a string or an int.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// The keys of the attributes of the spans.
+const (
+	// AttrPath is the name of the file as it was passed to the call.
+	AttrPath = "safe.path"
+	// AttrSize is the number of bytes which were read or passed to WriteFile.
+	AttrSize = "safe.size"
+	// AttrRetries is the number of retries of the call.
+	AttrRetries = "safe.retries"
+	// AttrStrategy is the Strategy which committed the file, or "custom" for a CommitStrategy. Only for writes.
+	AttrStrategy = "safe.strategy"
+	// AttrSync is the SyncPolicy of the write. Only for writes.
+	AttrSync = "safe.sync"
+)
+
+// WithTracer makes WriteFile and ReadFile record a span named "safe.WriteFile" or "safe.ReadFile" with the Tracer.
+// The span is a child of the span in the context of the call (see WriteFileContext).
+// It has the attributes AttrPath, AttrSize and AttrRetries, writes also AttrStrategy and AttrSync.
+// If the call fails, the error is recorded.
+func WithTracer(t Tracer) Option {
+	return func(c *config) {
+		c.tracer = t
+	}
+}
+
+// startSpan starts a span with the name if a Tracer is set and makes its context the context of the call.
+// It returns nil otherwise.
+func (c *config) startSpan(name string) Span {
+	if c.tracer == nil {
+		return nil
+	}
+	ctx, span := c.tracer.Start(c.ctx, name)
+	c.ctx = ctx
+	return span
+}
+
+// endSpan sets the attributes of the call of a file with the name, records the error and ends the span.
+func (c *config) endSpan(span Span, name string, size int, write bool, err error) {
+	attrs := []Attribute{{AttrPath, name}, {AttrSize, size}, {AttrRetries, c.retried}}
+	if write {
+		strategy := c.used.String()
+		if c.committer != nil {
+			strategy = "custom"
+		}
+		attrs = append(attrs, Attribute{AttrStrategy, strategy}, Attribute{AttrSync, c.syncPolicy().String()})
+	}
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,88 @@
+package safe
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// testSpan is a Span which records its attributes.
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+// testTracer is a Tracer which records its spans.
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &testSpan{name: name, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestWithTracer(t *testing.T) {
+	t.Run("should record a span for a write", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		tracer := &testTracer{}
+		if err := WriteFile("testfile", []byte("data"), WithTracer(tracer), WithStrategy(StrategyHardlink), WithSyncPolicy(SyncFull)); err != nil {
+			t.Fatal(err)
+		}
+		if len(tracer.spans) != 1 {
+			t.Fatalf("expect 1 span but got %d", len(tracer.spans))
+		}
+		s := tracer.spans[0]
+		if s.name != "safe.WriteFile" || !s.ended || s.err != nil {
+			t.Errorf("unexpected span %+v", s)
+		}
+		want := map[string]interface{}{
+			AttrPath:     "testfile",
+			AttrSize:     4,
+			AttrRetries:  0,
+			AttrStrategy: "hardlink",
+			AttrSync:     "full",
+		}
+		for k, v := range want {
+			if s.attrs[k] != v {
+				t.Errorf("expect %s to be %v but got %v", k, v, s.attrs[k])
+			}
+		}
+	})
+
+	t.Run("should record the retries and the error of a read", func(t *testing.T) {
+		tracer := &testTracer{}
+		if _, err := ReadFile("testfile", WithTracer(tracer), WithRetries(3)); !os.IsNotExist(err) {
+			t.Fatalf("expect a NotExist error but got %v", err)
+		}
+		if len(tracer.spans) != 1 {
+			t.Fatalf("expect 1 span but got %d", len(tracer.spans))
+		}
+		s := tracer.spans[0]
+		if s.name != "safe.ReadFile" || !os.IsNotExist(s.err) || s.attrs[AttrRetries] != 2 {
+			t.Errorf("unexpected span %+v", s)
+		}
+		if _, ok := s.attrs[AttrStrategy]; ok {
+			t.Errorf("expect no strategy for a read but got %v", s.attrs)
+		}
+	})
+}
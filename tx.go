@@ -0,0 +1,325 @@
+package safe
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TxJournalPrefix is the prefix of the name of the journal of a transaction. A copy of the journal is written
+// to every directory of the transaction and removed after the transaction was committed.
+const TxJournalPrefix = ".safe-tx"
+
+// Tx is a transaction which replaces the contents of several files together. Create a Tx with Begin.
+// The files are staged as temporary files by Write and committed by Commit.
+//
+//	tx := safe.Begin()
+//	if err := tx.Write("a.json", dataA); err != nil {
+//		tx.Rollback()
+//		return err
+//	}
+//	if err := tx.Write("b.json", dataB); err != nil {
+//		tx.Rollback()
+//		return err
+//	}
+//	return tx.Commit()
+type Tx struct {
+	opts []Option
+	m    *Manager
+
+	mu    sync.Mutex
+	files []*txFile
+	done  bool
+}
+
+// txFile is a file which was staged by a Tx.
+type txFile struct {
+	name string
+	path string
+	tmp  string
+	c    *config
+	data []byte
+	t    time.Time
+}
+
+// journal is the list of the staged files of a Tx which is written before the first file is committed.
+// The transaction is decided as soon as all copies of the journal exist.
+type journal struct {
+	Files []journalEntry `json:"files"`
+	// Copies are the absolute names of all copies of the journal.
+	Copies []string `json:"copies"`
+}
+
+// journalEntry is a staged file in the journal with absolute names.
+type journalEntry struct {
+	Name string `json:"name"`
+	Temp string `json:"temp"`
+}
+
+// Begin starts a transaction. The options apply to every file of the transaction.
+func Begin(opts ...Option) *Tx {
+	return &Tx{opts: opts}
+}
+
+// Write stages the data as the new contents of the file with the name. The file is not changed until Commit.
+// The options are applied after the options of the transaction. Writing the same name again replaces the staged data.
+func (tx *Tx) Write(name string, data []byte, opts ...Option) error {
+	all := make([]Option, 0, len(tx.opts)+len(opts))
+	all = append(all, tx.opts...)
+	all = append(all, opts...)
+	if tx.m != nil {
+		all = tx.m.options(name, all)
+	}
+	c := newConfig(all)
+	if err := c.writable("write", name); err != nil {
+		return err
+	}
+	path, err := c.path(name)
+	if err != nil {
+		return err
+	}
+	if err := c.checkReserved(path); err != nil {
+		return err
+	}
+	if err := c.prepareDir(path); err != nil {
+		return err
+	}
+	data, err = c.prepare(path, data)
+	if err != nil {
+		return err
+	}
+
+	f := &txFile{name: name, path: path, c: c, data: data, t: time.Now()}
+	f.tmp = c.tempName(path, f.t)
+	if err := c.writeTemp(path, f.tmp, data); err != nil {
+		os.Remove(f.tmp)
+		return err
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		os.Remove(f.tmp)
+		return &os.PathError{Op: "write", Path: name, Err: ErrTxDone}
+	}
+	for i, staged := range tx.files {
+		if staged.path == path {
+			os.Remove(staged.tmp)
+			tx.files[i] = f
+			return nil
+		}
+	}
+	tx.files = append(tx.files, f)
+	return nil
+}
+
+// Commit replaces the contents of all staged files. Before the first file is replaced,
+// a journal with the staged files is written to every directory of the transaction.
+// If the process is interrupted, Recover (or RecoverDir) on any of the directories completes the transaction
+// if all copies of the journal were written, or rolls it back otherwise.
+// Until then, some files may still have their old contents.
+// If Commit fails before the journal was written, no file is changed and the staged files are removed.
+func (tx *Tx) Commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if len(tx.files) == 0 {
+		return nil
+	}
+
+	// Lock the files in a fixed order, so two transactions with the same files can't deadlock.
+	files := tx.files
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	for _, f := range files {
+		_, unlock, err := f.c.begin(f.name)
+		if err != nil {
+			tx.discard()
+			return err
+		}
+		defer unlock()
+	}
+
+	staged := make([][2]string, 0, len(files))
+	for _, f := range files {
+		// The staged file may have been removed in the meantime, e.g. by RecoverDir.
+		if _, err := os.Stat(f.tmp); err != nil {
+			tx.discard()
+			return err
+		}
+		staged = append(staged, [2]string{f.path, f.tmp})
+	}
+	j, err := files[0].c.writeJournals(staged)
+	if err != nil {
+		tx.discard()
+		return err
+	}
+
+	// From here on, the transaction is decided. If a commit fails, the journal is kept for Recover.
+	for _, f := range files {
+		if err := f.c.commit(f.tmp, f.path, int64(len(f.data)), f.t, f.data); err != nil {
+			return err
+		}
+	}
+	tx.discard()
+	return removeJournal(j)
+}
+
+// writeJournals writes a journal of the staged files, which are pairs of a resolved name and its temporary file,
+// to the directory of every file. If a copy can not be written, the copies which were written are removed.
+func (c *config) writeJournals(staged [][2]string) (journal, error) {
+	var j journal
+	for _, s := range staged {
+		name, err := filepath.Abs(s[0])
+		if err != nil {
+			return j, err
+		}
+		tmp, err := filepath.Abs(s[1])
+		if err != nil {
+			return j, err
+		}
+		j.Files = append(j.Files, journalEntry{Name: name, Temp: tmp})
+	}
+	base := TxJournalPrefix + uniqueSuffix() + ".json"
+	dirs := make(map[string]bool)
+	for _, e := range j.Files {
+		if dir := filepath.Dir(e.Name); !dirs[dir] {
+			dirs[dir] = true
+			j.Copies = append(j.Copies, filepath.Join(dir, base))
+		}
+	}
+	for _, name := range j.Copies {
+		if err := c.writeJournal(name, j); err != nil {
+			removeJournal(j)
+			return j, err
+		}
+	}
+	return j, nil
+}
+
+// removeJournal removes all copies of the journal.
+func removeJournal(j journal) error {
+	var first error
+	for _, name := range j.Copies {
+		if err := remove(name); err != nil && first == nil {
+			first = err
+		}
+		if err := syncDir(filepath.Dir(name)); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Rollback removes the staged files. The files of the transaction are not changed.
+// Calling Rollback after Commit has no effect, so it can be deferred.
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return nil
+	}
+	tx.done = true
+	tx.discard()
+	return nil
+}
+
+// discard removes the staged files.
+func (tx *Tx) discard() {
+	for _, f := range tx.files {
+		os.Remove(f.tmp)
+	}
+}
+
+// writeJournal durably writes the journal to the resolved name.
+func (c *config) writeJournal(name string, j journal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	tmp := c.tempName(name, time.Now())
+	// The journal decides the transaction, so it is always synced.
+	if err := write(tmp, data, c.perm, (*os.File).Sync); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := replaceFile(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(name))
+}
+
+// isJournal reports whether the base name is the name of the journal of a transaction.
+func (c *config) isJournal(name string) bool {
+	if _, _, ok := c.isTemp(name); ok {
+		return false
+	}
+	return strings.HasPrefix(name, TxJournalPrefix) && strings.HasSuffix(name, ".json")
+}
+
+// recoverTx completes the transaction of the journal with the resolved name if all copies of the journal exist
+// and rolls it back otherwise. Then all copies of the journal are removed.
+// It returns the action which was taken, so an error is reported with it.
+func (c *config) recoverTx(name string, report *RecoveryReport) (RecoveryAction, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return ActionReadJournal, err
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return ActionReadJournal, &os.PathError{Op: "recover", Path: name, Err: &kindWrapError{err: err, kind: ErrCorrupt}}
+	}
+	for _, other := range j.Copies {
+		if _, err := os.Stat(other); err != nil {
+			return ActionRollBack, c.rollBack(j, report)
+		}
+	}
+	if err := c.rollForward(j, report); err != nil {
+		return ActionRollForward, err
+	}
+	return ActionRollForward, removeJournal(j)
+}
+
+// rollBack removes the staged files of the transaction of the journal and all copies of the journal.
+func (c *config) rollBack(j journal, report *RecoveryReport) error {
+	for _, e := range j.Files {
+		if _, err := os.Stat(e.Temp); os.IsNotExist(err) {
+			continue
+		}
+		if err := remove(e.Temp); err != nil {
+			return err
+		}
+		report.add(e.Name, ActionRollBack, nil)
+	}
+	return removeJournal(j)
+}
+
+// rollForward commits the staged files of the transaction of the journal.
+// Files whose staged file does not exist anymore were already committed.
+func (c *config) rollForward(j journal, report *RecoveryReport) error {
+	for _, e := range j.Files {
+		if _, err := os.Stat(e.Temp); os.IsNotExist(err) {
+			continue
+		}
+		s, err := c.strategy(e.Name)
+		if err == nil {
+			_, err = c.commitWith(s, e.Temp, c.altName(e.Name), e.Name)
+		}
+		if err == nil {
+			err = remove(e.Temp)
+		}
+		if err != nil {
+			return err
+		}
+		report.add(e.Name, ActionRollForward, nil)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTx(t *testing.T) {
+	t.Run("should commit all files", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/a", "old a")
+
+		tx := Begin(WithPrefix("testdir"))
+		if err := tx.Write("a", []byte("new a")); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Write("b", []byte("new b")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a", "old a")
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a", "new a")
+		checkContents(t, "testdir/b", "new b")
+
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 4 {
+			t.Errorf("expect only the files and their alt files but got %d files", len(infos))
+		}
+	})
+
+	t.Run("should not change the files on rollback", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		tx := Begin()
+		if err := tx.Write("testdir/a", []byte("new a")); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 0 {
+			t.Errorf("expect no files but got %d", len(infos))
+		}
+		if err := tx.Write("testdir/a", []byte("new a")); !errors.Is(err, ErrTxDone) {
+			t.Errorf("expect ErrTxDone but got %v", err)
+		}
+		if err := tx.Commit(); !errors.Is(err, ErrTxDone) {
+			t.Errorf("expect ErrTxDone but got %v", err)
+		}
+	})
+
+	t.Run("should use the last staged data of a name", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		tx := Begin()
+		for _, data := range []string{"first", "second"} {
+			if err := tx.Write("testdir/a", []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a", "second")
+	})
+}
+
+func TestRecoverDirTx(t *testing.T) {
+	t.Run("should complete an interrupted transaction", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/b", "old b")
+
+		c := newConfig(nil)
+		var j journal
+		for _, name := range []string{"testdir/a", "testdir/b"} {
+			tmp := c.tempName(name, time.Now().Add(-time.Hour))
+			if err := write(tmp, []byte("new"), c.perm, nil); err != nil {
+				t.Fatal(err)
+			}
+			abs, _ := filepath.Abs(name)
+			absTmp, _ := filepath.Abs(tmp)
+			j.Files = append(j.Files, journalEntry{Name: abs, Temp: absTmp})
+		}
+		abs, _ := filepath.Abs(filepath.Join("testdir", TxJournalPrefix+uniqueSuffix()+".json"))
+		j.Copies = []string{abs}
+		if err := c.writeJournal(abs, j); err != nil {
+			t.Fatal(err)
+		}
+		// The process was interrupted after the first file was committed.
+		if err := c.commit(j.Files[0].Temp, j.Files[0].Name, 3, time.Now(), []byte("new")); err != nil {
+			t.Fatal(err)
+		}
+		remove(j.Files[0].Temp)
+		checkContents(t, "testdir/b", "old b")
+
+		report, err := RecoverDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a", "new")
+		checkContents(t, "testdir/b", "new")
+		if len(report.Files) != 1 || report.Files[0].Action != ActionRollForward || report.Failed() {
+			t.Errorf("expect one roll forward but got %+v", report.Files)
+		}
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 4 {
+			t.Errorf("expect only the files and their alt files but got %d files", len(infos))
+		}
+	})
+
+	t.Run("should report the action which failed", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, filepath.Join("testdir", TxJournalPrefix+uniqueSuffix()+".json"), "{")
+
+		c := newConfig(nil)
+		// The staged file can not be removed, so the roll back fails.
+		tmp, _ := filepath.Abs(c.tempName("testdir/testfile", time.Now()))
+		createDir(t, tmp)
+		createFile(t, filepath.Join(tmp, "child"), "data")
+		abs, _ := filepath.Abs("testdir/testfile")
+		j := journal{
+			Files:  []journalEntry{{Name: abs, Temp: tmp}},
+			Copies: []string{filepath.Join(filepath.Dir(abs), TxJournalPrefix+"-1-00000000.json"), filepath.Join(filepath.Dir(abs), "missing.json")},
+		}
+		if err := c.writeJournal(j.Copies[0], j); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := RecoverDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions := make(map[RecoveryAction]bool)
+		for _, f := range report.Files {
+			if f.Error != "" {
+				actions[f.Action] = true
+			}
+		}
+		if len(actions) != 2 || !actions[ActionReadJournal] || !actions[ActionRollBack] {
+			t.Errorf("expect a failed read of the journal and a failed roll back but got %+v", report.Files)
+		}
+	})
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("should roll back a transaction whose journal is incomplete", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createDir(t, "testdir/a")
+		createDir(t, "testdir/b")
+		createFile(t, "testdir/a/testfile", "old a")
+
+		c := newConfig(nil)
+		var j journal
+		for _, name := range []string{"testdir/a/testfile", "testdir/b/testfile"} {
+			tmp := c.tempName(name, time.Now())
+			if err := write(tmp, []byte("new"), c.perm, nil); err != nil {
+				t.Fatal(err)
+			}
+			abs, _ := filepath.Abs(name)
+			absTmp, _ := filepath.Abs(tmp)
+			j.Files = append(j.Files, journalEntry{Name: abs, Temp: absTmp})
+			j.Copies = append(j.Copies, filepath.Join(filepath.Dir(abs), TxJournalPrefix+"-1-00000000.json"))
+		}
+		// The process was interrupted after the first copy of the journal was written.
+		if err := c.writeJournal(j.Copies[0], j); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := Recover("testdir/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a/testfile", "old a")
+		checkNotExist(t, "testdir/b/testfile")
+		if len(report.Files) != 2 || report.Files[0].Action != ActionRollBack || report.Files[1].Action != ActionRollBack {
+			t.Errorf("expect two roll backs but got %+v", report.Files)
+		}
+		for _, dir := range []string{"testdir/a", "testdir/b"} {
+			infos, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := map[string]int{"testdir/a": 1, "testdir/b": 0}[dir]; len(infos) != want {
+				t.Errorf("expect %d files in %s but got %d", want, dir, len(infos))
+			}
+		}
+	})
+
+	t.Run("should remove fresh temporary files", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		tmp := newConfig(nil).tempName("testdir/testfile", time.Now())
+		createFile(t, tmp, "some data")
+		report, err := Recover("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, tmp)
+		if len(report.Files) != 1 || report.Files[0].Action != ActionRemoveTemp {
+			t.Errorf("expect the temporary file to be removed but got %+v", report.Files)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package safe
+
+import (
+	"bytes"
+	"os"
+)
+
+// UpdateRetries is the number of times Update calls the callback again if the file changed concurrently.
+const UpdateRetries = 10
+
+// Update reads the current contents of the file with the name, passes them to the callback and writes the result.
+// If the file does not exist, the callback gets nil. If the callback returns an error, nothing is written and
+// the error is returned. If the file was replaced between the read and the write, the callback is called again
+// with the new contents, up to UpdateRetries times. After that, a *os.PathError wrapping ErrConflict is returned.
+// Update locks the file like WithLock while it checks and writes it, so updates within a process never lose changes.
+func Update(name string, fn func(old []byte) ([]byte, error), opts ...Option) error {
+	c := newConfig(opts)
+	c.locking = true
+
+	for i := 0; i <= UpdateRetries; i++ {
+		before, beforeErr := c.stat(name)
+		old, err := c.load(name)
+		if os.IsNotExist(err) {
+			old, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+		data, err := fn(old)
+		if err != nil {
+			return err
+		}
+
+		ok, err := c.writeIfUnchanged(name, data, before, beforeErr)
+		if ok || err != nil {
+			return err
+		}
+	}
+	return &os.PathError{Op: "update", Path: name, Err: ErrConflict}
+}
+
+// writeIfUnchanged writes the data to the file with the name if its state is still the one which was read before.
+// It reports whether the file was unchanged.
+func (c *config) writeIfUnchanged(name string, data []byte, before os.FileInfo, beforeErr error) (bool, error) {
+	resolved, unlock, err := c.begin(name)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	after, afterErr := c.stat(name)
+	if beforeErr != nil && !os.IsNotExist(beforeErr) {
+		return false, beforeErr
+	}
+	if changed(before, beforeErr, after, afterErr) {
+		return false, nil
+	}
+	data, err = c.prepare(resolved, data)
+	if err != nil {
+		return false, err
+	}
+	return true, c.replace(resolved, data)
+}
+
+// WriteFileIfChanged writes the data like WriteFile unless the file already has exactly these contents.
+// It reports whether the file was written. Skipping identical writes keeps the modification time, so watchers
+// and backups are not triggered by tools which rewrite the same contents. The comparison is made after
+// the transforms and the compression, so it compares the bytes which would be stored.
+func WriteFileIfChanged(name string, data []byte, opts ...Option) (bool, error) {
+	c := newConfig(opts)
+	resolved, unlock, err := c.begin(name)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	data, err = c.prepare(resolved, data)
+	if err != nil {
+		return false, err
+	}
+
+	old, err := c.read(resolved, c.altName(resolved))
+	if err == nil && bytes.Equal(old, data) {
+		return false, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, c.replace(resolved, data)
+}
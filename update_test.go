@@ -0,0 +1,112 @@
+package safe
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	t.Run("should not lose concurrent updates", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		increment := func(old []byte) ([]byte, error) {
+			n := 0
+			if old != nil {
+				var err error
+				if n, err = strconv.Atoi(string(old)); err != nil {
+					return nil, err
+				}
+			}
+			return []byte(strconv.Itoa(n + 1)), nil
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := Update("testfile", increment); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		checkContents(t, "testfile", "10")
+	})
+
+	t.Run("should call the callback again if the file changed", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		var calls []string
+		err := Update("testfile", func(old []byte) ([]byte, error) {
+			calls = append(calls, string(old))
+			if len(calls) == 1 {
+				if err := WriteFile("testfile", []byte("b")); err != nil {
+					return nil, err
+				}
+			}
+			return append(old, 'c'), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) != 2 || calls[1] != "b" {
+			t.Errorf("expect the callback to be called with a and b but got %v", calls)
+		}
+		checkContents(t, "testfile", "bc")
+	})
+
+	t.Run("should return the error of the callback", func(t *testing.T) {
+		defer clean(t, "testfile")
+		fail := errors.New("fail")
+
+		err := Update("testfile", func(old []byte) ([]byte, error) {
+			return nil, fail
+		})
+		if !errors.Is(err, fail) {
+			t.Errorf("expect %v but got %v", fail, err)
+		}
+		checkNotExist(t, "testfile")
+	})
+}
+
+func TestWriteFileIfChanged(t *testing.T) {
+	t.Run("should only write changed contents", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		written, err := WriteFileIfChanged("testfile", []byte("data"), WithRetries(1))
+		if err != nil || !written {
+			t.Fatalf("expect the file to be written but got %v, %v", written, err)
+		}
+		before, err := os.Stat("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		written, err = WriteFileIfChanged("testfile", []byte("data"))
+		if err != nil || written {
+			t.Fatalf("expect the file to be skipped but got %v, %v", written, err)
+		}
+		after, err := os.Stat("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !os.SameFile(before, after) {
+			t.Error("expect the file to stay untouched")
+		}
+
+		written, err = WriteFileIfChanged("testfile", []byte("new data"))
+		if err != nil || !written {
+			t.Fatalf("expect the file to be written but got %v, %v", written, err)
+		}
+		checkContents(t, "testfile", "new data")
+	})
+}
@@ -0,0 +1,97 @@
+package safe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// IssueKind describes an anomaly which was found by Verify.
+type IssueKind string
+
+const (
+	// IssueOrphanedTemp means that a temporary file is older than StaleTempAge,
+	// so it was left over by an interrupted write. RecoverDir removes it.
+	IssueOrphanedTemp IssueKind = "orphaned_temp"
+	// IssueOrphanedAlt means that an alt file exists without its name, so a write was interrupted
+	// or the name was removed by another program. RecoverDir or Repair restores the name.
+	IssueOrphanedAlt IssueKind = "orphaned_alt"
+	// IssueSingleLink means that the name has a link count of 1 although it has an alt file and the files
+	// are committed with StrategyHardlink, so the name and the alt file diverged. Repair reconciles them.
+	IssueSingleLink IssueKind = "single_link"
+	// IssuePermMismatch means that the name and its alt file have different permissions.
+	IssuePermMismatch IssueKind = "perm_mismatch"
+)
+
+// Issue is an anomaly of a file which was found by Verify.
+type Issue struct {
+	// Name is the name of the file with the anomaly.
+	Name string    `json:"name"`
+	Kind IssueKind `json:"kind"`
+}
+
+// String describes the Issue.
+func (i Issue) String() string {
+	return i.Name + ": " + string(i.Kind)
+}
+
+// Verify inspects the files in the directory and reports the anomalies of this package, e.g. to check
+// a directory after a crash before its files are trusted. Nothing is changed. The issues are sorted by name.
+// Files which have no alt file are not managed by this package and are only checked for orphaned temporary files.
+func Verify(dir string, opts ...Option) ([]Issue, error) {
+	c := newConfig(opts)
+	dir, err := c.path(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	alts, err := c.readAltDir(dir, infos)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.strategy(filepath.Join(dir, "verify"))
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	files := make(map[string]os.FileInfo, len(infos))
+	now := time.Now()
+	for _, info := range infos {
+		files[info.Name()] = info
+		if _, created, ok := c.isTemp(info.Name()); ok && now.Sub(created) > c.staleAge {
+			issues = append(issues, Issue{Name: filepath.Join(dir, info.Name()), Kind: IssueOrphanedTemp})
+		}
+	}
+	for _, alt := range alts {
+		if _, _, ok := c.isTemp(alt.Name()); ok || !alt.Mode().IsRegular() {
+			continue
+		}
+		base, ok := c.isAlt(alt.Name())
+		if !ok {
+			continue
+		}
+		name := filepath.Join(dir, base)
+		primary, ok := files[base]
+		if !ok {
+			issues = append(issues, Issue{Name: name, Kind: IssueOrphanedAlt})
+			continue
+		}
+		if !primary.Mode().IsRegular() {
+			continue
+		}
+		if _, links := inode(primary); links == 1 && s == StrategyHardlink {
+			issues = append(issues, Issue{Name: name, Kind: IssueSingleLink})
+		}
+		if primary.Mode().Perm() != alt.Mode().Perm() {
+			issues = append(issues, Issue{Name: name, Kind: IssuePermMismatch})
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Name < issues[j].Name })
+	return issues, nil
+}
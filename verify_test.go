@@ -0,0 +1,62 @@
+package safe
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	t.Run("should report no issues for consistent files", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/a", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/unmanaged", "some data")
+
+		issues, err := Verify("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != 0 {
+			t.Errorf("expect no issues but got %v", issues)
+		}
+	})
+
+	t.Run("should report the anomalies", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		temp := "testdir/a" + time.Now().Add(-time.Hour).Format(TimestampFormat)
+		createFile(t, temp, "stale data")
+		createFile(t, "testdir/b.1", "some data")
+		createFile(t, "testdir/c", "new data")
+		createFile(t, "testdir/c.1", "old data")
+		if err := WriteFile("testdir/d", []byte("d")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove("testdir/d.1"); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/d.1", "d")
+		if err := os.Chmod("testdir/d.1", 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		issues, err := Verify("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Issue{
+			{Name: temp, Kind: IssueOrphanedTemp},
+			{Name: "testdir/b", Kind: IssueOrphanedAlt},
+			{Name: "testdir/c", Kind: IssueSingleLink},
+			{Name: "testdir/d", Kind: IssueSingleLink},
+			{Name: "testdir/d", Kind: IssuePermMismatch},
+		}
+		if !reflect.DeepEqual(issues, want) {
+			t.Errorf("expect %v but got %v", want, issues)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package safe
+
+import "os"
+
+// Version identifies the contents of a file as returned by ReadVersioned.
+// It is the digest of the contents (see Digest), so it does not change if the same contents are written again.
+type Version string
+
+// NoVersion is the Version of a file which does not exist.
+// WriteFileIf with NoVersion only writes the file if it does not exist yet.
+const NoVersion Version = ""
+
+// ReadVersioned reads the file like ReadFile and returns the Version of its contents.
+func ReadVersioned(name string, opts ...Option) ([]byte, Version, error) {
+	c := newConfig(opts)
+	data, err := c.load(name)
+	if err != nil {
+		return nil, NoVersion, err
+	}
+	v, err := c.version(data)
+	if err != nil {
+		return nil, NoVersion, err
+	}
+	return data, v, nil
+}
+
+// WriteFileIf writes the file like WriteFile if its contents still have the version which was returned by
+// ReadVersioned. Otherwise, the file was modified in the meantime and a *os.PathError wrapping ErrConflict
+// is returned. The file is locked like with WithLock while the version is checked and the file is written,
+// so the check and the write are atomic within the process.
+func WriteFileIf(name string, data []byte, version Version, opts ...Option) error {
+	c := newConfig(opts)
+	c.locking = true
+	resolved, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current := NoVersion
+	old, err := c.loadLocked(resolved)
+	if err == nil {
+		if current, err = c.version(old); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if current != version {
+		return &os.PathError{Op: "write", Path: resolved, Err: ErrConflict}
+	}
+
+	data, err = c.prepare(resolved, data)
+	if err != nil {
+		return err
+	}
+	return c.replace(resolved, data)
+}
+
+// version returns the Version of the contents.
+func (c *config) version(data []byte) (Version, error) {
+	digest, err := Digest(c.hash, data)
+	return Version(digest), err
+}
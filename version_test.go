@@ -0,0 +1,46 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWriteFileIf(t *testing.T) {
+	t.Run("should write the file if the version matches", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("old data")); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		data, v, err := ReadVersioned("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "old data" {
+			t.Errorf("expect old data but got %s", data)
+		}
+		if err := WriteFileIf("testfile", []byte("new data"), v); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testfile", "new data")
+
+		if err := WriteFileIf("testfile", []byte("newer data"), v); !errors.Is(err, ErrConflict) {
+			t.Errorf("expect ErrConflict but got %v", err)
+		}
+		checkContents(t, "testfile", "new data")
+	})
+
+	t.Run("should only create the file with NoVersion if it does not exist", func(t *testing.T) {
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		if err := WriteFileIf("testfile", []byte("data"), NoVersion); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFileIf("testfile", []byte("other data"), NoVersion); !errors.Is(err, ErrConflict) {
+			t.Errorf("expect ErrConflict but got %v", err)
+		}
+		checkContents(t, "testfile", "data")
+	})
+}
@@ -0,0 +1,95 @@
+package safe
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// DefaultPollInterval is the interval in which Watch checks a file for changes if no other interval is set.
+const DefaultPollInterval = time.Second
+
+// Event is sent by Watch when the contents of a file changed.
+type Event struct {
+	// Name of the file as passed to Watch.
+	Name string
+	// Data is the new contents of the file.
+	Data []byte
+	// Err is set if the file could not be read, e.g. because it was removed.
+	Err error
+}
+
+// WithPollInterval sets the interval in which Watch checks a file for changes.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.pollInterval = d
+	}
+}
+
+// Watch sends the contents of the file with the name on the returned channel and sends them again
+// whenever the file is replaced or changed. The file is read like ReadFile, so writes with WriteFile
+// are never seen half-written. If the file can not be read, an Event with the error is sent once until
+// the file can be read again. The channel is closed when the context is done.
+func Watch(ctx context.Context, name string, opts ...Option) <-chan Event {
+	c := newConfig(opts)
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(c.pollInterval)
+		defer ticker.Stop()
+
+		var (
+			last    os.FileInfo
+			lastErr error
+			first   = true
+		)
+		for {
+			info, err := c.stat(name)
+			if first || changed(last, lastErr, info, err) {
+				first = false
+				last, lastErr = info, err
+
+				e := Event{Name: name}
+				if err == nil {
+					e.Data, e.Err = c.load(name)
+				} else {
+					e.Err = err
+				}
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// stat returns the FileInfo of the file with the name or $(name).1
+func (c *config) stat(name string) (os.FileInfo, error) {
+	name, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return os.Stat(c.altName(name))
+	}
+	return info, err
+}
+
+// changed reports whether the state of a file is different from the last state.
+func changed(last os.FileInfo, lastErr error, info os.FileInfo, err error) bool {
+	if err != nil || lastErr != nil {
+		return (err == nil) != (lastErr == nil)
+	}
+	return !os.SameFile(last, info) || !last.ModTime().Equal(info.ModTime()) || last.Size() != info.Size()
+}
@@ -0,0 +1,58 @@
+package safe
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	t.Run("should send the contents of the file initially and when it is replaced", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("first")); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := Watch(ctx, "testfile", WithPollInterval(time.Millisecond))
+		if e := <-events; e.Err != nil || string(e.Data) != "first" {
+			t.Fatalf("expect the initial contents but got %q (%v)", e.Data, e.Err)
+		}
+
+		if err := WriteFile("testfile", []byte("second")); err != nil {
+			t.Fatal(err)
+		}
+		if e := <-events; e.Err != nil || string(e.Data) != "second" {
+			t.Errorf("expect the new contents but got %q (%v)", e.Data, e.Err)
+		}
+	})
+
+	t.Run("should send an error if the file is removed", func(t *testing.T) {
+		if err := WriteFile("testfile", []byte("first")); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := Watch(ctx, "testfile", WithPollInterval(time.Millisecond))
+		<-events
+		if err := RemoveFile("testfile"); err != nil {
+			t.Fatal(err)
+		}
+		if e := <-events; !os.IsNotExist(e.Err) {
+			t.Errorf("expect NotExist error but got %v", e.Err)
+		}
+	})
+
+	t.Run("should close the channel when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		events := Watch(ctx, "testfile", WithPollInterval(time.Millisecond))
+		cancel()
+
+		for range events {
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package safe
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DirOp is the kind of change reported by WatchDir.
+type DirOp int
+
+const (
+	// DirAdded is sent for a file which was found when the watch started or was created later.
+	DirAdded DirOp = iota
+	// DirUpdated is sent when a file was replaced or changed.
+	DirUpdated
+	// DirRemoved is sent when a file and its alt file were removed.
+	DirRemoved
+)
+
+// Codec decodes the contents of a file into a value.
+type Codec interface {
+	Decode(data []byte) (interface{}, error)
+}
+
+// CodecFunc is a function which implements Codec.
+type CodecFunc func(data []byte) (interface{}, error)
+
+// Decode calls the function.
+func (f CodecFunc) Decode(data []byte) (interface{}, error) {
+	return f(data)
+}
+
+// JSONCodec returns a Codec which decodes JSON into a new value created by the newValue function,
+// e.g. func() interface{} { return new(Plugin) }.
+func JSONCodec(newValue func() interface{}) Codec {
+	return CodecFunc(func(data []byte) (interface{}, error) {
+		v := newValue()
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// DirEvent is sent by WatchDir when a file in the directory changed.
+type DirEvent struct {
+	Op DirOp
+	// Name of the file, joined with the directory as passed to WatchDir.
+	Name string
+	// Data is the new contents of the file. It is nil for DirRemoved.
+	Data []byte
+	// Value is the contents decoded by the Codec. It is nil if the Codec is nil or the file was removed.
+	Value interface{}
+	// Err is set if the file could not be read or decoded.
+	Err error
+}
+
+// WatchDir watches all managed files in the directory and sends a DirEvent whenever a file is added,
+// updated or removed, so plugin systems can follow a conf.d style directory.
+// When the watch starts, a DirAdded event is sent for every existing file.
+// Only files with an alt file are reported; temporary files, alt files, tombstones, the manifest and fence files
+// are hidden. Because a file is read with the fallback to its alt file, the steps of a replacement by WriteFile
+// are reported as a single DirUpdated event. The contents are decoded with the codec, which may be nil.
+// The directory is polled like with Watch (see WithPollInterval). The channel is closed when the context is done.
+func WatchDir(ctx context.Context, dir string, codec Codec, opts ...Option) <-chan DirEvent {
+	c := newConfig(opts)
+	events := make(chan DirEvent)
+
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(c.pollInterval)
+		defer ticker.Stop()
+
+		known := make(map[string]os.FileInfo)
+		for {
+			for _, e := range c.scanDir(dir, known, codec) {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// scanDir compares the managed files in the directory with the known files and returns the events of the changes.
+// The known files are updated.
+func (c *config) scanDir(dir string, known map[string]os.FileInfo, codec Codec) []DirEvent {
+	bases, err := c.managedFiles(dir)
+	if err != nil {
+		return []DirEvent{{Op: DirUpdated, Name: dir, Err: err}}
+	}
+
+	var events []DirEvent
+	seen := make(map[string]bool, len(bases))
+	for _, base := range bases {
+		name := filepath.Join(dir, base)
+		info, err := c.stat(name)
+		if err != nil {
+			// The file vanished after the directory was read; the next scan reports it.
+			continue
+		}
+		seen[name] = true
+		last, ok := known[name]
+		op := DirUpdated
+		if !ok {
+			op = DirAdded
+		} else if !changed(last, nil, info, nil) {
+			continue
+		}
+		known[name] = info
+
+		e := DirEvent{Op: op, Name: name}
+		e.Data, e.Err = c.load(name)
+		if e.Err == nil && codec != nil {
+			e.Value, e.Err = codec.Decode(e.Data)
+		}
+		events = append(events, e)
+	}
+	for name := range known {
+		if !seen[name] {
+			delete(known, name)
+			events = append(events, DirEvent{Op: DirRemoved, Name: name})
+		}
+	}
+	return events
+}
+
+// managedFiles returns the base names of the managed files in the directory.
+func (c *config) managedFiles(dir string) ([]string, error) {
+	resolved, err := c.path(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(resolved)
+	if err != nil {
+		return nil, err
+	}
+	alts, err := c.readAltDir(resolved, infos)
+	if err != nil {
+		return nil, err
+	}
+
+	var bases []string
+	for _, info := range alts {
+		if _, _, ok := c.isTemp(info.Name()); ok {
+			continue
+		}
+		base, ok := c.isAlt(info.Name())
+		if !ok || base == IndexName || strings.HasSuffix(base, FencePostfix) {
+			continue
+		}
+		if _, _, ok := c.isTemp(base); ok {
+			continue
+		}
+		bases = append(bases, base)
+	}
+	return bases, nil
+}
@@ -0,0 +1,64 @@
+package safe
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type plugin struct {
+	Name string `json:"name"`
+}
+
+func TestWatchDir(t *testing.T) {
+	t.Run("should send add, update and remove events with decoded values", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		if err := WriteFile("testdir/a.json", []byte(`{"name":"a"}`), WithIndex()); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, "testdir/unmanaged", "data")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		codec := JSONCodec(func() interface{} { return new(plugin) })
+		events := WatchDir(ctx, "testdir", codec, WithPollInterval(SleepTime))
+
+		next := func() DirEvent {
+			select {
+			case e := <-events:
+				return e
+			case <-time.After(time.Second):
+				t.Fatal("expect an event")
+				return DirEvent{}
+			}
+		}
+
+		e := next()
+		if e.Op != DirAdded || e.Name != "testdir/a.json" || e.Err != nil || e.Value.(*plugin).Name != "a" {
+			t.Errorf("unexpected event %+v", e)
+		}
+
+		// Make sure the modification time changes.
+		time.Sleep(SleepTime)
+		if err := WriteFile("testdir/a.json", []byte(`{"name":"b"}`)); err != nil {
+			t.Fatal(err)
+		}
+		e = next()
+		if e.Op != DirUpdated || e.Err != nil || e.Value.(*plugin).Name != "b" {
+			t.Errorf("unexpected event %+v", e)
+		}
+
+		if err := RemoveFile("testdir/a.json"); err != nil {
+			t.Fatal(err)
+		}
+		e = next()
+		if e.Op != DirRemoved || e.Name != "testdir/a.json" {
+			t.Errorf("unexpected event %+v", e)
+		}
+
+		cancel()
+		for range events {
+		}
+	})
+}
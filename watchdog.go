@@ -0,0 +1,90 @@
+package safe
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Watchdog tracks how often files are written and detects files which are rewritten too often,
+// e.g. because two processes keep overwriting each other's changes.
+// A Watchdog is safe for concurrent use and can be shared by several calls with WithWatchdog.
+type Watchdog struct {
+	limit       int
+	window      time.Duration
+	onThrashing func(name string, writes int)
+
+	mu     sync.Mutex
+	writes map[string][]time.Time
+	// swept is the time when the files without writes within the window were last dropped.
+	swept time.Time
+}
+
+// NewWatchdog creates a Watchdog which allows up to limit writes of a file within the window.
+// If a file is written more often, the onThrashing callback is called with the name and the number of writes
+// within the window and the write continues. If onThrashing is nil, the write fails with ErrThrashing instead.
+// A write which fails with ErrThrashing is not counted, so a retry succeeds once the earlier writes left the window.
+func NewWatchdog(limit int, window time.Duration, onThrashing func(name string, writes int)) *Watchdog {
+	return &Watchdog{
+		limit:       limit,
+		window:      window,
+		onThrashing: onThrashing,
+		writes:      make(map[string][]time.Time),
+	}
+}
+
+// WithWatchdog makes WriteFile report each write to the Watchdog.
+func WithWatchdog(w *Watchdog) Option {
+	return func(c *config) {
+		c.watchdog = w
+	}
+}
+
+// observe records a write of the file with the name.
+func (w *Watchdog) observe(name string) error {
+	n := w.record(name, time.Now())
+	if n <= w.limit {
+		return nil
+	}
+	if w.onThrashing != nil {
+		w.onThrashing(name, n)
+		return nil
+	}
+	return &os.PathError{Op: "write", Path: name, Err: ErrThrashing}
+}
+
+// record adds the write at the time t and returns the number of writes within the window including it.
+// A write which is rejected with ErrThrashing is not added, so retries after a rejection do not extend it.
+func (w *Watchdog) record(name string, t time.Time) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Drop the writes which are outside of the window.
+	writes := w.writes[name]
+	i := 0
+	for i < len(writes) && t.Sub(writes[i]) >= w.window {
+		i++
+	}
+	writes = writes[i:]
+	n := len(writes) + 1
+	if n <= w.limit || w.onThrashing != nil {
+		writes = append(writes, t)
+	}
+	if len(writes) > 0 {
+		w.writes[name] = writes
+	} else {
+		delete(w.writes, name)
+	}
+
+	// Drop the files which were not written within the window once per window,
+	// so the Watchdog does not grow with every file it has ever seen.
+	if t.Sub(w.swept) >= w.window {
+		for n, writes := range w.writes {
+			if t.Sub(writes[len(writes)-1]) >= w.window {
+				delete(w.writes, n)
+			}
+		}
+		w.swept = t
+	}
+	return n
+}
@@ -0,0 +1,79 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	t.Run("should return ErrThrashing if a file is written too often", func(t *testing.T) {
+		w := NewWatchdog(2, time.Minute, nil)
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		for i := 0; i < 2; i++ {
+			if err := WriteFile("testfile", []byte("data"), WithWatchdog(w)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := WriteFile("testfile", []byte("new data"), WithWatchdog(w))
+		if !errors.Is(err, ErrThrashing) {
+			t.Errorf("expect ErrThrashing but got %v", err)
+		}
+		checkContents(t, "testfile", "data")
+	})
+
+	t.Run("should not count a rejected write", func(t *testing.T) {
+		w := NewWatchdog(1, time.Minute, nil)
+		now := time.Now()
+
+		w.record("testfile", now.Add(-50*time.Second))
+		if n := w.record("testfile", now.Add(-20*time.Second)); n != 2 {
+			t.Errorf("expect the rejected write to be counted as the second write but got %d", n)
+		}
+		// Only the first write is within the window, so the retry is allowed once it has left.
+		if n := w.record("testfile", now.Add(15*time.Second)); n != 1 {
+			t.Errorf("expect the rejected write to be forgotten but got %d writes", n)
+		}
+	})
+
+	t.Run("should call the callback and continue the write if a file is written too often", func(t *testing.T) {
+		var got int
+		w := NewWatchdog(1, time.Minute, func(name string, writes int) {
+			got = writes
+		})
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		for i := 0; i < 2; i++ {
+			if err := WriteFile("testfile", []byte("data"), WithWatchdog(w)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got != 2 {
+			t.Errorf("expect the callback to be called with 2 writes but got %d", got)
+		}
+	})
+
+	t.Run("should forget writes which are outside of the window", func(t *testing.T) {
+		w := NewWatchdog(1, time.Minute, nil)
+		now := time.Now()
+
+		w.record("testfile", now.Add(-2*time.Minute))
+		if n := w.record("testfile", now); n != 1 {
+			t.Errorf("expect 1 write within the window but got %d", n)
+		}
+	})
+
+	t.Run("should drop the files which were not written within the window", func(t *testing.T) {
+		w := NewWatchdog(1, time.Minute, nil)
+		now := time.Now()
+
+		w.record("a", now.Add(-2*time.Minute))
+		w.record("b", now)
+		if _, ok := w.writes["a"]; ok || len(w.writes) != 1 {
+			t.Errorf("expect only the recent file to be tracked but got %v", w.writes)
+		}
+	})
+}
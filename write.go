@@ -3,6 +3,7 @@ package safe
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -19,13 +20,56 @@ const SleepTime = 10 * time.Millisecond
 const DefaultPerm = 0700
 
 // RemoveFile deletes the file with the name or $(name).1
-// NotExist errors are ignored.
-func RemoveFile(name string) error {
-	alt := name + AltNamePostfix
-	if err := remove(name); err != nil {
+// The temporary files which were left over by interrupted writes of the file, the versions of StrategySymlink
+// and WriteFileVersioned, the backup of WithBackup, the sidecar of WithChecksum and the fencing token of
+// WithFencingToken are removed as well, so no copies of the contents remain. A concurrent write of the file fails.
+// NotExist errors are ignored. If a file can not be removed, the others are still removed.
+// If several files fail, a *MultiError with the error of each of them is returned.
+func RemoveFile(name string, opts ...Option) error {
+	c := newConfig(opts)
+	if err := c.writable("remove", name); err != nil {
 		return err
 	}
-	return remove(alt)
+	name, err := c.path(name)
+	if err != nil {
+		return err
+	}
+	alt := c.altName(name)
+	names := []string{name, alt}
+	if c.backup != "" {
+		names = append(names, name+c.backup)
+	}
+	if c.checksum {
+		names = append(names, name+ChecksumPostfix)
+	}
+	if c.backend != nil {
+		if err := c.removeBackend(names); err != nil {
+			return err
+		}
+		return c.auditRemove(name)
+	}
+	names = append(names, name+FencePostfix, name+FencePostfix+AltNamePostfix)
+	var errs MultiError
+	for _, n := range names {
+		if err := remove(n); err != nil {
+			errs.add(n, err)
+		}
+	}
+	for _, n := range []string{name, alt} {
+		c.removeTemps(n, &errs)
+	}
+	if err := pruneVersions(name, "", ""); err != nil && !os.IsNotExist(err) {
+		errs.add(name, err)
+	}
+	if c.index {
+		if err := c.removeFromIndex(name); err != nil {
+			errs.add(filepath.Join(filepath.Dir(name), IndexName), err)
+		}
+	}
+	if err := errs.single(); err != nil {
+		return err
+	}
+	return c.auditRemove(name)
 }
 
 // remove a file but ignore NotExist errors
@@ -38,99 +82,474 @@ func remove(name string) error {
 }
 
 // ReadFile reads the contents of the file with the name or $(name).1
-// It automatically retries three times if the files don't exist in case they are replaced concurrently.
-func ReadFile(name string) ([]byte, error) {
-	alt := name + AltNamePostfix
+// It automatically retries DefaultRetries times if the files don't exist in case they are replaced concurrently.
+func ReadFile(name string, opts ...Option) ([]byte, error) {
+	c := newConfig(opts)
+	start := time.Now()
+	span := c.startSpan("safe.ReadFile")
+	data, err := c.readFile(name)
+	if span != nil {
+		c.endSpan(span, name, len(data), false, err)
+	}
+	if c.sampled() {
+		c.measure("read", name, len(data), start, err)
+	}
+	if err != nil {
+		c.observe(EventError, name, name, start, err)
+	}
+	return data, err
+}
+
+// readFile reads the file with the name and applies the includes and resolvers.
+func (c *config) readFile(name string) ([]byte, error) {
 	var (
 		data []byte
 		err  error
 	)
+	if c.includes {
+		data, err = readMerged(name, c)
+	} else {
+		data, err = c.load(name)
+	}
+	if err != nil || len(c.resolvers) == 0 {
+		return data, err
+	}
+	return c.resolveRefs(name, data)
+}
 
-	for i := 0; i < 3; i++ {
+// load reads the file with the name and decompresses it if decompression is enabled.
+func (c *config) load(name string) ([]byte, error) {
+	name, err := c.path(name)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := c.flock(name, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return c.loadLocked(name)
+}
+
+// loadLocked reads the resolved name like load while the caller already holds the lock of WithFlock,
+// e.g. the exclusive lock taken by begin. Taking the shared lock again would wait for the caller itself.
+func (c *config) loadLocked(name string) ([]byte, error) {
+	data, err := c.readContents(name)
+	if err == nil && c.checksum {
+		data, err = c.verifyChecksum(name, data)
+	}
+	if err != nil || !c.decompress {
+		return data, err
+	}
+	return decompress(name, data, c.maxDecompressedSize)
+}
+
+// readContents reads the resolved name or its alt name from the storage.
+func (c *config) readContents(name string) ([]byte, error) {
+	if c.backend != nil {
+		return c.readBackend(name, c.altName(name))
+	}
+	if c.coalesce {
+		return c.readShared(name, c.altName(name))
+	}
+	return c.read(name, c.altName(name))
+}
+
+// read the contents of the file with the name or the alt name and retry if neither exists.
+// The retries stop when the context of the call is done.
+func (c *config) read(name string, alt string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	for i := 0; i < c.retries; i++ {
 		data, err = ioutil.ReadFile(name)
 		if !os.IsNotExist(err) {
 			return data, err
 		}
+		start := time.Now()
 		data, err = ioutil.ReadFile(alt)
 		if !os.IsNotExist(err) {
+			c.observe(EventAltRead, name, alt, start, err)
 			return data, err
 		}
 
-		time.Sleep(SleepTime)
+		if i == c.retries-1 {
+			break
+		}
+		if err := c.wait(name, i+1); err != nil {
+			return nil, err
+		}
 	}
 
-	return data, err
+	// Report the name instead of the alt name, which was read last.
+	return data, &os.PathError{Op: "read", Path: name, Err: ErrNotExist}
 }
 
 // WriteFile writes data to a file with the provided name.
 // If possible, this method should not be executed concurrently for the same file.
 // This method also creates a temporary file which is deleted immediately after the write is complete.
 // It also creates a file $(name).1 which is used to make the write/update interrupt safe.
-func WriteFile(name string, data []byte) error {
+func WriteFile(name string, data []byte, opts ...Option) error {
+	c := newConfig(opts)
+	start := time.Now()
+	span := c.startSpan("safe.WriteFile")
+	err := c.writeFile(name, data)
+	if span != nil {
+		c.endSpan(span, name, len(data), true, err)
+	}
+	if c.sampled() {
+		c.measure("write", name, len(data), start, err)
+	}
+	if err != nil {
+		c.observe(EventError, name, name, start, err)
+	}
+	return err
+}
+
+// writeFile prepares the data and replaces the contents of the file with the name.
+func (c *config) writeFile(name string, data []byte) error {
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	data, err = c.prepare(name, data)
+	if err != nil {
+		return err
+	}
+	return c.replace(name, data)
+}
+
+// replace writes the data to a temporary file and commits it to the resolved name.
+func (c *config) replace(name string, data []byte) error {
 	t := time.Now()
 
-	tmp := name + t.Format(TimestampFormat)
-	alt := name + AltNamePostfix
+	tmp := c.tempName(name, t)
+
+	err := c.writeTemp(name, tmp, data)
+	defer c.removeTemp(name, tmp)
+	if err != nil {
+		return err
+	}
+	// The write can be canceled until the commit starts. The commit itself is short and must not be interrupted.
+	if err := c.ctx.Err(); err != nil {
+		return &os.PathError{Op: "write", Path: name, Err: err}
+	}
+	return c.commit(tmp, name, int64(len(data)), t, data)
+}
+
+// begin resolves the name of a file which is about to be written, checks whether it may be written and locks it.
+// The returned function releases the lock and must be called when the write is complete.
+func (c *config) begin(name string) (string, func(), error) {
+	if err := c.writable("write", name); err != nil {
+		return "", nil, err
+	}
+	name, err := c.path(name)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := c.checkReserved(name); err != nil {
+		return "", nil, err
+	}
+	unlock, err := c.lock(name)
+	if err != nil {
+		return "", nil, err
+	}
+	if c.watchdog != nil {
+		if err := c.watchdog.observe(name); err != nil {
+			unlock()
+			return "", nil, err
+		}
+	}
+	if err := c.prepareDir(name); err != nil {
+		unlock()
+		return "", nil, err
+	}
+	if err := c.prepareShadow(name); err != nil {
+		unlock()
+		return "", nil, err
+	}
+	funlock, err := c.flock(name, true)
+	if err != nil {
+		unlock()
+		return "", nil, err
+	}
+	return name, func() {
+		funlock()
+		unlock()
+	}, nil
+}
+
+// commit links the completely written tmpname to the name and its alt name.
+// The size and the data are the contents of the tmpname and are used for WithAssertCommitted, WithWriteStats,
+// WithIndex, WithChecksum and WithAudit.
+func (c *config) commit(tmpname string, name string, size int64, t time.Time, data []byte) error {
+	start := time.Now()
+	staged, err := c.stageBackup(name, t)
+	if err == nil {
+		err = c.finishBackup(name, staged, c.commitChecked(tmpname, name, size, t, data))
+	}
+	c.observe(EventCommitted, name, tmpname, start, err)
+	if err != nil {
+		return err
+	}
+	return c.audit(name, data)
+}
+
+// commitTemp commits the tmpname to the name like commit.
+func (c *config) commitTemp(tmpname string, name string, size int64, t time.Time, data []byte) error {
+	alt := c.altName(name)
+	old := c.previous(name)
+	// The fence, the times, the assertion and the index rely on the operating system.
+	onOS := c.backend == nil
+	if c.fenced && onOS {
+		unlock, err := claimFence(name, c.fencingToken)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	if c.reproducible && onOS {
+		t = reproducibleTime()
+		if err := os.Chtimes(tmpname, t, t); err != nil {
+			return err
+		}
+	}
+	strategy := StrategyHardlink
+	if c.committer != nil {
+		if err := c.committer.Commit(tmpname, alt, name); err != nil {
+			return err
+		}
+	} else {
+		s, err := c.strategy(name)
+		if err != nil {
+			return err
+		}
+		strategy, err = c.commitWith(s, tmpname, alt, name)
+		c.used = strategy
+		if err != nil {
+			return err
+		}
+	}
+	if c.dirSync >= DirSyncParent {
+		if err := c.syncDirs(name, alt); err != nil {
+			return err
+		}
+	}
+	if c.assertCommitted && onOS && c.committer == nil && strategy == StrategyHardlink {
+		if err := assertCommitted(tmpname, size, alt, name); err != nil {
+			return err
+		}
+	}
+	if c.writeStats {
+		c.recordWrite(name, old, data)
+	}
+	if c.index && onOS {
+		return c.addToIndex(name, data, t)
+	}
+	return nil
+}
 
-	err := write(tmp, data)
-	defer os.Remove(tmp)
+// assertCommitted checks that all names point to the tmpname and have the expected size.
+func assertCommitted(tmpname string, size int64, names ...string) error {
+	want, err := os.Stat(tmpname)
 	if err != nil {
 		return err
 	}
-	return safelink(tmp, alt, name)
+	for _, name := range names {
+		got, err := os.Stat(name)
+		if err != nil || !os.SameFile(want, got) || got.Size() != size {
+			return &os.PathError{Op: "assert committed", Path: name, Err: ErrCommitIncomplete}
+		}
+	}
+	return nil
 }
 
 // safelink creates hard links from the tmpname to the altname and from the altname to the name.
 // In case a previous process was interrupted, the altname is first linked to the name.
 // This complicated procedure makes sure that even if a process is interrupted before creating the link to the name,
 // the the contents of the file are never lost.
-func safelink(tmpname string, altname string, name string) error {
+// In strict mode, the result of each step is verified on the operating system. If report is not nil,
+// it is called with the alt link and with the links which were skipped. The files are linked in the Backend.
+func safelink(b Backend, tmpname string, altname string, name string, strict bool, report func(EventKind, string, time.Time, error)) error {
+	link := func(oldname string, newname string) error {
+		start := time.Now()
+		skipped, err := skippableLink(b, oldname, newname)
+		if skipped != nil && report != nil {
+			report(EventLinkSkipped, newname, start, skipped)
+		}
+		return err
+	}
+	// Only the links of the operating system can be inspected.
+	_, onOS := b.(OSBackend)
+	strict = strict && onOS
+
 	// Attempt final link in case a previous process was interrupted before the final link.
+	recovering := onOS && interrupted(altname, name)
 	if err := link(altname, name); err != nil {
 		return err
 	}
+	if recovering {
+		recordRecovery(name)
+	}
+	if strict {
+		if err := verifyPrevious(altname, name); err != nil {
+			return err
+		}
+	}
 	// Do alt link from tmp file.
-	if err := link(tmpname, altname); err != nil {
+	start := time.Now()
+	err := link(tmpname, altname)
+	if report != nil {
+		report(EventAltLinked, altname, start, err)
+	}
+	if err != nil {
 		return err
 	}
+	if strict {
+		if err := verifyLink(tmpname, altname); err != nil {
+			return err
+		}
+	}
 	// Do final link.
 	if err := link(altname, name); err != nil {
 		return err
 	}
+	if strict {
+		if err := verifyLink(tmpname, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interrupted reports whether a previous write was interrupted before the altname was linked to the name.
+func interrupted(altname string, name string) bool {
+	alt, err := os.Lstat(altname)
+	if err != nil {
+		return false
+	}
+	got, err := os.Lstat(name)
+	return err != nil || !os.SameFile(alt, got)
+}
+
+// verifyPrevious verifies the link from a previous altname to the name.
+// If the altname does not exist, there was nothing to recover.
+func verifyPrevious(altname string, name string) error {
+	if _, err := os.Lstat(altname); os.IsNotExist(err) {
+		return nil
+	}
+	return verifyLink(altname, name)
+}
+
+// verifyLink checks that the newname is a regular file which refers to the same inode as the oldname.
+func verifyLink(oldname string, newname string) error {
+	want, err := os.Lstat(oldname)
+	if err != nil {
+		return &os.LinkError{Op: "verify", Old: oldname, New: newname, Err: err}
+	}
+	got, err := os.Lstat(newname)
+	if err != nil {
+		return &os.LinkError{Op: "verify", Old: oldname, New: newname, Err: err}
+	}
+	if !got.Mode().IsRegular() {
+		return &os.LinkError{Op: "verify", Old: oldname, New: newname, Err: ErrNotRegular}
+	}
+	if !os.SameFile(want, got) {
+		return &os.LinkError{Op: "verify", Old: oldname, New: newname, Err: ErrLinkMismatch}
+	}
 	return nil
 }
 
 // link the oldname to the newname.
 func link(oldname string, newname string) error {
-	err := os.Remove(newname)
+	_, err := skippableLink(OSBackend{}, oldname, newname)
+	return err
+}
+
+// skippableLink links the oldname to the newname in the Backend like link and returns the NotExist or Exist error
+// which was ignored because the link was skipped.
+func skippableLink(b Backend, oldname string, newname string) (skipped error, err error) {
+	err = b.Remove(newname)
 	// Ignore NotExist errors in case this is the first time the link is created.
 	if err != nil && !os.IsNotExist(err) {
-		return err
+		return nil, err
 	}
 
-	err = os.Link(oldname, newname)
+	err = b.Link(oldname, newname)
 	if os.IsNotExist(err) || os.IsExist(err) {
 		// Link was concurrently created or alt link was concurrently deleted or alt link never existed.
-		return nil
+		return err, nil
 	}
 
+	return nil, err
+}
+
+// tempName returns the name of the temporary file for a write of the resolved name at the time t.
+func (c *config) tempName(name string, t time.Time) string {
+	dir := c.tempDir
+	if dir == "" {
+		dir = filepath.Dir(name)
+	}
+	return filepath.Join(dir, c.namer.TempName(filepath.Base(name), t))
+}
+
+// writeTemp writes the data to the temporary file tmp of the resolved name and reports it to the observers.
+func (c *config) writeTemp(name string, tmp string, data []byte) error {
+	start := time.Now()
+	err := c.createTemp(name, tmp, data)
+	c.observe(EventTempCreated, name, tmp, start, err)
 	return err
 }
 
-// write data to a new file described by the name with the provided mode.
-func write(name string, data []byte) error {
-	f, err := os.Create(name)
+// createTemp writes the data to the temporary file tmp and syncs it. Where O_TMPFILE is supported,
+// the file only gets its name after it was completely written and synced,
+// so a write which is interrupted before the commit leaves no temporary file behind.
+func (c *config) createTemp(name string, tmp string, data []byte) error {
+	if c.backend != nil {
+		return c.createBackendTemp(name, tmp, data)
+	}
+	sync := c.observedSync(name, tmp)
+	f, err := openAnonymous(filepath.Dir(tmp), c.perm)
+	if err != nil {
+		return write(tmp, data, c.perm, sync)
+	}
+	defer f.Close()
+
+	if err := f.Chmod(c.perm); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := sync(f); err != nil {
+		return err
+	}
+	if err := linkAnonymous(f, tmp); err != nil {
+		return write(tmp, data, c.perm, sync)
+	}
+	return nil
+}
+
+// write data to a new file described by the name with the provided mode and sync it with the function if it is not nil.
+func write(name string, data []byte, perm os.FileMode, sync func(*os.File) error) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	if err := f.Chmod(DefaultPerm); err != nil {
+	if err := f.Chmod(perm); err != nil {
 		return err
 	}
 	if _, err := f.Write(data); err != nil {
 		return err
 	}
+	if sync == nil {
+		return nil
+	}
 
-	return f.Sync()
+	return sync(f)
 }
@@ -1,6 +1,7 @@
 package safe
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -96,33 +97,33 @@ func TestReadFile(t *testing.T) {
 	})
 
 	t.Run("should automatically retry if testfile and testfile.1 do not exist and return when they are found within three intervals of 10ms", func(t *testing.T) {
-		finishRead := make(chan bool)
 		finishWrite := make(chan bool)
 
-		go func() {
-			checkNotExist(t, "testfile")
-
-			got, err := ReadFile("testfile")
-			if err != nil {
-				t.Fatal(err)
-			}
-			if string(got) != "some important data" {
-				t.Errorf("ReadFile does not return the correct file contents. Want %q but got %q", "some important data", got)
-			}
-
-			finishRead <- true
-		}()
-
+		// Clean up after the write is done, so the file is not removed while the next test writes it.
+		defer clean(t, "testfile.1")
 		go func() {
 			time.Sleep(10 * time.Millisecond)
-			createFile(t, "testfile.1", "some important data")
-			defer clean(t, "testfile.1")
+			// Create the file under another name first, so the reader never sees it empty.
+			createFile(t, "testfile.1.tmp", "some important data")
+			if err := os.Rename("testfile.1.tmp", "testfile.1"); err != nil {
+				t.Error(err)
+			}
 
 			finishWrite <- true
 		}()
+		defer func() {
+			<-finishWrite
+		}()
+
+		checkNotExist(t, "testfile")
 
-		<-finishRead
-		<-finishWrite
+		got, err := ReadFile("testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "some important data" {
+			t.Errorf("ReadFile does not return the correct file contents. Want %q but got %q", "some important data", got)
+		}
 	})
 }
 
@@ -159,6 +160,58 @@ func TestRemoveFile(t *testing.T) {
 		checkNotExist(t, "testfile")
 		checkNotExist(t, "testfile.1")
 	})
+
+	t.Run("should try to remove testfile.1 if testfile can not be removed", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createDir(t, "testdir/testfile")
+		createFile(t, "testdir/testfile/somefile", "")
+		createFile(t, "testdir/testfile.1", "")
+
+		var pathErr *os.PathError
+		if err := RemoveFile("testdir/testfile"); !errors.As(err, &pathErr) {
+			t.Errorf("expect a *os.PathError but got %v", err)
+		}
+		checkNotExist(t, "testdir/testfile.1")
+	})
+
+	t.Run("should report every file which can not be removed", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		for _, name := range []string{"testdir/testfile", "testdir/testfile.1"} {
+			createDir(t, name)
+			createFile(t, name+"/somefile", "")
+		}
+
+		err := RemoveFile("testdir/testfile")
+		var multi *MultiError
+		if !errors.As(err, &multi) {
+			t.Fatalf("expect a *MultiError but got %v", err)
+		}
+		if failed := multi.Failed(); len(failed) != 2 {
+			t.Errorf("expect 2 failed files but got %v", failed)
+		}
+	})
+
+	t.Run("should remove the temporary files of testfile", func(t *testing.T) {
+		createDir(t, "testdir")
+		defer clean(t, "testdir")
+		createFile(t, "testdir/testfile", "")
+		createFile(t, "testdir/testfile"+time.Now().Format(TimestampFormat), "secret")
+		createFile(t, "testdir/testfile.1"+time.Now().Format(TimestampFormat), "secret")
+		createFile(t, "testdir/other", "")
+
+		if err := RemoveFile("testdir/testfile"); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 1 || infos[0].Name() != "other" {
+			t.Errorf("expect only the other file but got %d files", len(infos))
+		}
+	})
 }
 
 func TestWriteFile(t *testing.T) {
@@ -197,4 +250,134 @@ func TestWriteFile(t *testing.T) {
 			t.Error(fmt.Errorf("expect NotExist error but got %e", err))
 		}
 	})
+	t.Run("should verify the links in strict mode", func(t *testing.T) {
+		err := WriteFile("testfile", []byte("data to be overwritten"), WithStrict())
+		if err != nil {
+			t.Error(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		err = WriteFile("testfile", []byte("new data"), WithStrict())
+		if err != nil {
+			t.Error(err)
+		}
+
+		checkContents(t, "testfile", "new data")
+		checkContents(t, "testfile.1", "new data")
+	})
+
+	t.Run("should not return an error if the commit is asserted", func(t *testing.T) {
+		err := WriteFile("testfile", []byte("important contents"), WithAssertCommitted())
+		if err != nil {
+			t.Error(err)
+		}
+		defer clean(t, "testfile")
+		defer clean(t, "testfile.1")
+
+		checkContents(t, "testfile", "important contents")
+	})
+
+	t.Run("should return ErrReservedName if the name is an alt name", func(t *testing.T) {
+		err := WriteFile("testfile.1", []byte("some data"))
+		if !errors.Is(err, ErrReservedName) {
+			t.Errorf("expect ErrReservedName but got %v", err)
+		}
+		checkNotExist(t, "testfile.1")
+	})
+
+	t.Run("should return ErrReservedName if the name is the name of a temporary file", func(t *testing.T) {
+		err := WriteFile("testfile"+time.Now().Format(TimestampFormat), []byte("some data"))
+		if !errors.Is(err, ErrReservedName) {
+			t.Errorf("expect ErrReservedName but got %v", err)
+		}
+	})
+
+	t.Run("should write reserved names if they are allowed", func(t *testing.T) {
+		err := WriteFile("testfile.1", []byte("some data"), WithAllowReservedNames())
+		if err != nil {
+			t.Error(err)
+		}
+		defer clean(t, "testfile.1")
+		defer clean(t, "testfile.1.1")
+
+		checkContents(t, "testfile.1", "some data")
+	})
+}
+
+func TestAssertCommitted(t *testing.T) {
+	t.Run("should return ErrCommitIncomplete if a name points to a different file", func(t *testing.T) {
+		createFile(t, "testfile.tmp", "data")
+		defer clean(t, "testfile.tmp")
+		createFile(t, "testfile", "data")
+		defer clean(t, "testfile")
+
+		err := assertCommitted("testfile.tmp", 4, "testfile")
+		if !errors.Is(err, ErrCommitIncomplete) {
+			t.Errorf("expect ErrCommitIncomplete but got %v", err)
+		}
+	})
+
+	t.Run("should return ErrCommitIncomplete if a name does not exist", func(t *testing.T) {
+		createFile(t, "testfile.tmp", "data")
+		defer clean(t, "testfile.tmp")
+
+		err := assertCommitted("testfile.tmp", 4, "testfile")
+		if !errors.Is(err, ErrCommitIncomplete) {
+			t.Errorf("expect ErrCommitIncomplete but got %v", err)
+		}
+	})
+
+	t.Run("should return ErrCommitIncomplete if the size does not match", func(t *testing.T) {
+		createFile(t, "testfile.tmp", "data")
+		defer clean(t, "testfile.tmp")
+		if err := os.Link("testfile.tmp", "testfile"); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile")
+
+		err := assertCommitted("testfile.tmp", 5, "testfile")
+		if !errors.Is(err, ErrCommitIncomplete) {
+			t.Errorf("expect ErrCommitIncomplete but got %v", err)
+		}
+	})
+}
+
+func TestVerifyLink(t *testing.T) {
+	t.Run("should not return an error if both names point to the same file", func(t *testing.T) {
+		createFile(t, "testfile", "")
+		defer clean(t, "testfile")
+		if err := os.Link("testfile", "testfile.1"); err != nil {
+			t.Fatal(err)
+		}
+		defer clean(t, "testfile.1")
+
+		if err := verifyLink("testfile", "testfile.1"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("should return ErrLinkMismatch if the names point to different files", func(t *testing.T) {
+		createFile(t, "testfile", "")
+		defer clean(t, "testfile")
+		createFile(t, "testfile.1", "")
+		defer clean(t, "testfile.1")
+
+		err := verifyLink("testfile", "testfile.1")
+		if !errors.Is(err, ErrLinkMismatch) {
+			t.Errorf("expect ErrLinkMismatch but got %v", err)
+		}
+	})
+
+	t.Run("should return ErrNotRegular if the new name is a directory", func(t *testing.T) {
+		createFile(t, "testfile", "")
+		defer clean(t, "testfile")
+		createDir(t, "testfile.1")
+		defer clean(t, "testfile.1")
+
+		err := verifyLink("testfile", "testfile.1")
+		if !errors.Is(err, ErrNotRegular) {
+			t.Errorf("expect ErrNotRegular but got %v", err)
+		}
+	})
 }
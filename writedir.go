@@ -0,0 +1,121 @@
+package safe
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteDir replaces the directory with the name with a new directory which is populated by the function,
+// so a bundle of files (e.g. certificates, configuration and templates) changes all at once.
+// The function is called with the name of an empty staging directory. When it succeeds,
+// the staging directory is synced and becomes a new version $(name).v$(version) (see VersionPostfix),
+// and the name, which is a symbolic link, is atomically switched to it. The alt name is a symbolic link
+// to the previous version, which is kept like the $(name).1 of a file. Older versions are removed.
+// If the name is a directory, it is turned into the first version. Until the link is created,
+// the name does not exist for a moment. The platform must support symbolic links.
+func WriteDir(name string, populate func(dir string) error, opts ...Option) error {
+	c := newConfig(opts)
+	name, unlock, err := c.begin(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	t := time.Now()
+	staging := c.tempName(name, t)
+	if err := os.Mkdir(staging, dirMode(c.perm)); err != nil {
+		return err
+	}
+	if err := populate(staging); err != nil {
+		os.RemoveAll(staging)
+		return &os.PathError{Op: "populate", Path: name, Err: err}
+	}
+	if !c.noSync {
+		if err := syncTree(staging); err != nil {
+			os.RemoveAll(staging)
+			return err
+		}
+	}
+	if err := c.ctx.Err(); err != nil {
+		os.RemoveAll(staging)
+		return &os.PathError{Op: "write", Path: name, Err: err}
+	}
+
+	previous, err := c.currentVersion(name, t)
+	if err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+	version := versionName(name, t)
+	if err := os.Rename(staging, version); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+	if previous != "" {
+		if err := c.relink(c.altName(name), previous); err != nil {
+			return err
+		}
+	}
+	if err := c.relink(name, version); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(name)); err != nil {
+		return err
+	}
+	return pruneVersions(name, version, previous)
+}
+
+// currentVersion returns the name of the version the resolved name points to, or "" if it does not exist.
+// If the name is a directory, it is renamed to a version first.
+func (c *config) currentVersion(name string, t time.Time) (string, error) {
+	target, err := symlinkTarget(name)
+	if target != "" || err != nil {
+		return target, err
+	}
+	info, err := os.Lstat(name)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", &os.PathError{Op: "write", Path: name, Err: ErrNotDir}
+	}
+	version := versionName(name, t.Add(-1))
+	if err := os.Rename(name, version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// syncTree syncs all files and directories in the directory, including the directory itself.
+func syncTree(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		if info.IsDir() {
+			return syncDir(path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// dirMode returns the mode of a directory whose files have the mode perm,
+// which can be entered by everybody who can read the files.
+func dirMode(perm os.FileMode) os.FileMode {
+	return perm | (perm&0444)>>2
+}
@@ -0,0 +1,102 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// populateWith returns a populate function which writes a file with the name and contents.
+func populateWith(name string, contents string) func(dir string) error {
+	return func(dir string) error {
+		return ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600)
+	}
+}
+
+// countVersions returns the number of versions of the directory with the name.
+func countVersions(t *testing.T, name string) int {
+	infos, err := ioutil.ReadDir(filepath.Dir(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), filepath.Base(name)+VersionPostfix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWriteDir(t *testing.T) {
+	t.Run("should switch the directory and keep the previous version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		for _, contents := range []string{"first", "second", "third"} {
+			if err := WriteDir("testdir/conf", populateWith("file", contents)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkContents(t, "testdir/conf/file", "third")
+		checkContents(t, "testdir/conf.1/file", "second")
+		if n := countVersions(t, "testdir/conf"); n != 2 {
+			t.Errorf("expect 2 versions but got %d", n)
+		}
+	})
+
+	t.Run("should keep the current version if populate fails", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+
+		if err := WriteDir("testdir/conf", populateWith("file", "some data")); err != nil {
+			t.Fatal(err)
+		}
+		errPopulate := errors.New("populate failed")
+		err := WriteDir("testdir/conf", func(dir string) error {
+			if err := populateWith("file", "broken")(dir); err != nil {
+				return err
+			}
+			return errPopulate
+		})
+		if !errors.Is(err, errPopulate) {
+			t.Errorf("expect the populate error but got %v", err)
+		}
+		checkContents(t, "testdir/conf/file", "some data")
+		infos, err := ioutil.ReadDir("testdir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The link, the version and the lock file.
+		if len(infos) > 3 {
+			t.Errorf("expect the staging directory to be removed but got %d entries", len(infos))
+		}
+	})
+
+	t.Run("should turn an existing directory into the previous version", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createDir(t, "testdir/conf")
+		createFile(t, "testdir/conf/file", "old data")
+
+		if err := WriteDir("testdir/conf", populateWith("file", "new data")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/conf/file", "new data")
+		checkContents(t, "testdir/conf.1/file", "old data")
+	})
+
+	t.Run("should not replace a file", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		createFile(t, "testdir/conf", "some data")
+
+		err := WriteDir("testdir/conf", populateWith("file", "new data"))
+		if !errors.Is(err, ErrNotDir) {
+			t.Errorf("expect ErrNotDir but got %v", err)
+		}
+		checkContents(t, "testdir/conf", "some data")
+	})
+}
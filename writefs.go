@@ -0,0 +1,75 @@
+//go:build go1.16
+// +build go1.16
+
+package safe
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// WriteFS is an fs.FS which can also write files with the procedure of WriteFile. It is implemented by *FS.
+// Adapters for storage abstractions like afero.Fs can be built on it, so applications can switch to safe writes
+// without this package depending on them.
+type WriteFS interface {
+	fs.FS
+	// WriteFile writes the file with the name like the WriteFile function of this package.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// OpenFile starts writing the file with the name like Create. Only the flags os.O_WRONLY, os.O_RDWR,
+	// os.O_CREATE, os.O_TRUNC and os.O_EXCL are supported, because a safe write always replaces the whole file.
+	OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error)
+	// Remove removes the file with the name like RemoveFile.
+	Remove(name string) error
+}
+
+var _ WriteFS = (*FS)(nil)
+
+// WriteFile writes the file with the name and the permissions like the WriteFile function of this package.
+func (fsys *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	path, err := fsys.path("write", name)
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data, fsys.with(WithPerm(perm))...)
+}
+
+// OpenFile starts writing the file with the name and the permissions like Create.
+// The contents are committed when the returned *File is closed. With os.O_EXCL, it fails if the file exists.
+// Other flags than os.O_WRONLY, os.O_RDWR, os.O_CREATE, os.O_TRUNC and os.O_EXCL are rejected with ErrUnsupportedFlag.
+func (fsys *FS) OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error) {
+	path, err := fsys.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	const supported = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_EXCL
+	if flag&^supported != 0 || flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrUnsupportedFlag}
+	}
+	if flag&os.O_EXCL != 0 {
+		exists, err := Exists(path, fsys.with(WithRetries(1))...)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+	}
+	return Create(path, fsys.with(WithPerm(perm))...)
+}
+
+// Remove removes the file with the name like RemoveFile.
+func (fsys *FS) Remove(name string) error {
+	path, err := fsys.path("remove", name)
+	if err != nil {
+		return err
+	}
+	return RemoveFile(path, fsys.opts...)
+}
+
+// with returns the options of the FS followed by the options.
+func (fsys *FS) with(opts ...Option) []Option {
+	all := make([]Option, 0, len(fsys.opts)+len(opts))
+	all = append(all, fsys.opts...)
+	return append(all, opts...)
+}
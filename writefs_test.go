@@ -0,0 +1,65 @@
+//go:build go1.16
+// +build go1.16
+
+package safe
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestWriteFS(t *testing.T) {
+	t.Run("should write the files safely", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		var fsys WriteFS = NewFS("testdir")
+
+		if err := fsys.WriteFile("a.json", []byte("a"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/a.json", "a")
+		checkContents(t, "testdir/a.json.1", "a")
+
+		w, err := fsys.OpenFile("b.json", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("b")); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/b.json")
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := fs.ReadFile(fsys, "b.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "b" {
+			t.Errorf("expect %q but got %q", "b", data)
+		}
+
+		if err := fsys.Remove("a.json"); err != nil {
+			t.Fatal(err)
+		}
+		checkNotExist(t, "testdir/a.json.1")
+	})
+
+	t.Run("should reject unsupported flags", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		fsys := NewFS("testdir")
+		if err := fsys.WriteFile("a.json", []byte("a"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := fsys.OpenFile("a.json", os.O_WRONLY|os.O_APPEND, 0600); !errors.Is(err, ErrUnsupportedFlag) {
+			t.Errorf("expect ErrUnsupportedFlag but got %v", err)
+		}
+		if _, err := fsys.OpenFile("a.json", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("expect ErrExist but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package safe
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Writer writes the same file again and again, e.g. a state file which is saved many times per second.
+// Instead of creating and removing a temporary file for every write, it keeps two staging files open and
+// takes turns between them: while one of them is the committed file, the other one is truncated and rewritten
+// for the next write. This saves the creation and the removal of a file per write.
+// Because the staging file of the previous version is reused, a reader which still has the previous version open
+// (e.g. a Snapshot) sees it change. With WithBackup, the staging file of the previous version becomes the backup,
+// so it is not reused and a new staging file is created instead. A Writer is safe for concurrent use.
+type Writer struct {
+	c    *config
+	name string
+
+	mu     sync.Mutex
+	slots  [2]*os.File
+	next   int
+	closed bool
+}
+
+// NewWriter creates a Writer for the file with the name. The options apply to every write.
+func NewWriter(name string, opts ...Option) *Writer {
+	return &Writer{c: newConfig(opts), name: name}
+}
+
+// WriteFile commits the data as the new contents of the file with the same procedure as WriteFile.
+func (w *Writer) WriteFile(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return &os.PathError{Op: "write", Path: w.name, Err: ErrClosed}
+	}
+
+	name, unlock, err := w.c.begin(w.name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	data, err = w.c.prepare(name, data)
+	if err != nil {
+		return err
+	}
+
+	f, err := w.slot(name)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	if err := w.c.syncFile(f); err != nil {
+		return err
+	}
+	if err := w.c.commit(f.Name(), name, int64(len(data)), time.Now(), data); err != nil {
+		return err
+	}
+	if w.c.backup != "" {
+		// The backup links the contents of the previous staging file, so it must not be rewritten.
+		w.retire(1 - w.next)
+	}
+	w.next = 1 - w.next
+	return nil
+}
+
+// retire closes the staging file of the slot and removes its name, so the next write of the slot creates a new one.
+func (w *Writer) retire(slot int) {
+	if f := w.slots[slot]; f != nil {
+		f.Close()
+		os.Remove(f.Name())
+		w.slots[slot] = nil
+	}
+}
+
+// slot returns the staging file for the next write of the resolved name.
+// The staging file is created again if it was removed or replaced in the meantime, e.g. by RecoverDir.
+func (w *Writer) slot(name string) (*os.File, error) {
+	if f := w.slots[w.next]; f != nil {
+		want, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if got, err := os.Stat(f.Name()); err == nil && os.SameFile(want, got) {
+			return f, nil
+		}
+		f.Close()
+		w.slots[w.next] = nil
+	}
+
+	var (
+		f   *os.File
+		err error
+	)
+	// The staging files are named like temporary files, so they are cleaned up if the process is interrupted.
+	for i := 0; i < 3; i++ {
+		f, err = os.OpenFile(w.c.tempName(name, time.Now()), os.O_RDWR|os.O_CREATE|os.O_EXCL, w.c.perm)
+		if !os.IsExist(err) {
+			break
+		}
+		time.Sleep(time.Microsecond)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(w.c.perm); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	w.slots[w.next] = f
+	return f, nil
+}
+
+// Close removes the staging files. The committed file is not affected.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+
+	var first error
+	for i, f := range w.slots {
+		if f == nil {
+			continue
+		}
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+		if err := remove(f.Name()); err != nil && first == nil {
+			first = err
+		}
+		w.slots[i] = nil
+	}
+	return first
+}
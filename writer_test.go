@@ -0,0 +1,93 @@
+package safe
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	t.Run("should reuse two staging files", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		w := NewWriter("testdir/testfile", WithAssertCommitted())
+
+		var staging []string
+		for _, data := range []string{"a", "bb", "c", "dd"} {
+			if err := w.WriteFile([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+			checkContents(t, "testdir/testfile", data)
+			checkContents(t, "testdir/testfile.1", data)
+			staging = append(staging, w.slots[1-w.next].Name())
+		}
+		if staging[0] != staging[2] || staging[1] != staging[3] || staging[0] == staging[1] {
+			t.Errorf("expect two alternating staging files but got %v", staging)
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if infos, _ := ioutil.ReadDir("testdir"); len(infos) != 2 {
+			t.Errorf("expect only the file and its alt file but got %d files", len(infos))
+		}
+		checkContents(t, "testdir/testfile", "dd")
+		if err := w.WriteFile(nil); !errors.Is(err, ErrClosed) {
+			t.Errorf("expect ErrClosed but got %v", err)
+		}
+	})
+
+	t.Run("should recreate a staging file which was removed", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		w := NewWriter("testdir/testfile")
+		defer w.Close()
+
+		for _, data := range []string{"a", "b"} {
+			if err := w.WriteFile([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Remove(w.slots[w.next].Name()); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteFile([]byte("c")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "c")
+		checkContents(t, "testdir/testfile.1", "c")
+	})
+	t.Run("should keep the backup if a write fails", func(t *testing.T) {
+		defer clean(t, "testdir")
+		createDir(t, "testdir")
+		failure := errors.New("failure")
+		failing := false
+		w := NewWriter("testdir/testfile", WithBackup(".bak"), WithCommitStrategy(CommitFunc(func(tmp string, alt string, final string) error {
+			if failing {
+				return failure
+			}
+			return HardlinkCommit{}.Commit(tmp, alt, final)
+		})))
+		defer w.Close()
+
+		for _, data := range []string{"v1", "v2", "v3"} {
+			if err := w.WriteFile([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		failing = true
+		if err := w.WriteFile([]byte("v4-uncommitted")); !errors.Is(err, failure) {
+			t.Fatalf("expect the error of the commit but got %v", err)
+		}
+		checkContents(t, "testdir/testfile", "v3")
+		checkContents(t, "testdir/testfile.bak", "v2")
+
+		failing = false
+		if err := w.WriteFile([]byte("v5")); err != nil {
+			t.Fatal(err)
+		}
+		checkContents(t, "testdir/testfile", "v5")
+		checkContents(t, "testdir/testfile.bak", "v3")
+	})
+}